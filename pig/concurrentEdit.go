@@ -0,0 +1,96 @@
+package pig
+
+//
+// Concurrent edits: when a contributor saves an idiom or an impl
+// which has been modified since they started editing, their changes
+// are merged field by field with the current version, using the
+// version they started from as the common base.
+//
+
+// FieldMerge is the three-way merge of a single form field.
+type FieldMerge struct {
+	// Name is the form field name.
+	Name string
+	// Label is displayed in the merge page.
+	Label string
+	// Base is the value the contributor started from.
+	Base string
+	// Current is the value saved in the meantime by someone else.
+	Current string
+	// Mine is the value submitted by the contributor.
+	Mine string
+	// Merged is the resulting value. When Conflict, it is Mine.
+	Merged   string
+	Conflict bool
+	// BaseKnown is false when the base version could not be found.
+	BaseKnown bool
+}
+
+// MergeField merges the concurrent changes current and mine of the same base value.
+// A field changed on one side only takes that change. A field changed
+// on both sides is a conflict, unless both sides made the same change.
+func MergeField(name, base, current, mine string) FieldMerge {
+	m := FieldMerge{
+		Name:      name,
+		Base:      base,
+		Current:   current,
+		Mine:      mine,
+		BaseKnown: true,
+	}
+	switch {
+	case mine == current, current == base:
+		m.Merged = mine
+	case mine == base:
+		m.Merged = current
+	default:
+		m.Merged = mine
+		m.Conflict = true
+	}
+	return m
+}
+
+// MergeFieldUnknownBase is MergeField without a common base:
+// any difference between current and mine is a conflict.
+func MergeFieldUnknownBase(name, current, mine string) FieldMerge {
+	return FieldMerge{
+		Name:     name,
+		Current:  current,
+		Mine:     mine,
+		Merged:   mine,
+		Conflict: current != mine,
+	}
+}
+
+// FieldMerges is the merge of all the fields of a form.
+type FieldMerges []FieldMerge
+
+// HasConflict is true if at least one field needs to be resolved by the contributor.
+func (ms FieldMerges) HasConflict() bool {
+	for _, m := range ms {
+		if m.Conflict {
+			return true
+		}
+	}
+	return false
+}
+
+// Merged returns the merged values, by field name.
+func (ms FieldMerges) Merged() map[string]string {
+	values := make(map[string]string, len(ms))
+	for _, m := range ms {
+		values[m.Name] = m.Merged
+	}
+	return values
+}
+
+// AutoMerged returns the fields changed by someone else and kept as is,
+// because the contributor didn't change them.
+func (ms FieldMerges) AutoMerged() FieldMerges {
+	var auto FieldMerges
+	for _, m := range ms {
+		if !m.Conflict && m.Merged != m.Mine {
+			auto = append(auto, m)
+		}
+	}
+	return auto
+}
@@ -0,0 +1,55 @@
+package pig
+
+import (
+	"testing"
+)
+
+func TestMergeField(t *testing.T) {
+	for _, c := range []struct {
+		base, current, mine string
+		merged              string
+		conflict            bool
+	}{
+		{"a", "a", "a", "a", false},
+		{"a", "a", "b", "b", false},
+		{"a", "b", "a", "b", false},
+		{"a", "b", "b", "b", false},
+		{"a", "b", "c", "c", true},
+		{"", "b", "c", "c", true},
+	} {
+		m := MergeField("f", c.base, c.current, c.mine)
+		if m.Merged != c.merged || m.Conflict != c.conflict {
+			t.Errorf("MergeField(%q, %q, %q) = %q, %v, expected %q, %v", c.base, c.current, c.mine, m.Merged, m.Conflict, c.merged, c.conflict)
+		}
+	}
+}
+
+func TestMergeFieldUnknownBase(t *testing.T) {
+	if m := MergeFieldUnknownBase("f", "a", "a"); m.Conflict || m.Merged != "a" {
+		t.Errorf("Unexpected merge %v", m)
+	}
+	if m := MergeFieldUnknownBase("f", "a", "b"); !m.Conflict || m.BaseKnown {
+		t.Errorf("Unexpected merge %v", m)
+	}
+}
+
+func TestFieldMerges(t *testing.T) {
+	ms := FieldMerges{
+		MergeField("code", "x", "x", "y"),
+		MergeField("comment", "c", "d", "c"),
+	}
+	if ms.HasConflict() {
+		t.Errorf("Unexpected conflict in %v", ms)
+	}
+	merged := ms.Merged()
+	if merged["code"] != "y" || merged["comment"] != "d" {
+		t.Errorf("Unexpected merged values %v", merged)
+	}
+	if auto := ms.AutoMerged(); len(auto) != 1 || auto[0].Name != "comment" {
+		t.Errorf("Unexpected auto-merged fields %v", auto)
+	}
+	ms = append(ms, MergeField("doc", "u", "v", "w"))
+	if !ms.HasConflict() {
+		t.Errorf("Expected conflict in %v", ms)
+	}
+}
@@ -0,0 +1,364 @@
+package pig
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//
+// Diff engine for the comparison of two versions of an idiom.
+// Code is compared line by line, and prose word by word,
+// with the Myers algorithm.
+//
+
+// DiffOp is the operation of a diff line or chunk.
+type DiffOp string
+
+const (
+	// DiffEqual is in both sides.
+	DiffEqual DiffOp = "equal"
+	// DiffDelete is in left side only.
+	DiffDelete DiffOp = "delete"
+	// DiffInsert is in right side only.
+	DiffInsert DiffOp = "insert"
+)
+
+// DiffContext is the number of unchanged lines shown around changes.
+const DiffContext = 3
+
+// maxDiffTokens bounds the cost of myers, which is quadratic in the worst case.
+// Above it, once the common prefix and suffix are set apart, the middle
+// is a whole-block replace.
+const maxDiffTokens = 1000
+
+// myers computes a shortest edit script from a to b, as one DiffOp
+// per token: DiffEqual consumes a token of a and b, DiffDelete a token
+// of a, DiffInsert a token of b.
+func myers(a, b []string) []DiffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	ops := make([]DiffOp, 0, prefix+len(a)+len(b)+suffix)
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, DiffEqual)
+	}
+	if len(a)+len(b) > maxDiffTokens {
+		for range a {
+			ops = append(ops, DiffDelete)
+		}
+		for range b {
+			ops = append(ops, DiffInsert)
+		}
+	} else {
+		ops = append(ops, shortestEditScript(a, b)...)
+	}
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, DiffEqual)
+	}
+	return ops
+}
+
+// shortestEditScript is the Myers algorithm. Its memory is O(d²),
+// d being the number of edits.
+func shortestEditScript(a, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] is v[-d-1..d+1] before step d, needed to backtrack.
+	var trace [][]int
+	var x, y int
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y = x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Backtrack from (n, m), collecting ops in reverse order.
+	ops := make([]DiffOp, 0, max)
+	x, y = n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		base := d + 1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[base+k-1] < v[base+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[base+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, DiffEqual)
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, DiffInsert)
+		} else {
+			ops = append(ops, DiffDelete)
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, DiffEqual)
+		x--
+		y--
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// DiffLine is a line of a line-level diff.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+	// LeftNumber and RightNumber are the 1-based line numbers,
+	// 0 when the line is not in that side.
+	LeftNumber  int `json:",omitempty"`
+	RightNumber int `json:",omitempty"`
+}
+
+// splitLines splits s into lines, without their line terminator.
+func splitLines(s string) []string {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// DiffLines compares left and right line by line.
+func DiffLines(left, right string) []DiffLine {
+	a, b := splitLines(left), splitLines(right)
+	ops := myers(a, b)
+	lines := make([]DiffLine, 0, len(ops))
+	var i, j int
+	for _, op := range ops {
+		switch op {
+		case DiffEqual:
+			lines = append(lines, DiffLine{Op: op, Text: a[i], LeftNumber: i + 1, RightNumber: j + 1})
+			i++
+			j++
+		case DiffDelete:
+			lines = append(lines, DiffLine{Op: op, Text: a[i], LeftNumber: i + 1})
+			i++
+		case DiffInsert:
+			lines = append(lines, DiffLine{Op: op, Text: b[j], RightNumber: j + 1})
+			j++
+		}
+	}
+	return lines
+}
+
+// DiffChunk is a run of text of a word-level diff.
+type DiffChunk struct {
+	Op   DiffOp
+	Text string
+}
+
+// splitWords splits s into words, runs of spaces, and single punctuation
+// characters. Concatenating the tokens gives s back.
+func splitWords(s string) []string {
+	var tokens []string
+	class := func(r rune) int {
+		switch {
+		case unicode.IsSpace(r):
+			return 1
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '_':
+			return 2
+		}
+		return 3
+	}
+	start, startClass := 0, 0
+	for i, r := range s {
+		c := class(r)
+		if i > start && (c != startClass || c == 3) {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		if i == start {
+			startClass = c
+		}
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+// DiffWords compares left and right word by word.
+// Consecutive words with the same DiffOp are in a single chunk.
+func DiffWords(left, right string) []DiffChunk {
+	a, b := splitWords(left), splitWords(right)
+	ops := myers(a, b)
+	var chunks []DiffChunk
+	add := func(op DiffOp, text string) {
+		if n := len(chunks); n > 0 && chunks[n-1].Op == op {
+			chunks[n-1].Text += text
+			return
+		}
+		chunks = append(chunks, DiffChunk{Op: op, Text: text})
+	}
+	var i, j int
+	for _, op := range ops {
+		switch op {
+		case DiffEqual:
+			add(op, a[i])
+			i++
+			j++
+		case DiffDelete:
+			add(op, a[i])
+			i++
+		case DiffInsert:
+			add(op, b[j])
+			j++
+		}
+	}
+	return chunks
+}
+
+// DiffHunk is a group of changed lines, with their context.
+type DiffHunk struct {
+	LeftStart, LeftCount   int
+	RightStart, RightCount int
+	Lines                  []DiffLine
+}
+
+// Header is the "@@ -l,s +l,s @@" line of the unified format.
+func (h DiffHunk) Header() string {
+	rng := func(start, count int) string {
+		if count == 1 {
+			return fmt.Sprint(start)
+		}
+		return fmt.Sprintf("%d,%d", start, count)
+	}
+	return fmt.Sprintf("@@ -%s +%s @@", rng(h.LeftStart, h.LeftCount), rng(h.RightStart, h.RightCount))
+}
+
+// Hunks groups the changes of lines, with context unchanged lines
+// around them. Changes closer than 2*context lines are in the same hunk.
+func Hunks(lines []DiffLine, context int) []DiffHunk {
+	var hunks []DiffHunk
+	i := 0
+	for {
+		for i < len(lines) && lines[i].Op == DiffEqual {
+			i++
+		}
+		if i == len(lines) {
+			return hunks
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for {
+			for end < len(lines) && lines[end].Op != DiffEqual {
+				end++
+			}
+			next := end
+			for next < len(lines) && lines[next].Op == DiffEqual {
+				next++
+			}
+			if next < len(lines) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end += context
+			if end > len(lines) {
+				end = len(lines)
+			}
+			break
+		}
+		hunks = append(hunks, newHunk(lines, start, end))
+		i = end
+	}
+}
+
+func newHunk(lines []DiffLine, start, end int) DiffHunk {
+	h := DiffHunk{Lines: lines[start:end]}
+	// Line numbers of the first lines of the hunk
+	for _, line := range lines[:start] {
+		if line.Op != DiffInsert {
+			h.LeftStart++
+		}
+		if line.Op != DiffDelete {
+			h.RightStart++
+		}
+	}
+	for _, line := range h.Lines {
+		if line.Op != DiffInsert {
+			h.LeftCount++
+		}
+		if line.Op != DiffDelete {
+			h.RightCount++
+		}
+	}
+	// An empty range starts at the line before it, as in GNU diff
+	if h.LeftCount > 0 {
+		h.LeftStart++
+	}
+	if h.RightCount > 0 {
+		h.RightStart++
+	}
+	return h
+}
+
+// Prefix is the first character of the line in the unified format.
+func (line DiffLine) Prefix() string {
+	switch line.Op {
+	case DiffDelete:
+		return "-"
+	case DiffInsert:
+		return "+"
+	}
+	return " "
+}
+
+// UnifiedDiff formats lines in the unified diff format, with the file
+// names leftName and rightName. It is empty when there is no change.
+func UnifiedDiff(leftName, rightName string, lines []DiffLine, context int) string {
+	hunks := Hunks(lines, context)
+	if len(hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", leftName, rightName)
+	for _, h := range hunks {
+		b.WriteString(h.Header())
+		b.WriteString("\n")
+		for _, line := range h.Lines {
+			b.WriteString(line.Prefix())
+			b.WriteString(line.Text)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
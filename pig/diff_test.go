@@ -0,0 +1,162 @@
+package pig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// applyOps rebuilds both sides from an edit script, to check it.
+func applyOps(a, b []string, ops []DiffOp) (left, right []string) {
+	var i, j int
+	for _, op := range ops {
+		switch op {
+		case DiffEqual:
+			left = append(left, a[i])
+			right = append(right, b[j])
+			i++
+			j++
+		case DiffDelete:
+			left = append(left, a[i])
+			i++
+		case DiffInsert:
+			right = append(right, b[j])
+			j++
+		}
+	}
+	return left, right
+}
+
+func TestMyers(t *testing.T) {
+	for _, c := range []struct {
+		a, b  string
+		edits int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"abcabba", "cbabac", 5},
+		{"abcdef", "abxdef", 2},
+		{"xyz", "abc", 6},
+	} {
+		a, b := strings.Split(c.a, ""), strings.Split(c.b, "")
+		if c.a == "" {
+			a = nil
+		}
+		if c.b == "" {
+			b = nil
+		}
+		ops := myers(a, b)
+		left, right := applyOps(a, b, ops)
+		if strings.Join(left, "") != c.a || strings.Join(right, "") != c.b {
+			t.Errorf("myers(%q, %q) = %v does not rebuild both sides", c.a, c.b, ops)
+		}
+		edits := 0
+		for _, op := range ops {
+			if op != DiffEqual {
+				edits++
+			}
+		}
+		if edits != c.edits {
+			t.Errorf("myers(%q, %q) has %d edits, expected %d", c.a, c.b, edits, c.edits)
+		}
+	}
+}
+
+func TestMyersAboveCap(t *testing.T) {
+	var a, b []string
+	a = append(a, "same")
+	b = append(b, "same")
+	for i := 0; i < maxDiffTokens; i++ {
+		a = append(a, fmt.Sprint("left", i))
+		b = append(b, fmt.Sprint("right", i))
+	}
+	ops := myers(a, b)
+	left, right := applyOps(a, b, ops)
+	if !reflect.DeepEqual(left, a) || !reflect.DeepEqual(right, b) {
+		t.Fatalf("edit script does not rebuild both sides")
+	}
+	if ops[0] != DiffEqual || ops[1] != DiffDelete || ops[len(ops)-1] != DiffInsert {
+		t.Errorf("expected the common prefix, then a whole-block replace")
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	lines := DiffLines("a\nb\nc\n", "a\r\nB\r\nc")
+	expected := []DiffLine{
+		{Op: DiffEqual, Text: "a", LeftNumber: 1, RightNumber: 1},
+		{Op: DiffDelete, Text: "b", LeftNumber: 2},
+		{Op: DiffInsert, Text: "B", RightNumber: 2},
+		{Op: DiffEqual, Text: "c", LeftNumber: 3, RightNumber: 3},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Unexpected line diff %v", lines)
+	}
+}
+
+func TestDiffWords(t *testing.T) {
+	chunks := DiffWords("Sort the list, in place", "Sort a list, in place.")
+	expected := []DiffChunk{
+		{Op: DiffEqual, Text: "Sort "},
+		{Op: DiffDelete, Text: "the"},
+		{Op: DiffInsert, Text: "a"},
+		{Op: DiffEqual, Text: " list, in place"},
+		{Op: DiffInsert, Text: "."},
+	}
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("Unexpected word diff %q", chunks)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	s := "Hello,  wörld_1 (x+y)!"
+	tokens := splitWords(s)
+	expected := []string{"Hello", ",", "  ", "wörld_1", " ", "(", "x", "+", "y", ")", "!"}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("splitWords(%q) = %q", s, tokens)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	left := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15"
+	right := "1\n2\n3\n4\n5\n6\n7\nhuit\n9\n10\n11\n12\n13\n14\n15\n16"
+	got := UnifiedDiff("a", "b", DiffLines(left, right), 3)
+	expected := `--- a
++++ b
+@@ -5,7 +5,7 @@
+ 5
+ 6
+ 7
+-8
++huit
+ 9
+ 10
+ 11
+@@ -13,3 +13,4 @@
+ 13
+ 14
+ 15
++16
+`
+	if got != expected {
+		t.Errorf("Unexpected unified diff:\n%s", got)
+	}
+
+	if got := UnifiedDiff("a", "b", DiffLines("", "x"), 3); got != "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n" {
+		t.Errorf("Unexpected unified diff for a creation:\n%s", got)
+	}
+	if got := UnifiedDiff("a", "b", DiffLines("x", "x"), 3); got != "" {
+		t.Errorf("Expected empty diff, got:\n%s", got)
+	}
+}
+
+func TestHunksMerged(t *testing.T) {
+	left := "1\n2\n3\n4\n5\n6\n7\n8"
+	right := "1\nb\n3\n4\n5\n6\ng\n8"
+	hunks := Hunks(DiffLines(left, right), 3)
+	if len(hunks) != 1 || hunks[0].Header() != "@@ -1,8 +1,8 @@" {
+		t.Errorf("Expected a single hunk, got %v", hunks)
+	}
+}
@@ -0,0 +1,101 @@
+package pig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//
+// Discussion threads let visitors comment on an idiom, or on one of its impls,
+// without editing it.
+// The comment text is markup, rendered like the impl comments.
+//
+
+// Comment is a message in the discussion thread of an idiom (ImplID 0)
+// or of an impl.
+type Comment struct {
+	IdiomID int
+	// ImplID is 0 for the comments about the idiom statement.
+	ImplID int
+
+	// IdiomVersion and ImplVersion are the versions the comment was written against.
+	IdiomVersion int
+	ImplVersion  int
+
+	Author string
+	// Authenticated is true when Author is a signed-in account,
+	// false when it is a nickname.
+	Authenticated bool
+
+	Text         string `datastore:",noindex"`
+	CreationDate time.Time
+
+	// Hidden comments are shown only to the admins.
+	Hidden bool
+	// Moderator is the admin who hid the comment.
+	Moderator      string
+	ModerationDate time.Time
+}
+
+// MaxCommentLength is the maximum number of chars of a Comment Text.
+const MaxCommentLength = 2000
+
+// MaxCommentLinks is the maximum number of URLs in a Comment Text.
+const MaxCommentLinks = 3
+
+// ValidateCommentText returns an error if text is not an acceptable Comment Text.
+func ValidateCommentText(text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return errors.New("comment is empty")
+	}
+	if n := len([]rune(text)); n > MaxCommentLength {
+		return fmt.Errorf("comment is too long (%d chars, max %d)", n, MaxCommentLength)
+	}
+	return nil
+}
+
+// CommentSpamMotive returns why text looks like spam, or "" if it looks fine.
+func CommentSpamMotive(text string) string {
+	lower := strings.ToLower(text)
+	for _, trash := range []string{"href=", "[url=", "[link="} {
+		if strings.Contains(lower, trash) {
+			return "raw link markup " + trash
+		}
+	}
+	if n := strings.Count(lower, "http://") + strings.Count(lower, "https://"); n > MaxCommentLinks {
+		return fmt.Sprintf("too many links (%d)", n)
+	}
+	return ""
+}
+
+// Outdated tells if c is about an older version of its impl in idiom.
+// The comments about the idiom statement are never considered outdated,
+// because the idiom version changes with each impl edit.
+func (c *Comment) Outdated(idiom *Idiom) bool {
+	if c.ImplID == 0 {
+		return false
+	}
+	_, impl, found := idiom.FindImplInIdiom(c.ImplID)
+	return found && c.ImplVersion < impl.Version
+}
+
+// CommentRecipients are the users to be notified of comment c on idiom:
+// the author of the impl, or the author of the idiom.
+// The commenter is never notified of their own comment.
+func CommentRecipients(c *Comment, idiom *Idiom) []string {
+	author := idiom.Author
+	if c.ImplID != 0 {
+		_, impl, found := idiom.FindImplInIdiom(c.ImplID)
+		if !found {
+			return nil
+		}
+		author = impl.Author
+	}
+	if author == "" || strings.EqualFold(author, c.Author) {
+		return nil
+	}
+	return []string{author}
+}
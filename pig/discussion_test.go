@@ -0,0 +1,83 @@
+package pig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCommentText(t *testing.T) {
+	for _, text := range []string{"", "  \n "} {
+		if err := ValidateCommentText(text); err == nil {
+			t.Errorf("Expected error for empty comment %q", text)
+		}
+	}
+	if err := ValidateCommentText(strings.Repeat("é", MaxCommentLength)); err != nil {
+		t.Errorf("Unexpected error for a comment of max length: %v", err)
+	}
+	if err := ValidateCommentText(strings.Repeat("a", MaxCommentLength+1)); err == nil {
+		t.Errorf("Expected error for a too long comment")
+	}
+}
+
+func TestCommentSpamMotive(t *testing.T) {
+	for _, text := range []string{
+		"This is wrong for empty lists.",
+		"See [the doc](https://golang.org/pkg/sort/) and https://go.dev/blog",
+	} {
+		if motive := CommentSpamMotive(text); motive != "" {
+			t.Errorf("Unexpected spam motive %q for %q", motive, text)
+		}
+	}
+	for _, text := range []string{
+		`Cheap <a HREF="http://x.example">pills</a>`,
+		"[url=http://x.example]pills[/url]",
+		"http://a.example http://b.example https://c.example https://d.example",
+	} {
+		if motive := CommentSpamMotive(text); motive == "" {
+			t.Errorf("Expected spam motive for %q", text)
+		}
+	}
+}
+
+func TestCommentOutdated(t *testing.T) {
+	idiom := &Idiom{
+		Id:              1,
+		Version:         9,
+		Implementations: []Impl{{Id: 10, Version: 3}},
+	}
+	for _, c := range []struct {
+		comment  Comment
+		outdated bool
+	}{
+		{Comment{IdiomID: 1, IdiomVersion: 2}, false},
+		{Comment{IdiomID: 1, ImplID: 10, ImplVersion: 3}, false},
+		{Comment{IdiomID: 1, ImplID: 10, ImplVersion: 2}, true},
+		{Comment{IdiomID: 1, ImplID: 11, ImplVersion: 1}, false},
+	} {
+		if got := c.comment.Outdated(idiom); got != c.outdated {
+			t.Errorf("Outdated(%v) = %v, expected %v", c.comment, got, c.outdated)
+		}
+	}
+}
+
+func TestCommentRecipients(t *testing.T) {
+	idiom := &Idiom{
+		Id:              1,
+		Author:          "alice",
+		Implementations: []Impl{{Id: 10, Author: "bob"}, {Id: 11}},
+	}
+	for _, c := range []struct {
+		comment    Comment
+		recipients string
+	}{
+		{Comment{ImplID: 0, Author: "carol"}, "alice"},
+		{Comment{ImplID: 10, Author: "carol"}, "bob"},
+		{Comment{ImplID: 10, Author: "Bob"}, ""},
+		{Comment{ImplID: 11, Author: "carol"}, ""},
+		{Comment{ImplID: 12, Author: "carol"}, ""},
+	} {
+		if got := strings.Join(CommentRecipients(&c.comment, idiom), ","); got != c.recipients {
+			t.Errorf("CommentRecipients(%v) = %q, expected %q", c.comment, got, c.recipients)
+		}
+	}
+}
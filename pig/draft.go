@@ -0,0 +1,156 @@
+package pig
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+//
+// Drafts are edits saved without being published.
+// They are previewed with the idiom detail templates, at a secret URL
+// which may be shared. When published, they go through the usual save,
+// against the version they were based on.
+//
+
+// DraftKind tells what a Draft is about.
+type DraftKind string
+
+const (
+	// DraftIdiom is an edit of the idiom statement.
+	DraftIdiom DraftKind = "idiom"
+	// DraftImpl is an edit of an existing impl.
+	DraftImpl DraftKind = "impl"
+	// DraftNewImpl is a new impl of an existing idiom.
+	DraftNewImpl DraftKind = "new-impl"
+)
+
+// Draft is an unpublished edit of an idiom statement or of an impl.
+// Its Datastore key name is its ID.
+type Draft struct {
+	// ID is random. It is part of the preview URL, which is not listed anywhere.
+	ID      string
+	Kind    DraftKind
+	IdiomID int
+	// ImplID is 0 for DraftIdiom and DraftNewImpl.
+	ImplID int
+	// BaseVersion is the version of the idiom (DraftIdiom) or of the impl (DraftImpl)
+	// the draft was based on. It is 0 for DraftNewImpl.
+	BaseVersion int
+
+	// Owner is the account of the draft creator, or else the secret token
+	// of the anonymous creator.
+	Owner string
+	// Authenticated is true when Owner is a signed-in account.
+	Authenticated bool
+	// Nickname is the username used when publishing.
+	Nickname string
+
+	CreationDate time.Time
+	UpdateDate   time.Time
+	EditSummary  string
+
+	// Idiom statement fields, for DraftIdiom
+	Title          string
+	LeadParagraph  string `datastore:",noindex"`
+	ExtraKeywords  string `datastore:",noindex"`
+	Tags           []string
+	ExampleInputs  []string `datastore:",noindex"`
+	ExampleOutputs []string `datastore:",noindex"`
+
+	// Impl fields, for DraftImpl and DraftNewImpl
+	LanguageName           string
+	ImportsBlock           string `datastore:",noindex"`
+	CodeBlock              string `datastore:",noindex"`
+	AuthorComment          string `datastore:",noindex"`
+	OriginalAttributionURL string `datastore:",noindex"`
+	DemoURL                string `datastore:",noindex"`
+	DocumentationURL       string `datastore:",noindex"`
+	MinVersion             string `datastore:",noindex"`
+	Dialect                string `datastore:",noindex"`
+	ExpectedOutput         string `datastore:",noindex"`
+	VerifiedExamples       bool   `datastore:",noindex"`
+}
+
+// NewDraftID returns a random, unguessable Draft ID.
+func NewDraftID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Apply writes the draft contents into idiom, which is modified.
+// The Version of the idiom or impl is set to BaseVersion, so that the
+// edit forms filled from idiom send the version the draft was based on.
+// It returns the impl of the draft, nil for DraftIdiom.
+// A DraftNewImpl impl is appended to idiom, with Id 0.
+func (d *Draft) Apply(idiom *Idiom) (*Impl, error) {
+	if d.IdiomID != idiom.Id {
+		return nil, fmt.Errorf("draft %s is about idiom %d, not %d", d.ID, d.IdiomID, idiom.Id)
+	}
+	var impl *Impl
+	switch d.Kind {
+	case DraftIdiom:
+		idiom.Title = d.Title
+		idiom.LeadParagraph = d.LeadParagraph
+		idiom.ExtraKeywords = d.ExtraKeywords
+		idiom.Tags = d.Tags
+		idiom.SetExamples(d.ExampleInputs, d.ExampleOutputs)
+		idiom.Version = d.BaseVersion
+		idiom.EditSummary = d.EditSummary
+		idiom.LastEditor = d.Nickname
+		return nil, nil
+	case DraftImpl:
+		var found bool
+		if _, impl, found = idiom.FindImplInIdiom(d.ImplID); !found {
+			return nil, fmt.Errorf("impl %d not found in idiom %d", d.ImplID, idiom.Id)
+		}
+		impl.Version = d.BaseVersion
+	case DraftNewImpl:
+		idiom.Implementations = append(idiom.Implementations, Impl{
+			Author:       d.Nickname,
+			CreationDate: d.CreationDate,
+			LanguageName: d.LanguageName,
+		})
+		impl = &idiom.Implementations[len(idiom.Implementations)-1]
+	default:
+		return nil, fmt.Errorf("unknown draft kind %q", d.Kind)
+	}
+	impl.ImportsBlock = d.ImportsBlock
+	impl.CodeBlock = d.CodeBlock
+	impl.AuthorComment = d.AuthorComment
+	impl.OriginalAttributionURL = d.OriginalAttributionURL
+	impl.DemoURL = d.DemoURL
+	impl.DocumentationURL = d.DocumentationURL
+	impl.MinVersion = d.MinVersion
+	impl.Dialect = d.Dialect
+	impl.ExpectedOutput = d.ExpectedOutput
+	impl.VerifiedExamples = d.VerifiedExamples
+	impl.LastEditor = d.Nickname
+	impl.VersionDate = d.UpdateDate
+	return impl, nil
+}
+
+// CurrentVersion is the current version of what d is about, in idiom.
+// It is 0 for a DraftNewImpl, and -1 if the impl doesn't exist anymore.
+func (d *Draft) CurrentVersion(idiom *Idiom) int {
+	switch d.Kind {
+	case DraftIdiom:
+		return idiom.Version
+	case DraftImpl:
+		if _, impl, found := idiom.FindImplInIdiom(d.ImplID); found {
+			return impl.Version
+		}
+		return -1
+	}
+	return 0
+}
+
+// Outdated tells if idiom has changed since the draft was created,
+// in which case publishing merges the draft with the current version.
+func (d *Draft) Outdated(idiom *Idiom) bool {
+	return d.CurrentVersion(idiom) != d.BaseVersion
+}
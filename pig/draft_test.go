@@ -0,0 +1,95 @@
+package pig
+
+import (
+	"testing"
+)
+
+func draftTestIdiom() *Idiom {
+	return &Idiom{
+		Id:      1,
+		Title:   "Sort a list",
+		Version: 7,
+		Implementations: []Impl{
+			{Id: 10, LanguageName: "Go", CodeBlock: "sort.Ints(x)", Version: 3},
+		},
+	}
+}
+
+func TestNewDraftID(t *testing.T) {
+	a, err := NewDraftID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewDraftID()
+	if len(a) != 32 || a == b {
+		t.Errorf("Unexpected draft IDs %q, %q", a, b)
+	}
+}
+
+func TestDraftApplyIdiom(t *testing.T) {
+	idiom := draftTestIdiom()
+	d := &Draft{Kind: DraftIdiom, IdiomID: 1, BaseVersion: 5, Title: "Sort a slice", Tags: []string{"Sorting"},
+		ExampleInputs: []string{"x = [3, 1]"}, ExampleOutputs: []string{"[1, 3]"}}
+	impl, err := d.Apply(idiom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if impl != nil {
+		t.Errorf("Expected no impl for an idiom draft, got %v", impl)
+	}
+	if idiom.Title != "Sort a slice" || idiom.Version != 5 || len(idiom.Tags) != 1 || !idiom.HasExamples() {
+		t.Errorf("Draft not applied: %v", idiom)
+	}
+}
+
+func TestDraftApplyImpl(t *testing.T) {
+	idiom := draftTestIdiom()
+	d := &Draft{Kind: DraftImpl, IdiomID: 1, ImplID: 10, BaseVersion: 2, CodeBlock: "sort.Slice(x, less)", Nickname: "alice"}
+	impl, err := d.Apply(idiom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if impl != &idiom.Implementations[0] || impl.CodeBlock != "sort.Slice(x, less)" || impl.Version != 2 || impl.LastEditor != "alice" {
+		t.Errorf("Draft not applied: %v", impl)
+	}
+
+	d = &Draft{Kind: DraftImpl, IdiomID: 1, ImplID: 11}
+	if _, err := d.Apply(draftTestIdiom()); err == nil {
+		t.Errorf("Expected error for a missing impl")
+	}
+	d = &Draft{Kind: DraftImpl, IdiomID: 2, ImplID: 10}
+	if _, err := d.Apply(draftTestIdiom()); err == nil {
+		t.Errorf("Expected error for another idiom")
+	}
+}
+
+func TestDraftApplyNewImpl(t *testing.T) {
+	idiom := draftTestIdiom()
+	d := &Draft{Kind: DraftNewImpl, IdiomID: 1, LanguageName: "Rust", CodeBlock: "x.sort();", Nickname: "bob"}
+	impl, err := d.Apply(idiom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idiom.Implementations) != 2 || impl.Id != 0 || impl.LanguageName != "Rust" || impl.Author != "bob" || impl.CodeBlock != "x.sort();" {
+		t.Errorf("Draft not applied: %v", idiom.Implementations)
+	}
+}
+
+func TestDraftOutdated(t *testing.T) {
+	idiom := draftTestIdiom()
+	for _, c := range []struct {
+		draft    Draft
+		outdated bool
+	}{
+		{Draft{Kind: DraftIdiom, BaseVersion: 7}, false},
+		{Draft{Kind: DraftIdiom, BaseVersion: 6}, true},
+		{Draft{Kind: DraftImpl, ImplID: 10, BaseVersion: 3}, false},
+		{Draft{Kind: DraftImpl, ImplID: 10, BaseVersion: 2}, true},
+		{Draft{Kind: DraftImpl, ImplID: 11, BaseVersion: 1}, true},
+		{Draft{Kind: DraftNewImpl}, false},
+	} {
+		if got := c.draft.Outdated(idiom); got != c.outdated {
+			t.Errorf("Outdated(%v) = %v, expected %v", c.draft, got, c.outdated)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package pig
+
+import (
+	"fmt"
+	"strings"
+)
+
+//
+// Idiom examples are sample inputs (bound to the idiom Variables)
+// and their expected outputs. They are a reference for impl authors and reviewers.
+//
+// They are stored in the parallel slices Idiom.ExampleInputs and Idiom.ExampleOutputs,
+// because the datastore doesn't allow slices of slices.
+//
+
+// Example is one sample input and its expected output.
+type Example struct {
+	Inputs []ExampleBinding
+	Output string
+}
+
+// ExampleBinding is the value of one variable in an example input.
+// Name is empty for an input line without "=".
+type ExampleBinding struct {
+	Name  string
+	Value string
+}
+
+// ParseExampleInputs reads one "name = value" binding per line.
+// Blank lines are ignored.
+func ParseExampleInputs(inputs string) []ExampleBinding {
+	var bindings []ExampleBinding
+	for _, line := range strings.Split(inputs, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var b ExampleBinding
+		if i := strings.Index(line, "="); i > 0 && !strings.HasPrefix(line[i:], "==") {
+			b.Name = strings.TrimSpace(line[:i])
+			b.Value = strings.TrimSpace(line[i+1:])
+		} else {
+			b.Value = line
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings
+}
+
+// Examples returns the structured examples of idiom.
+func (idiom *Idiom) Examples() []Example {
+	examples := make([]Example, 0, len(idiom.ExampleInputs))
+	for i, inputs := range idiom.ExampleInputs {
+		ex := Example{Inputs: ParseExampleInputs(inputs)}
+		if i < len(idiom.ExampleOutputs) {
+			ex.Output = idiom.ExampleOutputs[i]
+		}
+		examples = append(examples, ex)
+	}
+	return examples
+}
+
+// HasExamples tells if idiom has at least one example.
+func (idiom *Idiom) HasExamples() bool {
+	return len(idiom.ExampleInputs) > 0
+}
+
+// SetExamples replaces the examples of idiom. Pairs having
+// neither input nor output are discarded.
+func (idiom *Idiom) SetExamples(inputs, outputs []string) {
+	idiom.ExampleInputs = nil
+	idiom.ExampleOutputs = nil
+	for i := range inputs {
+		in := strings.TrimSpace(NoCR(inputs[i]))
+		out := ""
+		if i < len(outputs) {
+			out = strings.TrimSpace(NoCR(outputs[i]))
+		}
+		if in == "" && out == "" {
+			continue
+		}
+		idiom.ExampleInputs = append(idiom.ExampleInputs, in)
+		idiom.ExampleOutputs = append(idiom.ExampleOutputs, out)
+	}
+}
+
+// CheckExampleVariables returns an error if an example binds a name
+// which is not one of the idiom Variables.
+// Idioms without declared Variables accept any name.
+func (idiom *Idiom) CheckExampleVariables() error {
+	if len(idiom.Variables) == 0 {
+		return nil
+	}
+	for i, ex := range idiom.Examples() {
+		for _, b := range ex.Inputs {
+			if b.Name != "" && !StringSliceContains(idiom.Variables, b.Name) {
+				return fmt.Errorf("example %d: %q is not a variable of this idiom (expected one of %v)", i+1, b.Name, idiom.Variables)
+			}
+		}
+	}
+	return nil
+}
+
+// SameExamples tells if a and b have the exact same examples.
+func SameExamples(a, b *Idiom) bool {
+	if len(a.ExampleInputs) != len(b.ExampleInputs) || len(a.ExampleOutputs) != len(b.ExampleOutputs) {
+		return false
+	}
+	for i := range a.ExampleInputs {
+		if a.ExampleInputs[i] != b.ExampleInputs[i] {
+			return false
+		}
+	}
+	for i := range a.ExampleOutputs {
+		if a.ExampleOutputs[i] != b.ExampleOutputs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ResetVerifiedExamples unmarks all the impls of idiom as verified,
+// typically because the examples have changed.
+func (idiom *Idiom) ResetVerifiedExamples() {
+	for i := range idiom.Implementations {
+		idiom.Implementations[i].VerifiedExamples = false
+	}
+}
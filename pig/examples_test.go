@@ -0,0 +1,83 @@
+package pig
+
+import "testing"
+
+func TestParseExampleInputs(t *testing.T) {
+	for _, tc := range []struct {
+		inputs   string
+		expected []ExampleBinding
+	}{
+		{"", nil},
+		{"x = 3", []ExampleBinding{{"x", "3"}}},
+		{"s = \"a=b\"\n\n  n=2 ", []ExampleBinding{{"s", "\"a=b\""}, {"n", "2"}}},
+		{"[1, 2, 3]", []ExampleBinding{{"", "[1, 2, 3]"}}},
+		{"a == b", []ExampleBinding{{"", "a == b"}}},
+	} {
+		got := ParseExampleInputs(tc.inputs)
+		if len(got) != len(tc.expected) {
+			t.Errorf("ParseExampleInputs(%q): expected %v, got %v", tc.inputs, tc.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.expected[i] {
+				t.Errorf("ParseExampleInputs(%q): expected %v, got %v", tc.inputs, tc.expected, got)
+			}
+		}
+	}
+}
+
+func TestSetExamples(t *testing.T) {
+	var idiom Idiom
+	idiom.SetExamples(
+		[]string{"x = 1\r\ny = 2", "", ""},
+		[]string{"3", "", "nothing"},
+	)
+	if len(idiom.ExampleInputs) != 2 || len(idiom.ExampleOutputs) != 2 {
+		t.Fatalf("Expected 2 examples, got %q, %q", idiom.ExampleInputs, idiom.ExampleOutputs)
+	}
+	if idiom.ExampleInputs[0] != "x = 1\ny = 2" {
+		t.Errorf("Expected CRLF to be normalized, got %q", idiom.ExampleInputs[0])
+	}
+	if idiom.ExampleInputs[1] != "" || idiom.ExampleOutputs[1] != "nothing" {
+		t.Errorf("Expected output-only example, got %q -> %q", idiom.ExampleInputs[1], idiom.ExampleOutputs[1])
+	}
+	examples := idiom.Examples()
+	if len(examples) != 2 || len(examples[0].Inputs) != 2 || examples[0].Output != "3" {
+		t.Errorf("Unexpected examples %v", examples)
+	}
+}
+
+func TestCheckExampleVariables(t *testing.T) {
+	idiom := Idiom{
+		Variables:      []string{"x", "y"},
+		ExampleInputs:  []string{"x = 1\ny = 2"},
+		ExampleOutputs: []string{"3"},
+	}
+	if err := idiom.CheckExampleVariables(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	idiom.ExampleInputs = append(idiom.ExampleInputs, "z = 5")
+	idiom.ExampleOutputs = append(idiom.ExampleOutputs, "5")
+	if err := idiom.CheckExampleVariables(); err == nil {
+		t.Errorf("Expected error for unknown variable z")
+	}
+	idiom.Variables = nil
+	if err := idiom.CheckExampleVariables(); err != nil {
+		t.Errorf("Idiom without Variables should accept any name, got %v", err)
+	}
+}
+
+func TestSameExamples(t *testing.T) {
+	a := &Idiom{ExampleInputs: []string{"x = 1"}, ExampleOutputs: []string{"1"}}
+	b := &Idiom{ExampleInputs: []string{"x = 1"}, ExampleOutputs: []string{"1"}}
+	if !SameExamples(a, b) {
+		t.Errorf("Expected same examples")
+	}
+	b.ExampleOutputs[0] = "2"
+	if SameExamples(a, b) {
+		t.Errorf("Expected different examples")
+	}
+	if !SameExamples(&Idiom{}, &Idiom{ExampleInputs: []string{}}) {
+		t.Errorf("Expected nil and empty examples to be the same")
+	}
+}
@@ -34,6 +34,15 @@ type UserProfile struct {
 	Nickname          string
 	FavoriteLanguages []string
 	SeeNonFavorite    bool
+	// TargetVersions maps a language name to the version the user is working with.
+	TargetVersions map[string]string
+	// HideTooNew hides the impls requiring a newer version than TargetVersions.
+	// Otherwise, they are only flagged.
+	HideTooNew bool
+	// CheckedOnly hides the impls not yet approved by a reviewer.
+	CheckedOnly bool
+	// Locale is the human language of the idioms texts, when not the DefaultLocale.
+	Locale string
 	// IsAdmin will never be set by user himself
 	IsAdmin bool
 }
@@ -51,6 +60,9 @@ func (u UserProfile) Empty() bool {
 	return u.Nickname == "" &&
 		len(u.FavoriteLanguages) == 0 &&
 		u.SeeNonFavorite == true &&
+		len(u.TargetVersions) == 0 &&
+		u.CheckedOnly == false &&
+		u.Locale == "" &&
 		u.IsAdmin == false
 }
 
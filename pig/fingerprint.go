@@ -0,0 +1,90 @@
+package pig
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+//
+// This file is about fingerprinting code snippets, to find
+// implementations similar to a code pasted by the user.
+//
+
+// ShingleSize is the number of consecutive tokens in a shingle.
+const ShingleSize = 3
+
+// CodeFingerprint is the set of hashed token shingles of a code snippet.
+type CodeFingerprint map[uint64]bool
+
+// TokenizeCode cuts a code snippet into lowercase tokens.
+// Identifiers and numbers are single tokens, each other non-space
+// character is a token of its own.
+func TokenizeCode(code string) []string {
+	var tokens []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	for _, c := range code {
+		switch {
+		case c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			word = append(word, c)
+		case unicode.IsSpace(c):
+			flush()
+		default:
+			flush()
+			tokens = append(tokens, string(c))
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Fingerprint computes the shingle set of the concatenation of
+// the given code blocks (e.g. ImportsBlock and CodeBlock).
+// A snippet shorter than ShingleSize yields a single shingle.
+func Fingerprint(codes ...string) CodeFingerprint {
+	var tokens []string
+	for _, code := range codes {
+		tokens = append(tokens, TokenizeCode(code)...)
+	}
+	fp := make(CodeFingerprint, len(tokens))
+	if len(tokens) == 0 {
+		return fp
+	}
+	n := ShingleSize
+	if len(tokens) < n {
+		n = len(tokens)
+	}
+	for i := 0; i+n <= len(tokens); i++ {
+		h := fnv.New64a()
+		for _, token := range tokens[i : i+n] {
+			h.Write([]byte(token))
+			h.Write([]byte{0})
+		}
+		fp[h.Sum64()] = true
+	}
+	return fp
+}
+
+// Similarity is the Jaccard index of two fingerprints, between 0 and 1.
+func (fp CodeFingerprint) Similarity(other CodeFingerprint) float64 {
+	if len(fp) == 0 || len(other) == 0 {
+		return 0
+	}
+	small, big := fp, other
+	if len(small) > len(big) {
+		small, big = big, small
+	}
+	common := 0
+	for h := range small {
+		if big[h] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(fp)+len(other)-common)
+}
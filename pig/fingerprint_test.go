@@ -0,0 +1,64 @@
+package pig
+
+import (
+	"testing"
+)
+
+var tokenizeCodeTests = []struct {
+	in  string
+	out []string
+}{
+	{"", nil},
+	{"   ", nil},
+	{"x := 42", []string{"x", ":", "=", "42"}},
+	{"for i in range(N):", []string{"for", "i", "in", "range", "(", "n", ")", ":"}},
+	{"my_var.Len()", []string{"my_var", ".", "len", "(", ")"}},
+	{"café\tNé", []string{"café", "né"}},
+}
+
+func TestTokenizeCode(t *testing.T) {
+	for i, tt := range tokenizeCodeTests {
+		out := TokenizeCode(tt.in)
+		if !StringSliceEquals(out, tt.out) {
+			t.Errorf("%d. TokenizeCode(%q) => %q, want %q", i, tt.in, out, tt.out)
+		}
+	}
+}
+
+// ---
+
+var similarityTests = []struct {
+	a, b     string
+	min, max float64
+}{
+	// Empty cases
+	{"", "", 0, 0},
+	{"x := 42", "", 0, 0},
+	// Identical, modulo spaces and case
+	{"x := 42", "x:=42", 1, 1},
+	{"Sort.Ints(a)", "sort.ints( a )", 1, 1},
+	// Unrelated
+	{"x := 42", "print('hello')", 0, 0},
+	// Partial overlap
+	{"for _, x := range items {\n\tfmt.Println(x)\n}", "for _, x := range items {\n\tprint(x)\n}", 0.3, 0.9},
+}
+
+func TestSimilarity(t *testing.T) {
+	for i, tt := range similarityTests {
+		fa, fb := Fingerprint(tt.a), Fingerprint(tt.b)
+		s := fa.Similarity(fb)
+		if s < tt.min || s > tt.max {
+			t.Errorf("%d. Similarity(%q, %q) => %v, want in [%v, %v]", i, tt.a, tt.b, s, tt.min, tt.max)
+		}
+		if r := fb.Similarity(fa); r != s {
+			t.Errorf("%d. Similarity is not symmetric: %v != %v", i, s, r)
+		}
+	}
+}
+
+func TestFingerprintConcatenation(t *testing.T) {
+	imports, code := "import sys", "sys.exit(1)"
+	if s := Fingerprint(imports, code).Similarity(Fingerprint(imports + "\n" + code)); s != 1 {
+		t.Errorf("Fingerprint of separate blocks should equal fingerprint of whole, got similarity %v", s)
+	}
+}
@@ -0,0 +1,445 @@
+package pig
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//
+// This file is about server-side syntax highlighting.
+//
+// The output uses the same CSS classes as google-code-prettify
+// (kwd, typ, str, com, lit, pun, dec), so that the existing
+// stylesheet applies to both server-side and client-side highlighting.
+//
+
+// Lexer describes the lexical rules of a language, good enough for coloring.
+type Lexer struct {
+	Keywords map[string]bool
+	// Types are builtin type names.
+	Types map[string]bool
+	// Literals are named constants like true, false, nil.
+	Literals map[string]bool
+	// LineComments are the prefixes of comments running until end of line.
+	LineComments []string
+	// BlockComments are pairs of comment delimiters, e.g. {"/*", "*/"}.
+	BlockComments [][2]string
+	// LineStartBlockComments requires the block comment delimiters to be
+	// at the very start of a line, e.g. Ruby =begin and =end.
+	LineStartBlockComments bool
+	// StringDelims are the runes opening and closing a string, e.g. `"'`.
+	StringDelims string
+	// RawStringDelims are the runes delimiting multiline strings without escapes, e.g. "`" for Go.
+	RawStringDelims string
+	// TripleQuotes enables """ and ''' strings.
+	TripleQuotes bool
+	// Preprocessor enables C-like directives: a line starting with #.
+	Preprocessor bool
+	// Lifetimes enables Rust-like 'a (which is not a char literal).
+	Lifetimes bool
+	// CapitalizedTypes regards identifiers starting with an uppercase letter as types.
+	CapitalizedTypes bool
+	// CaseInsensitive keywords, e.g. in PHP.
+	CaseInsensitive bool
+	// MethodSuffixes allows identifiers ending with ? or !, e.g. Ruby defined? and sort!
+	MethodSuffixes bool
+}
+
+// Lexers maps a language name to its Lexer.
+// Languages without a Lexer are highlighted client-side.
+var Lexers = map[string]*Lexer{}
+
+// FindLexer returns the Lexer of lang, or nil.
+func FindLexer(lang string) *Lexer {
+	if lx, ok := Lexers[lang]; ok {
+		return lx
+	}
+	return Lexers[NormLang(lang)]
+}
+
+// HighlightHTML returns the HTML-escaped code, with tokens wrapped in classed spans.
+// ok is false when lang has no Lexer.
+func HighlightHTML(lang, code string) (h string, ok bool) {
+	lx := FindLexer(lang)
+	if lx == nil {
+		return "", false
+	}
+	var buf strings.Builder
+	lx.tokenize(code, func(class, text string) {
+		if class == "" {
+			buf.WriteString(html.EscapeString(text))
+			return
+		}
+		buf.WriteString(`<span class="` + class + `">`)
+		buf.WriteString(html.EscapeString(text))
+		buf.WriteString(`</span>`)
+	})
+	return buf.String(), true
+}
+
+// HighlightStyles are the inline CSS equivalents of the prettify classes,
+// for contexts where no stylesheet is available (e.g. RSS readers).
+var HighlightStyles = map[string]string{
+	"kwd": "color:#008",
+	"typ": "color:#606",
+	"str": "color:#080",
+	"com": "color:#800",
+	"lit": "color:#066",
+	"pun": "color:#660",
+	"dec": "color:#606",
+}
+
+// HighlightHTMLInline is like HighlightHTML, with inline styles instead of classes.
+func HighlightHTMLInline(lang, code string) (h string, ok bool) {
+	lx := FindLexer(lang)
+	if lx == nil {
+		return "", false
+	}
+	var buf strings.Builder
+	lx.tokenize(code, func(class, text string) {
+		style := HighlightStyles[class]
+		if style == "" {
+			buf.WriteString(html.EscapeString(text))
+			return
+		}
+		buf.WriteString(`<span style="` + style + `">`)
+		buf.WriteString(html.EscapeString(text))
+		buf.WriteString(`</span>`)
+	})
+	return buf.String(), true
+}
+
+// tokenize calls emit for each token, in order. The concatenation of
+// all the texts is exactly code. Consecutive tokens of same class are merged.
+func (lx *Lexer) tokenize(code string, emit func(class, text string)) {
+	pendingClass, pendingStart := "", 0
+	push := func(class string, start, end int) {
+		if class != pendingClass {
+			if start > pendingStart {
+				emit(pendingClass, code[pendingStart:start])
+			}
+			pendingClass, pendingStart = class, start
+		}
+	}
+
+	lineStart := true
+	i := 0
+	for i < len(code) {
+		rest := code[i:]
+		c, size := utf8.DecodeRuneInString(rest)
+		class, n := "", size
+
+		switch {
+		case c == '\n':
+			lineStart = true
+			push("", i, i+1)
+			i++
+			continue
+		case unicode.IsSpace(c):
+			class = ""
+		case lx.Preprocessor && lineStart && c == '#':
+			class, n = "dec", lineLength(rest)
+		case lx.startsLineComment(rest):
+			class, n = "com", lineLength(rest)
+		case lx.blockCommentLength(rest, i == 0 || code[i-1] == '\n') > 0:
+			class, n = "com", lx.blockCommentLength(rest, true)
+		case lx.TripleQuotes && (strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, `'''`)):
+			class, n = "str", delimitedLength(rest, rest[:3], false)
+		case strings.ContainsRune(lx.RawStringDelims, c):
+			class, n = "str", delimitedLength(rest, string(c), false)
+		case c == '\'' && lx.Lifetimes && isLifetime(rest):
+			class, n = "typ", 1+identLength(rest[1:])
+		case strings.ContainsRune(lx.StringDelims, c):
+			if m := stringLength(rest, c); m > 0 {
+				class, n = "str", m
+			} else {
+				class = "pun"
+			}
+		case unicode.IsDigit(c) || (c == '.' && len(rest) > 1 && rest[1] >= '0' && rest[1] <= '9'):
+			class, n = "lit", numberLength(rest)
+		case c == '_' || c == '$' || c == '@' || unicode.IsLetter(c):
+			n = identLength(rest)
+			if lx.MethodSuffixes {
+				n += methodSuffixLength(rest[n:])
+			}
+			class = lx.classifyWord(rest[:n])
+		default:
+			class = "pun"
+		}
+		if class != "" || !unicode.IsSpace(c) {
+			lineStart = false
+		}
+		push(class, i, i+n)
+		i += n
+	}
+	if len(code) > pendingStart {
+		emit(pendingClass, code[pendingStart:])
+	}
+}
+
+func (lx *Lexer) classifyWord(word string) string {
+	key := word
+	if lx.CaseInsensitive {
+		key = strings.ToLower(word)
+	}
+	switch {
+	case lx.Keywords[key]:
+		return "kwd"
+	case lx.Literals[key]:
+		return "lit"
+	case lx.Types[word]:
+		return "typ"
+	case lx.CapitalizedTypes:
+		if r, _ := utf8.DecodeRuneInString(word); unicode.IsUpper(r) {
+			return "typ"
+		}
+	}
+	return ""
+}
+
+func (lx *Lexer) startsLineComment(s string) bool {
+	for _, prefix := range lx.LineComments {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockCommentLength is the length of the block comment starting at s[0], or 0.
+// atLineStart tells if s[0] is the first character of a line.
+func (lx *Lexer) blockCommentLength(s string, atLineStart bool) int {
+	if lx.LineStartBlockComments && !atLineStart {
+		return 0
+	}
+	for _, delims := range lx.BlockComments {
+		if !strings.HasPrefix(s, delims[0]) {
+			continue
+		}
+		closing := delims[1]
+		if lx.LineStartBlockComments {
+			closing = "\n" + closing
+		}
+		end := strings.Index(s[len(delims[0]):], closing)
+		if end == -1 {
+			return len(s)
+		}
+		n := len(delims[0]) + end + len(closing)
+		if lx.LineStartBlockComments {
+			// The rest of the closing line is a comment too
+			n += lineLength(s[n:])
+		}
+		return n
+	}
+	return 0
+}
+
+func lineLength(s string) int {
+	if n := strings.IndexByte(s, '\n'); n != -1 {
+		return n
+	}
+	return len(s)
+}
+
+// delimitedLength is the length of s up to the closing delim (included),
+// or the full length if not closed.
+func delimitedLength(s string, delim string, escapes bool) int {
+	for i := len(delim); i < len(s); i++ {
+		if escapes && s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], delim) {
+			return i + len(delim)
+		}
+	}
+	return len(s)
+}
+
+// stringLength is the length of a single-line string literal starting at s[0],
+// or 0 if it isn't closed on the same line.
+func stringLength(s string, quote rune) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\n':
+			return 0
+		case byte(quote):
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func identLength(s string) int {
+	for i, c := range s {
+		if i > 0 && c == '$' {
+			// e.g. PHP "$a$b" is 2 variables
+			return i
+		}
+		if !(c == '_' || c == '$' || c == '@' || unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// methodSuffixLength is 1 if s starts with a ? or ! ending an identifier,
+// but not with the operator != or ?= .
+func methodSuffixLength(s string) int {
+	if len(s) == 0 || (s[0] != '?' && s[0] != '!') {
+		return 0
+	}
+	if len(s) > 1 && s[1] == '=' {
+		return 0
+	}
+	return 1
+}
+
+func numberLength(s string) int {
+	for i, c := range s {
+		if !(c == '.' || c == '_' || unicode.IsDigit(c) || unicode.IsLetter(c)) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// isLifetime recognizes 'a but not 'a'
+func isLifetime(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	c, size := utf8.DecodeRuneInString(s[1:])
+	if !(c == '_' || unicode.IsLetter(c)) {
+		return false
+	}
+	return len(s) <= 1+size || s[1+size] != '\''
+}
+
+func wordSet(words string) map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		m[w] = true
+	}
+	return m
+}
+
+func init() {
+	cKeywords := "auto break case const continue default do else enum extern for goto if inline register restrict return sizeof static struct switch typedef union volatile while"
+	cTypes := "char double float int long short signed unsigned void bool size_t int8_t int16_t int32_t int64_t uint8_t uint16_t uint32_t uint64_t FILE"
+	cComments := [][2]string{{"/*", "*/"}}
+
+	Lexers["C"] = &Lexer{
+		Keywords:      wordSet(cKeywords),
+		Types:         wordSet(cTypes),
+		Literals:      wordSet("NULL true false"),
+		LineComments:  []string{"//"},
+		BlockComments: cComments,
+		StringDelims:  `"'`,
+		Preprocessor:  true,
+	}
+	Lexers["Cpp"] = &Lexer{
+		Keywords:      wordSet(cKeywords + " alignas alignof catch class concept consteval constexpr constinit co_await co_return co_yield decltype delete explicit export friend mutable namespace new noexcept operator override final private protected public requires static_assert template this throw try typeid typename using virtual"),
+		Types:         wordSet(cTypes + " wchar_t char8_t char16_t char32_t string vector map set unordered_map auto"),
+		Literals:      wordSet("NULL nullptr true false"),
+		LineComments:  []string{"//"},
+		BlockComments: cComments,
+		StringDelims:  `"'`,
+		Preprocessor:  true,
+	}
+	Lexers["Obj-C"] = &Lexer{
+		Keywords:      wordSet(cKeywords + " @interface @implementation @end @property @synthesize @protocol @selector @class @autoreleasepool self super in id instancetype"),
+		Types:         wordSet(cTypes + " BOOL NSInteger NSUInteger CGFloat"),
+		Literals:      wordSet("nil Nil NULL YES NO true false"),
+		LineComments:  []string{"//"},
+		BlockComments: cComments,
+		StringDelims:  `"'`,
+		Preprocessor:  true,
+	}
+	Lexers["Csharp"] = &Lexer{
+		Keywords:         wordSet("abstract as async await base break case catch checked class const continue default delegate do else enum event explicit extern finally fixed for foreach goto if implicit in interface internal is lock namespace new operator out override params private protected public readonly record ref return sealed sizeof stackalloc static struct switch this throw try typeof unchecked unsafe using var virtual volatile when where while yield"),
+		Types:            wordSet("bool byte char decimal double dynamic float int long object sbyte short string uint ulong ushort void"),
+		Literals:         wordSet("true false null"),
+		LineComments:     []string{"//"},
+		BlockComments:    cComments,
+		StringDelims:     `"'`,
+		CapitalizedTypes: true,
+	}
+	Lexers["Go"] = &Lexer{
+		Keywords:        wordSet("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var"),
+		Types:           wordSet("any bool byte comparable complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr"),
+		Literals:        wordSet("true false nil iota"),
+		LineComments:    []string{"//"},
+		BlockComments:   cComments,
+		StringDelims:    `"'`,
+		RawStringDelims: "`",
+	}
+	Lexers["Java"] = &Lexer{
+		Keywords:         wordSet("abstract assert break case catch class const continue default do else enum extends final finally for goto if implements import instanceof interface native new package private protected public record return static strictfp super switch synchronized this throw throws transient try var volatile while yield"),
+		Types:            wordSet("boolean byte char double float int long short void"),
+		Literals:         wordSet("true false null"),
+		LineComments:     []string{"//"},
+		BlockComments:    cComments,
+		StringDelims:     `"'`,
+		TripleQuotes:     true,
+		CapitalizedTypes: true,
+	}
+	Lexers["JS"] = &Lexer{
+		Keywords:        wordSet("async await break case catch class const continue debugger default delete do else export extends finally for function if import in instanceof let new of return static super switch this throw try typeof var void while with yield"),
+		Literals:        wordSet("true false null undefined NaN Infinity"),
+		LineComments:    []string{"//"},
+		BlockComments:   cComments,
+		StringDelims:    `"'`,
+		RawStringDelims: "`",
+	}
+	Lexers["PHP"] = &Lexer{
+		Keywords:        wordSet("abstract and array as break callable case catch class clone const continue declare default do echo else elseif empty enddeclare endfor endforeach endif endswitch endwhile extends final finally fn for foreach function global goto if implements include include_once instanceof insteadof interface isset list match namespace new or print private protected public readonly require require_once return static switch throw trait try unset use var while xor yield"),
+		Types:           wordSet("int float bool string void mixed object iterable"),
+		Literals:        wordSet("true false null"),
+		LineComments:    []string{"//", "#"},
+		BlockComments:   cComments,
+		StringDelims:    `"'`,
+		CaseInsensitive: true,
+	}
+	Lexers["Python"] = &Lexer{
+		Keywords:     wordSet("and as assert async await break class continue def del elif else except finally for from global if import in is lambda match nonlocal not or pass raise return try while with yield"),
+		Types:        wordSet("bool bytes dict float frozenset int list object set str tuple"),
+		Literals:     wordSet("True False None"),
+		LineComments: []string{"#"},
+		StringDelims: `"'`,
+		TripleQuotes: true,
+	}
+	Lexers["Ruby"] = &Lexer{
+		Keywords:               wordSet("alias and begin break case class def defined? do else elsif end ensure for if in module next not or redo rescue retry return self super then undef unless until when while yield require puts"),
+		Literals:               wordSet("true false nil"),
+		LineComments:           []string{"#"},
+		BlockComments:          [][2]string{{"=begin", "=end"}},
+		LineStartBlockComments: true,
+		StringDelims:           `"'`,
+		CapitalizedTypes:       true,
+		MethodSuffixes:         true,
+	}
+	Lexers["Rust"] = &Lexer{
+		Keywords:         wordSet("as async await break const continue crate dyn else enum extern fn for if impl in let loop match mod move mut pub ref return self static struct super trait type unsafe use where while"),
+		Types:            wordSet("bool char f32 f64 i8 i16 i32 i64 i128 isize str u8 u16 u32 u64 u128 usize"),
+		Literals:         wordSet("true false None Some Ok Err"),
+		LineComments:     []string{"//"},
+		BlockComments:    cComments,
+		StringDelims:     `"'`,
+		Lifetimes:        true,
+		CapitalizedTypes: true,
+	}
+	Lexers["Kotlin"] = &Lexer{
+		Keywords:         wordSet("abstract as break by catch class companion const constructor continue data do else enum external final finally for fun if import in infix init inline inner interface internal is lateinit object open operator out override package private protected public reified return sealed super suspend this throw try typealias val var vararg when where while"),
+		Literals:         wordSet("true false null"),
+		LineComments:     []string{"//"},
+		BlockComments:    cComments,
+		StringDelims:     `"'`,
+		TripleQuotes:     true,
+		CapitalizedTypes: true,
+	}
+}
@@ -0,0 +1,56 @@
+package pig
+
+import (
+	"testing"
+)
+
+var highlightTests = []struct {
+	lang string
+	code string
+	out  string
+}{
+	{"Go", `x := 42`, `x <span class="pun">:=</span> <span class="lit">42</span>`},
+	{"Go", `return nil // done`, `<span class="kwd">return</span> <span class="lit">nil</span> <span class="com">// done</span>`},
+	{"Go", `var s string = "a<b"`, `<span class="kwd">var</span> s <span class="typ">string</span> <span class="pun">=</span> <span class="str">&#34;a&lt;b&#34;</span>`},
+	{"Go", "`raw\nstring`", "<span class=\"str\">`raw\nstring`</span>"},
+	{"golang", `/* a */ if`, `<span class="com">/* a */</span> <span class="kwd">if</span>`},
+	{"Python", `def f(): return None  # x`, `<span class="kwd">def</span> f<span class="pun">():</span> <span class="kwd">return</span> <span class="lit">None</span>  <span class="com"># x</span>`},
+	{"Python", `s = """a"b"""`, `s <span class="pun">=</span> <span class="str">&#34;&#34;&#34;a&#34;b&#34;&#34;&#34;</span>`},
+	{"C", "#include <stdio.h>\nint x;", "<span class=\"dec\">#include &lt;stdio.h&gt;</span>\n<span class=\"typ\">int</span> x<span class=\"pun\">;</span>"},
+	{"Rust", `fn f<'a>(c: char) { 'z' }`, `<span class="kwd">fn</span> f<span class="pun">&lt;</span><span class="typ">&#39;a</span><span class="pun">&gt;(</span>c<span class="pun">:</span> <span class="typ">char</span><span class="pun">)</span> <span class="pun">{</span> <span class="str">&#39;z&#39;</span> <span class="pun">}</span>`},
+	{"Java", `String s = "\"";`, `<span class="typ">String</span> s <span class="pun">=</span> <span class="str">&#34;\&#34;&#34;</span><span class="pun">;</span>`},
+	{"PHP", `ECHO $x;`, `<span class="kwd">ECHO</span> $x<span class="pun">;</span>`},
+	{"Ruby", `puts "it's"`, `<span class="kwd">puts</span> <span class="str">&#34;it&#39;s&#34;</span>`},
+	{"Ruby", `defined?(x)`, `<span class="kwd">defined?</span><span class="pun">(</span>x<span class="pun">)</span>`},
+	{"Ruby", `a!=b`, `a<span class="pun">!=</span>b`},
+	{"Ruby", "=begin\nx\n=end doc\ny", "<span class=\"com\">=begin\nx\n=end doc</span>\ny"},
+	{"Ruby", `x =begin`, `x <span class="pun">=</span><span class="kwd">begin</span>`},
+	{"Ruby", "=begin\nx =end\n=end", "<span class=\"com\">=begin\nx =end\n=end</span>"},
+}
+
+func TestHighlightHTML(t *testing.T) {
+	for i, tt := range highlightTests {
+		out, ok := HighlightHTML(tt.lang, tt.code)
+		if !ok {
+			t.Errorf("%d. HighlightHTML(%q, %q) not supported", i, tt.lang, tt.code)
+			continue
+		}
+		if out != tt.out {
+			t.Errorf("%d. HighlightHTML(%q, %q) => \n%s\n, want \n%s", i, tt.lang, tt.code, out, tt.out)
+		}
+	}
+}
+
+func TestHighlightHTMLUnsupported(t *testing.T) {
+	if _, ok := HighlightHTML("Cobol", "DISPLAY 'Hello'."); ok {
+		t.Errorf("Cobol was not expected to have a server-side lexer")
+	}
+}
+
+func TestHighlightMainstreamLexers(t *testing.T) {
+	for _, lang := range append(MainStreamLanguages(), "Rust", "Kotlin") {
+		if FindLexer(lang) == nil {
+			t.Errorf("Missing lexer for %q", lang)
+		}
+	}
+}
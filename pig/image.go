@@ -0,0 +1,287 @@
+package pig
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register GIF decoding
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+//
+// Uploaded pictures of idioms and impls are validated, then resized
+// into a few variants (thumbnail, full size), and kept in a BlobStore.
+// Blob names are derived from the image contents, so they never change
+// and can be cached forever.
+//
+
+// MaxImageUploadBytes is the maximum size of an uploaded picture.
+const MaxImageUploadBytes = 2 << 20
+
+// MaxImagePixels is the maximum width*height of an uploaded picture,
+// checked before decoding.
+const MaxImagePixels = 2000 * 2000
+
+// ImageVariant is a resized version of the uploaded picture.
+type ImageVariant struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}
+
+// ImageVariants are generated for each uploaded picture.
+var ImageVariants = []ImageVariant{
+	{Name: "thumb", MaxWidth: 160, MaxHeight: 160},
+	{Name: "full", MaxWidth: 800, MaxHeight: 800},
+}
+
+// ProcessedImage is a validated and resized upload.
+type ProcessedImage struct {
+	// Hash of the original contents.
+	Hash        string
+	ContentType string
+	Width       int
+	Height      int
+	// Blobs are the encoded variants, by variant name.
+	Blobs map[string][]byte
+}
+
+// BlobName is the name of the variant in the BlobStore, e.g. "3f786850e387550fdab836ed7e6dc881de23001b-thumb.png".
+func (pi *ProcessedImage) BlobName(variant string) string {
+	ext := ".png"
+	if pi.ContentType == "image/jpeg" {
+		ext = ".jpg"
+	}
+	return pi.Hash + "-" + variant + ext
+}
+
+// PendingBlobName is the name of a variant waiting for moderation.
+// Pending blobs are never served publicly: they are moved to their
+// public name when the upload is approved.
+func PendingBlobName(name string) string {
+	return "pending-" + name
+}
+
+var publicImageNameRegexp = regexp.MustCompile(`^([0-9a-f]{40})-[a-z]+\.(png|jpg)$`)
+
+// ImageHashOfBlobName returns the hash of the upload of a public variant name.
+func ImageHashOfBlobName(name string) (hash string, ok bool) {
+	m := publicImageNameRegexp.FindStringSubmatch(name)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+var allowedImageTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// ProcessImage validates the uploaded data, and generates the ImageVariants.
+// GIF pictures are converted to PNG.
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	if len(data) > MaxImageUploadBytes {
+		return nil, fmt.Errorf("picture is too large (%d kB, max %d kB)", len(data)>>10, MaxImageUploadBytes>>10)
+	}
+	contentType := http.DetectContentType(data)
+	if !StringSliceContains(allowedImageTypes, contentType) {
+		return nil, fmt.Errorf("unsupported picture type %q, expected PNG, JPEG or GIF", contentType)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid picture: %v", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxImagePixels {
+		return nil, fmt.Errorf("picture dimensions %dx%d are not accepted (max %d pixels)", config.Width, config.Height, MaxImagePixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid picture: %v", err)
+	}
+	if contentType == "image/gif" {
+		contentType = "image/png"
+	}
+
+	pi := &ProcessedImage{
+		Hash:        fmt.Sprintf("%x", sha1.Sum(data)),
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		Blobs:       map[string][]byte{},
+	}
+	for _, variant := range ImageVariants {
+		resized := ResizeImage(img, variant.MaxWidth, variant.MaxHeight)
+		var buf bytes.Buffer
+		if contentType == "image/jpeg" {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return nil, err
+		}
+		pi.Blobs[variant.Name] = buf.Bytes()
+	}
+	return pi, nil
+}
+
+// ResizeImage scales img down to fit in maxWidth x maxHeight, keeping
+// the aspect ratio. Each pixel is the average of the source pixels it covers.
+// Smaller images are returned unchanged.
+func ResizeImage(img image.Image, maxWidth, maxHeight int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxWidth && h <= maxHeight {
+		return img
+	}
+	nw, nh := maxWidth, h*maxWidth/w
+	if nh > maxHeight {
+		nw, nh = w*maxHeight/h, maxHeight
+	}
+	if nw < 1 {
+		nw = 1
+	}
+	if nh < 1 {
+		nh = 1
+	}
+
+	// Read straight from the decoded image: no full-size copy
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		y0, y1 := b.Min.Y+y*h/nh, b.Min.Y+(y+1)*h/nh
+		for x := 0; x < nw; x++ {
+			x0, x1 := b.Min.X+x*w/nw, b.Min.X+(x+1)*w/nw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8)})
+		}
+	}
+	return dst
+}
+
+// ImageUploadStatus is the moderation status of an ImageUpload.
+type ImageUploadStatus string
+
+const (
+	// ImageUploadPending waits for an admin decision.
+	ImageUploadPending ImageUploadStatus = "pending"
+	// ImageUploadApproved has been applied to the idiom or impl.
+	ImageUploadApproved ImageUploadStatus = "approved"
+	// ImageUploadRejected has been refused, and its blobs deleted.
+	ImageUploadRejected ImageUploadStatus = "rejected"
+)
+
+// ImageUpload is a picture uploaded for an idiom (ImplID 0) or an impl.
+// Its Datastore key name is its KeyName. The blobs are named after the Hash only,
+// so the same picture uploaded for several idioms or impls is stored once.
+type ImageUpload struct {
+	Hash        string
+	IdiomID     int
+	ImplID      int
+	Uploader    string
+	UploadDate  time.Time
+	ContentType string
+	Width       int
+	Height      int
+	// ThumbName and FullName are the names of the variants in the BlobStore.
+	ThumbName    string
+	FullName     string
+	Status       ImageUploadStatus
+	Decider      string
+	DecisionDate time.Time
+}
+
+// KeyName is the Datastore key name of the upload of a picture for a
+// given idiom or impl, e.g. "3f786850e387550fdab836ed7e6dc881de23001b_12_345".
+func (u *ImageUpload) KeyName() string {
+	return ImageUploadKeyName(u.Hash, u.IdiomID, u.ImplID)
+}
+
+// ImageUploadKeyName is the KeyName of the upload of picture hash for idiomID and implID.
+func ImageUploadKeyName(hash string, idiomID, implID int) string {
+	return fmt.Sprintf("%s_%d_%d", hash, idiomID, implID)
+}
+
+// ErrBlobNotFound is returned by BlobStore.Get for an unknown name.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps the pictures. Implementations must be safe for concurrent use.
+type BlobStore interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// LocalBlobStore is a BlobStore in a directory of the local filesystem.
+type LocalBlobStore struct {
+	Dir string
+}
+
+var blobNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._\-]*$`)
+
+func (s LocalBlobStore) path(name string) (string, error) {
+	if !blobNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid blob name %q", name)
+	}
+	return filepath.Join(s.Dir, name), nil
+}
+
+// Put writes the blob file.
+func (s LocalBlobStore) Put(ctx context.Context, name string, data []byte) error {
+	p, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	// Write then rename, so that readers never see a partial file
+	tmp := p + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// Get reads the blob file.
+func (s LocalBlobStore) Get(ctx context.Context, name string) ([]byte, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return nil, ErrBlobNotFound
+	}
+	data, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+// Delete removes the blob file, if it exists.
+func (s LocalBlobStore) Delete(ctx context.Context, name string) error {
+	p, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package pig
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func encodeTestPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessImage(t *testing.T) {
+	pi, err := ProcessImage(encodeTestPNG(t, 1000, 500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pi.ContentType != "image/png" || pi.Width != 1000 || pi.Height != 500 {
+		t.Errorf("Unexpected image %v %dx%d", pi.ContentType, pi.Width, pi.Height)
+	}
+	for name, size := range map[string]image.Point{"thumb": {160, 80}, "full": {800, 400}} {
+		img, err := png.Decode(bytes.NewReader(pi.Blobs[name]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Size() != size {
+			t.Errorf("Expected %s of size %v, got %v", name, size, img.Bounds().Size())
+		}
+	}
+	if name := pi.BlobName("thumb"); name != pi.Hash+"-thumb.png" {
+		t.Errorf("Unexpected blob name %q", name)
+	}
+	if hash, ok := ImageHashOfBlobName(pi.BlobName("full")); !ok || hash != pi.Hash {
+		t.Errorf("Expected hash %q, got %q %v", pi.Hash, hash, ok)
+	}
+	for _, name := range []string{
+		PendingBlobName(pi.BlobName("full")),
+		pi.Hash + "-full.png.bak",
+		"../" + pi.BlobName("full"),
+		"",
+	} {
+		if _, ok := ImageHashOfBlobName(name); ok {
+			t.Errorf("Expected %q not to be a public name", name)
+		}
+	}
+}
+
+func TestProcessImageRejects(t *testing.T) {
+	if _, err := ProcessImage([]byte("<html>not a picture</html>")); err == nil {
+		t.Errorf("Expected error for HTML")
+	}
+	if _, err := ProcessImage(make([]byte, MaxImageUploadBytes+1)); err == nil {
+		t.Errorf("Expected error for a too large upload")
+	}
+	truncated := encodeTestPNG(t, 10, 10)[:30]
+	if _, err := ProcessImage(truncated); err == nil {
+		t.Errorf("Expected error for a truncated PNG")
+	}
+	var huge bytes.Buffer
+	if err := png.Encode(&huge, image.NewGray(image.Rect(0, 0, 3000, 2000))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProcessImage(huge.Bytes()); err == nil {
+		t.Errorf("Expected error for too many pixels")
+	}
+}
+
+func TestResizeImageKeepsSmallImages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	if ResizeImage(img, 160, 160) != image.Image(img) {
+		t.Errorf("Expected small image to be unchanged")
+	}
+}
+
+func TestResizeImageAverages(t *testing.T) {
+	img := image.NewGray(image.Rect(10, 10, 14, 12))
+	for x := 10; x < 14; x++ {
+		img.SetGray(x, 10, color.Gray{Y: 200})
+	}
+	resized := ResizeImage(img, 2, 2)
+	if size := resized.Bounds().Size(); size != (image.Point{2, 1}) {
+		t.Fatalf("Expected size 2x1, got %v", size)
+	}
+	if c := resized.At(1, 0).(color.RGBA); c != (color.RGBA{R: 100, G: 100, B: 100, A: 255}) {
+		t.Errorf("Expected average gray, got %v", c)
+	}
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := LocalBlobStore{Dir: dir}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "a-thumb.png", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := store.Get(ctx, "a-thumb.png")
+	if err != nil || string(data) != "data" {
+		t.Errorf("Unexpected %q, %v", data, err)
+	}
+	if err := store.Delete(ctx, "a-thumb.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "a-thumb.png"); err != ErrBlobNotFound {
+		t.Errorf("Expected ErrBlobNotFound, got %v", err)
+	}
+	if err := store.Put(ctx, "../escape", nil); err == nil {
+		t.Errorf("Expected error for a path outside of the store")
+	}
+}
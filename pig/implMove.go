@@ -0,0 +1,60 @@
+package pig
+
+import (
+	"fmt"
+)
+
+//
+// An implementation may be moved (or copied) by an admin to another idiom,
+// when it answers the other idiom better.
+//
+
+// MoveImpl moves the impl implID from idiom into target.
+// The impl keeps its ID, author, version and rating.
+func (idiom *Idiom) MoveImpl(implID int, target *Idiom) (*Impl, error) {
+	if idiom.Id == target.Id {
+		return nil, fmt.Errorf("impl %d is already in idiom %d", implID, idiom.Id)
+	}
+	i, impl, found := idiom.FindImplInIdiom(implID)
+	if !found {
+		return nil, fmt.Errorf("could not find impl %d in idiom %d", implID, idiom.Id)
+	}
+	moved := *impl
+	idiom.Implementations = append(idiom.Implementations[:i], idiom.Implementations[i+1:]...)
+	idiom.ImplCount = len(idiom.Implementations)
+	idiom.LastEditedImplID = 0
+	idiom.EditSummary = fmt.Sprintf("Moved %s impl %d to idiom #%d [%v]", moved.LanguageName, implID, target.Id, target.Title)
+
+	target.Implementations = append(target.Implementations, moved)
+	target.ImplCount = len(target.Implementations)
+	target.LastEditedImplID = implID
+	target.EditSummary = fmt.Sprintf("Moved %s impl %d from idiom #%d [%v]", moved.LanguageName, implID, idiom.Id, idiom.Title)
+	_, impl, _ = target.FindImplInIdiom(implID)
+	return impl, nil
+}
+
+// CopyImpl copies the impl implID from idiom into target, as a new impl newImplID.
+// The copy keeps the author, and its OrigId is implID. It starts at version 1,
+// without any vote.
+func (idiom *Idiom) CopyImpl(implID int, target *Idiom, newImplID int) (*Impl, error) {
+	_, impl, found := idiom.FindImplInIdiom(implID)
+	if !found {
+		return nil, fmt.Errorf("could not find impl %d in idiom %d", implID, idiom.Id)
+	}
+	if _, _, exists := target.FindImplInIdiom(newImplID); exists {
+		return nil, fmt.Errorf("impl %d already exists in idiom %d", newImplID, target.Id)
+	}
+	copied := *impl
+	copied.Id = newImplID
+	copied.OrigId = implID
+	copied.Version = 1
+	copied.Rating = 0
+	copied.Deco = ImplRenderingDecoration{}
+
+	target.Implementations = append(target.Implementations, copied)
+	target.ImplCount = len(target.Implementations)
+	target.LastEditedImplID = newImplID
+	target.EditSummary = fmt.Sprintf("Copied %s impl %d from idiom #%d [%v]", copied.LanguageName, implID, idiom.Id, idiom.Title)
+	_, impl, _ = target.FindImplInIdiom(newImplID)
+	return impl, nil
+}
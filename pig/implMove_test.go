@@ -0,0 +1,68 @@
+package pig
+
+import (
+	"testing"
+)
+
+func TestMoveImpl(t *testing.T) {
+	source := &Idiom{
+		Id:    1,
+		Title: "Sort a list",
+		Implementations: []Impl{
+			{Id: 10, LanguageName: "Go", Author: "alice", Version: 3, Rating: 5},
+			{Id: 11, LanguageName: "Rust"},
+		},
+		ImplCount: 2,
+	}
+	target := &Idiom{
+		Id:              2,
+		Title:           "Sort a list, stable",
+		Implementations: []Impl{{Id: 20, LanguageName: "Go"}},
+		ImplCount:       1,
+	}
+	impl, err := source.MoveImpl(10, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if impl.Id != 10 || impl.Author != "alice" || impl.Version != 3 || impl.Rating != 5 {
+		t.Errorf("Unexpected moved impl %v", impl)
+	}
+	if source.ImplCount != 1 || source.Implementations[0].Id != 11 {
+		t.Errorf("Unexpected source impls %v", source.Implementations)
+	}
+	if target.ImplCount != 2 || target.LastEditedImplID != 10 {
+		t.Errorf("Unexpected target impls %v", target.Implementations)
+	}
+	if _, _, found := target.FindImplInIdiom(10); !found {
+		t.Errorf("Moved impl not found in target")
+	}
+
+	if _, err := source.MoveImpl(10, target); err == nil {
+		t.Errorf("Expected error for impl not in source")
+	}
+	if _, err := target.MoveImpl(20, target); err == nil {
+		t.Errorf("Expected error when moving into the same idiom")
+	}
+}
+
+func TestCopyImpl(t *testing.T) {
+	source := &Idiom{
+		Id:              1,
+		Implementations: []Impl{{Id: 10, LanguageName: "Go", Author: "alice", Version: 3, Rating: 5}},
+		ImplCount:       1,
+	}
+	target := &Idiom{Id: 2}
+	impl, err := source.CopyImpl(10, target, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if impl.Id != 30 || impl.OrigId != 10 || impl.Author != "alice" || impl.Version != 1 || impl.Rating != 0 {
+		t.Errorf("Unexpected copied impl %v", impl)
+	}
+	if source.ImplCount != 1 || target.ImplCount != 1 {
+		t.Errorf("Unexpected impl counts %d, %d", source.ImplCount, target.ImplCount)
+	}
+	if _, err := source.CopyImpl(10, target, 30); err == nil {
+		t.Errorf("Expected error for existing impl ID")
+	}
+}
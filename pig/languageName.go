@@ -1,85 +1,194 @@
 package pig
 
 import (
-	"fmt"
-	"os"
+	"net/url"
 	"sort"
 	"strings"
+	"sync"
 )
 
 //
 // Language names exist in 3 forms : nice, standard, lowercase
 // Ex : "C++", "Cpp", "cpp"
 //
+// The supported languages are data-driven: see Language and SetLanguages.
+// Until the Language entities are loaded from the datastore,
+// DefaultLanguages are used.
+//
 
-var mainStreamLangs = [...]string{"C", "Cpp", "Csharp", "Go", "Java", "JS", "Obj-C", "PHP", "Python", "Ruby"}
+// Language is a programming language supported by the site.
+type Language struct {
+	// Name is the canonical name, used in URLs and in Impl.LanguageName. Ex: "Cpp"
+	Name string
+	// NiceName is the display name. Ex: "C++"
+	NiceName string
+	// Aliases are other names recognized by NormLang. Ex: "c++", "cc"
+	Aliases []string
+	// Extensions are the usual source file extensions, without dot. Ex: "cpp", "hpp"
+	Extensions []string
+	// Keywords are extra search keywords for this language.
+	Keywords []string
+	// HighlighterClass is the syntax coloring CSS class suffix. Ex: "cs" for "lang-cs"
+	HighlighterClass string
+	// HighlighterScript is an optional extra syntax coloring JS script. Ex: "lang-go.js"
+	HighlighterScript string
+	// DocURLTemplate is a documentation search URL, where %s is replaced by a term.
+	DocURLTemplate string
+	// PlaygroundURLTemplate is an online playground URL, where %s is replaced by the escaped code.
+	// It is not used for Go, Rust, C and C++, which have their own playground links.
+	PlaygroundURLTemplate string
+	// Mainstream languages are displayed first.
+	Mainstream bool
+	// Similar are the closest languages, by decreasing affinity. Ex: "Java" for "Kotlin"
+	// They are suggested when an idiom has no impl in this language.
+	Similar []string
+}
 
-// Return alpha codes for each language (no encoding problems).
-// See PrintNiceLang to display them more fancy.
-func MainStreamLanguages() []string {
-	return mainStreamLangs[:]
+// Nice returns the display name of lg.
+func (lg *Language) Nice() string {
+	if lg.NiceName != "" {
+		return lg.NiceName
+	}
+	return lg.Name
 }
 
-var moreLangs = [...]string{"Ada", "Caml", "Clojure", "Cobol", "D", "Dart", "Elixir", "Erlang", "Fortran", "Groovy", "Haskell", "Kotlin", "Lua", "Lisp", "Pascal", "Perl", "Prolog", "Rust", "Scala", "Scheme", "VB"}
+// DocURL returns the documentation search URL for term, or "" if lg has no DocURLTemplate.
+func (lg *Language) DocURL(term string) string {
+	return expandURLTemplate(lg.DocURLTemplate, term)
+}
 
-func MoreLanguages() []string {
-	// These do *not* include the MainStreamLanguages()
-	return moreLangs[:]
+// PlaygroundURL returns the playground URL opening code, or "" if lg has no PlaygroundURLTemplate.
+func (lg *Language) PlaygroundURL(code string) string {
+	return expandURLTemplate(lg.PlaygroundURLTemplate, code)
 }
 
-var synonymLangs = map[string]string{
-	"Javascript":   "JS",
-	"Objective C":  "Obj-C",
-	"Visual Basic": "VB",
+func expandURLTemplate(tmpl, value string) string {
+	if tmpl == "" {
+		return ""
+	}
+	return strings.Replace(tmpl, "%s", url.QueryEscape(value), 1)
 }
 
-var AllLangs []string
-var AllNiceLangs []string
+// languageRegistry holds the supported languages and precomputed lookups.
+// It is immutable once built: SetLanguages replaces it as a whole.
+type languageRegistry struct {
+	byName          map[string]*Language
+	mainstream      []string
+	more            []string
+	all             []string
+	allNice         []string
+	lowerCaseIndex  map[string]string
+	autocompletions map[string][]string
+}
 
-func AllLanguages() []string {
-	if AllLangs == nil {
-		mainstream := MainStreamLanguages()
-		more := MoreLanguages()
-		AllLangs = make([]string, len(mainstream)+len(more))
-		copy(AllLangs, mainstream)
-		copy(AllLangs[len(mainstream):], more)
-		sort.Strings(AllLangs)
-		AllNiceLangs = make([]string, len(AllLangs))
-		for i, lg := range AllLangs {
-			AllNiceLangs[i] = PrintNiceLang(lg)
+var (
+	languagesMutex sync.RWMutex
+	languages      = buildLanguageRegistry(DefaultLanguages())
+)
+
+func currentLanguages() *languageRegistry {
+	languagesMutex.RLock()
+	defer languagesMutex.RUnlock()
+	return languages
+}
+
+// SetLanguages replaces the set of supported languages.
+func SetLanguages(langs []*Language) {
+	reg := buildLanguageRegistry(langs)
+	languagesMutex.Lock()
+	languages = reg
+	languagesMutex.Unlock()
+}
+
+// Languages returns the supported languages, sorted by Name.
+func Languages() []*Language {
+	reg := currentLanguages()
+	langs := make([]*Language, len(reg.all))
+	for i, name := range reg.all {
+		langs[i] = reg.byName[name]
+	}
+	return langs
+}
+
+// FindLanguage returns the Language having name (or alias) lang, or nil.
+func FindLanguage(lang string) *Language {
+	reg := currentLanguages()
+	return reg.byName[reg.lowerCaseIndex[strings.TrimSpace(strings.ToLower(lang))]]
+}
+
+func buildLanguageRegistry(langs []*Language) *languageRegistry {
+	reg := &languageRegistry{
+		byName:         make(map[string]*Language, len(langs)),
+		lowerCaseIndex: make(map[string]string, 4*len(langs)),
+	}
+	for _, lg := range langs {
+		if lg.Name == "" {
+			continue
+		}
+		reg.byName[lg.Name] = lg
+		reg.all = append(reg.all, lg.Name)
+		if lg.Mainstream {
+			reg.mainstream = append(reg.mainstream, lg.Name)
+		} else {
+			reg.more = append(reg.more, lg.Name)
+		}
+	}
+	sort.Strings(reg.all)
+	sort.Strings(reg.mainstream)
+	sort.Strings(reg.more)
+
+	reg.allNice = make([]string, len(reg.all))
+	for i, name := range reg.all {
+		reg.allNice[i] = reg.byName[name].Nice()
+	}
+
+	// Aliases first, so that they never shadow a real name or nice name.
+	for _, name := range reg.all {
+		for _, alias := range reg.byName[name].Aliases {
+			reg.lowerCaseIndex[strings.TrimSpace(strings.ToLower(alias))] = name
 		}
 	}
-	return AllLangs
+	for _, name := range reg.all {
+		reg.lowerCaseIndex[strings.ToLower(reg.byName[name].Nice())] = name
+		reg.lowerCaseIndex[strings.ToLower(name)] = name
+	}
+
+	reg.autocompletions = precomputeAutocompletions(reg)
+	return reg
+}
+
+// Return alpha codes for each language (no encoding problems).
+// See PrintNiceLang to display them more fancy.
+func MainStreamLanguages() []string {
+	return currentLanguages().mainstream
+}
+
+func MoreLanguages() []string {
+	// These do *not* include the MainStreamLanguages()
+	return currentLanguages().more
 }
 
-// autocompletions is a map[string][]string
-var autocompletions = precomputeAutocompletions()
+func AllLanguages() []string {
+	return currentLanguages().all
+}
+
+// AllNiceLanguages returns the display names of AllLanguages.
+func AllNiceLanguages() []string {
+	return currentLanguages().allNice
+}
 
 func LanguageAutoComplete(fragment string) []string {
 	fragment = strings.ToLower(fragment)
 
-	// Dynamic search (slow)
-	// options := []string{}
-	// for _, lg := range AllLanguages() {
-	// 	if strings.Contains(strings.ToLower(lg), fragment) || strings.Contains(strings.ToLower(PrintNiceLang(lg)), fragment) {
-	// 		options = append(options, PrintNiceLang(lg))
-	// 	}
-	// }
-	// return options
-
 	// Precomputed search (fast)
-	return autocompletions[fragment]
+	return currentLanguages().autocompletions[fragment]
 }
 
 func PrintNiceLang(lang string) string {
-	switch strings.TrimSpace(strings.ToLower(lang)) {
-	case "cpp":
-		return "C++"
-	case "csharp", "cs":
-		return "C#"
-	default:
-		return lang
+	if lg := FindLanguage(lang); lg != nil {
+		return lg.Nice()
 	}
+	return lang
 }
 
 func PrintNiceLangs(langs []string) []string {
@@ -90,58 +199,39 @@ func PrintNiceLangs(langs []string) []string {
 	return nice
 }
 
-func indexByLowerCase(langs []string) map[string]string {
-	m := map[string]string{}
-	for _, lg := range langs {
-		m[strings.ToLower(lg)] = lg
+func NormLang(lang string) string {
+	lg := strings.TrimSpace(strings.ToLower(lang))
+	return currentLanguages().lowerCaseIndex[lg]
+}
+
+// LanguageNames maps the lowercase names, nice names and aliases
+// to the standard names, like NormLang. Ex: "c++" -> "Cpp"
+func LanguageNames() map[string]string {
+	reg := currentLanguages()
+	names := make(map[string]string, len(reg.lowerCaseIndex))
+	for k, v := range reg.lowerCaseIndex {
+		names[k] = v
 	}
-	return m
+	return names
 }
 
-var langLowerCaseIndex = indexByLowerCase(AllLanguages())
+// LanguageNiceNames maps the standard names to the display names,
+// like PrintNiceLang. Ex: "Cpp" -> "C++"
+func LanguageNiceNames() map[string]string {
+	reg := currentLanguages()
+	names := make(map[string]string, len(reg.all))
+	for i, name := range reg.all {
+		names[name] = reg.allNice[i]
+	}
+	return names
+}
 
-func NormLang(lang string) string {
-	lg := strings.TrimSpace(strings.ToLower(lang))
-	switch lg {
-	case "c++", "cc":
-		return "Cpp"
-	case "c#", "cs":
-		return "Csharp"
-	case "javascript":
-		return "JS"
-	case "golang":
-		return "Go"
-	case "objective c":
-		return "Obj-C"
-	case "py":
-		return "Python"
-	case "rs":
-		return "Rust"
-	default:
-		return langLowerCaseIndex[lg]
-	}
-}
-
-func precomputeAutocompletions() map[string][]string {
+func precomputeAutocompletions(reg *languageRegistry) map[string][]string {
 	m := make(map[string][]string, 100)
 
 	// Crazy shadowing of variable "lg" is allowed in go...
-	for _, trueLg := range AllLanguages() {
-		niceLg := PrintNiceLang(trueLg)
-		for _, lg := range []string{trueLg, niceLg} {
-			lg := strings.ToLower(lg)
-			fragments := substrings(lg)
-			for _, frag := range fragments {
-				if !StringSliceContains(m[frag], niceLg) {
-					m[frag] = append(m[frag], niceLg)
-				}
-			}
-		}
-	}
-
-	for lg, trueLg := range synonymLangs {
-		niceLg := PrintNiceLang(trueLg)
-		lg := strings.ToLower(lg)
+	for lg, trueLg := range reg.lowerCaseIndex {
+		niceLg := reg.byName[trueLg].Nice()
 		fragments := substrings(lg)
 		for _, frag := range fragments {
 			if !StringSliceContains(m[frag], niceLg) {
@@ -149,8 +239,9 @@ func precomputeAutocompletions() map[string][]string {
 			}
 		}
 	}
-
-	fmt.Fprintf(os.Stderr, "---\n")
+	for _, options := range m {
+		sort.Strings(options)
+	}
 	return m
 }
 
@@ -172,28 +263,66 @@ func substrings(s string) []string {
 	return fragments
 }
 
-var langsExtraKeywords = map[string][]string{
-	"Clojure": []string{"clj", "cljs", "cljc", "edn"},
-	"Csharp":  []string{"cs"},
-	"D":       []string{"dlang"},
-	"Elixir":  []string{"ex", "exs"},
-	"Erlang":  []string{"erl", "hrl"},
-	"Fortran": []string{"for", "f90", "f95"},
-	"Go":      []string{"golang"},
-	"Haskell": []string{"hs", "lhs"},
-	"JS":      []string{"javascript"},
-	"Obj-C":   []string{"Objective", "Objective-C", "mm"},
-	"Pascal":  []string{"pp", "pas", "inc"},
-	"Perl":    []string{"pl"},
-	"Python":  []string{"py"},
-	"Ruby":    []string{"rb"},
-	"Rust":    []string{"rs"},
-	"Scala":   []string{"sc"},
-	"Scheme":  []string{"scs", "ss"},
-	"VB":      []string{"visual", "basic", "vba", "vb6"},
-}
-
 func LanguageExtraKeywords(lg string) []string {
 	// Careful, no defensive copy here!
-	return langsExtraKeywords[lg]
+	if lang := currentLanguages().byName[lg]; lang != nil {
+		return lang.Keywords
+	}
+	return nil
+}
+
+// DefaultLanguages are the languages supported out of the box,
+// before any Language entity is saved by an admin.
+func DefaultLanguages() []*Language {
+	return []*Language{
+		{Name: "Ada", Extensions: []string{"adb", "ads"}, HighlighterClass: "ada", Similar: []string{"Pascal"}},
+		{Name: "C", Extensions: []string{"c", "h"}, HighlighterClass: "c", Mainstream: true, Similar: []string{"Cpp", "Obj-C"}},
+		{Name: "Caml", Aliases: []string{"ocaml"}, Extensions: []string{"ml", "mli"}, HighlighterClass: "caml", Similar: []string{"Haskell"}},
+		{Name: "Clojure", Extensions: []string{"clj", "cljs", "cljc", "edn"}, Keywords: []string{"clj", "cljs", "cljc", "edn"}, HighlighterClass: "clj", HighlighterScript: "lang-clj.js", Similar: []string{"Lisp", "Scheme"}},
+		{Name: "Cobol", Extensions: []string{"cob", "cbl"}, HighlighterClass: "cobol"},
+		{Name: "Cpp", NiceName: "C++", Aliases: []string{"c++", "cc"}, Extensions: []string{"cpp", "cc", "hpp"}, HighlighterClass: "cpp", Mainstream: true, Similar: []string{"C", "D"}},
+		{Name: "Csharp", NiceName: "C#", Aliases: []string{"c#", "cs"}, Extensions: []string{"cs"}, Keywords: []string{"cs"}, HighlighterClass: "cs", Mainstream: true, Similar: []string{"Java", "VB"}},
+		{Name: "D", Extensions: []string{"d"}, Keywords: []string{"dlang"}, HighlighterClass: "d", Similar: []string{"Cpp", "C"}},
+		{Name: "Dart", Extensions: []string{"dart"}, HighlighterClass: "dart", HighlighterScript: "lang-dart.js", Similar: []string{"JS", "Java"}},
+		{Name: "Elixir", Extensions: []string{"ex", "exs"}, Keywords: []string{"ex", "exs"}, HighlighterClass: "elixir", Similar: []string{"Erlang", "Ruby"}},
+		{Name: "Erlang", Extensions: []string{"erl", "hrl"}, Keywords: []string{"erl", "hrl"}, HighlighterClass: "erlang", HighlighterScript: "lang-erlang.js", Similar: []string{"Elixir"}},
+		{Name: "Fortran", Extensions: []string{"f", "for", "f90", "f95"}, Keywords: []string{"for", "f90", "f95"}, HighlighterClass: "fortran", Similar: []string{"C"}},
+		{Name: "Go", Aliases: []string{"golang"}, Extensions: []string{"go"}, Keywords: []string{"golang"}, HighlighterClass: "go", HighlighterScript: "lang-go.js", DocURLTemplate: "https://pkg.go.dev/search?q=%s", Mainstream: true, Similar: []string{"C"}},
+		{Name: "Groovy", Extensions: []string{"groovy"}, HighlighterClass: "groovy", Similar: []string{"Java", "Kotlin"}},
+		{Name: "Haskell", Extensions: []string{"hs", "lhs"}, Keywords: []string{"hs", "lhs"}, HighlighterClass: "hs", HighlighterScript: "lang-hs.js", Similar: []string{"Caml"}},
+		{Name: "Java", Extensions: []string{"java"}, HighlighterClass: "java", Mainstream: true, Similar: []string{"Kotlin", "Csharp"}},
+		{Name: "JS", Aliases: []string{"javascript"}, Extensions: []string{"js"}, Keywords: []string{"javascript"}, HighlighterClass: "js", DocURLTemplate: "https://developer.mozilla.org/en-US/search?q=%s", Mainstream: true, Similar: []string{"Dart"}},
+		{Name: "Kotlin", Extensions: []string{"kt", "kts"}, HighlighterClass: "kotlin", Similar: []string{"Java", "Scala"}},
+		{Name: "Lisp", Extensions: []string{"lisp", "lsp"}, HighlighterClass: "lisp", HighlighterScript: "lang-lisp.js", Similar: []string{"Scheme", "Clojure"}},
+		{Name: "Lua", Extensions: []string{"lua"}, HighlighterClass: "lua", HighlighterScript: "lang-lua.js", Similar: []string{"JS", "Python"}},
+		{Name: "Obj-C", Aliases: []string{"objective c", "objective-c"}, Extensions: []string{"m", "mm"}, Keywords: []string{"Objective", "Objective-C", "mm"}, HighlighterClass: "obj-c", Mainstream: true, Similar: []string{"C"}},
+		{Name: "Pascal", Extensions: []string{"pp", "pas", "inc"}, Keywords: []string{"pp", "pas", "inc"}, HighlighterClass: "pascal", HighlighterScript: "lang-pascal.js", Similar: []string{"Ada"}},
+		{Name: "Perl", Extensions: []string{"pl", "pm"}, Keywords: []string{"pl"}, HighlighterClass: "perl", Similar: []string{"Ruby", "PHP"}},
+		{Name: "PHP", Extensions: []string{"php"}, HighlighterClass: "php", DocURLTemplate: "https://www.php.net/manual-lookup.php?pattern=%s", Mainstream: true, Similar: []string{"Perl", "JS"}},
+		{Name: "Prolog", Extensions: []string{"pro"}, HighlighterClass: "prolog"},
+		{Name: "Python", Aliases: []string{"py"}, Extensions: []string{"py"}, Keywords: []string{"py"}, HighlighterClass: "py", DocURLTemplate: "https://docs.python.org/3/search.html?q=%s", Mainstream: true, Similar: []string{"Ruby"}},
+		{Name: "Ruby", Extensions: []string{"rb"}, Keywords: []string{"rb"}, HighlighterClass: "rb", Mainstream: true, Similar: []string{"Python", "Elixir"}},
+		{Name: "Rust", Aliases: []string{"rs"}, Extensions: []string{"rs"}, Keywords: []string{"rs"}, HighlighterClass: "rust", DocURLTemplate: "https://doc.rust-lang.org/std/?search=%s", Similar: []string{"Cpp"}},
+		{Name: "Scala", Extensions: []string{"scala", "sc"}, Keywords: []string{"sc"}, HighlighterClass: "scala", HighlighterScript: "lang-scala.js", Similar: []string{"Java", "Kotlin"}},
+		{Name: "Scheme", Extensions: []string{"scm", "ss"}, Keywords: []string{"scs", "ss"}, HighlighterClass: "scm", HighlighterScript: "lang-lisp.js", Similar: []string{"Lisp", "Clojure"}},
+		{Name: "VB", Aliases: []string{"visual basic"}, Extensions: []string{"vb", "bas"}, Keywords: []string{"visual", "basic", "vba", "vb6"}, HighlighterClass: "vb", Similar: []string{"Csharp"}},
+	}
+}
+
+// ClosestLanguages returns the supported languages similar to lang,
+// by decreasing affinity.
+func ClosestLanguages(lang string) []string {
+	lg := FindLanguage(lang)
+	if lg == nil {
+		return nil
+	}
+	closest := make([]string, 0, len(lg.Similar))
+	for _, other := range lg.Similar {
+		name := NormLang(other)
+		if name == "" || name == lg.Name || StringSliceContains(closest, name) {
+			continue
+		}
+		closest = append(closest, name)
+	}
+	return closest
 }
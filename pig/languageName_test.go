@@ -0,0 +1,111 @@
+package pig
+
+import (
+	"testing"
+)
+
+var normLangTests = []struct {
+	in  string
+	out string
+}{
+	{"", ""},
+	{"foobar", ""},
+	{"Go", "Go"},
+	{" go ", "Go"},
+	{"golang", "Go"},
+	{"C++", "Cpp"},
+	{"cc", "Cpp"},
+	{"c#", "Csharp"},
+	{"cs", "Csharp"},
+	{"Javascript", "JS"},
+	{"Objective C", "Obj-C"},
+	{"py", "Python"},
+	{"rs", "Rust"},
+}
+
+func TestNormLang(t *testing.T) {
+	for i, tt := range normLangTests {
+		if out := NormLang(tt.in); out != tt.out {
+			t.Errorf("%d. NormLang(%q) => %q, want %q", i, tt.in, out, tt.out)
+		}
+	}
+}
+
+var printNiceLangTests = []struct {
+	in  string
+	out string
+}{
+	{"Cpp", "C++"},
+	{"cpp", "C++"},
+	{"Csharp", "C#"},
+	{"Go", "Go"},
+	{"foobar", "foobar"},
+}
+
+func TestPrintNiceLang(t *testing.T) {
+	for i, tt := range printNiceLangTests {
+		if out := PrintNiceLang(tt.in); out != tt.out {
+			t.Errorf("%d. PrintNiceLang(%q) => %q, want %q", i, tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestSetLanguages(t *testing.T) {
+	defer SetLanguages(DefaultLanguages())
+
+	SetLanguages([]*Language{
+		{Name: "Go", Aliases: []string{"golang"}, Mainstream: true},
+		{Name: "Zig", NiceName: "Zig!", Aliases: []string{"ziglang"}},
+	})
+	if out := NormLang("ziglang"); out != "Zig" {
+		t.Errorf("NormLang(ziglang) => %q, want Zig", out)
+	}
+	if out := NormLang("Python"); out != "" {
+		t.Errorf("NormLang(Python) => %q, want empty string", out)
+	}
+	if out := AllNiceLanguages(); !StringSliceEquals(out, []string{"Go", "Zig!"}) {
+		t.Errorf("AllNiceLanguages() => %q", out)
+	}
+	if out := MainStreamLanguages(); !StringSliceEquals(out, []string{"Go"}) {
+		t.Errorf("MainStreamLanguages() => %q", out)
+	}
+	if out := LanguageAutoComplete("zi"); !StringSliceEquals(out, []string{"Zig!"}) {
+		t.Errorf("LanguageAutoComplete(zi) => %q", out)
+	}
+}
+
+func TestClosestLanguages(t *testing.T) {
+	closest := ClosestLanguages("kotlin")
+	if len(closest) == 0 || closest[0] != "Java" {
+		t.Errorf("Expected Java as closest to Kotlin, got %v", closest)
+	}
+	if closest := ClosestLanguages("foobar"); len(closest) != 0 {
+		t.Errorf("Expected no closest language for unknown language, got %v", closest)
+	}
+}
+
+func TestLanguageURLTemplates(t *testing.T) {
+	lg := &Language{
+		Name:                  "Zig",
+		DocURLTemplate:        "https://ziglang.org/search?q=%s",
+		PlaygroundURLTemplate: "https://zig.run/?code=%s",
+	}
+	if out := lg.DocURL("array list"); out != "https://ziglang.org/search?q=array+list" {
+		t.Errorf("DocURL => %q", out)
+	}
+	if out := lg.PlaygroundURL("a&b=c"); out != "https://zig.run/?code=a%26b%3Dc" {
+		t.Errorf("PlaygroundURL => %q", out)
+	}
+	if out := (&Language{Name: "Zig"}).DocURL("x"); out != "" {
+		t.Errorf("DocURL without template => %q, want empty string", out)
+	}
+}
+
+func TestLanguageNames(t *testing.T) {
+	if out := LanguageNames()["c++"]; out != "Cpp" {
+		t.Errorf("LanguageNames()[c++] => %q, want Cpp", out)
+	}
+	if out := LanguageNiceNames()["Csharp"]; out != "C#" {
+		t.Errorf("LanguageNiceNames()[Csharp] => %q, want C#", out)
+	}
+}
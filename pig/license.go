@@ -0,0 +1,206 @@
+package pig
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+//
+// Idioms and impls are published under the site license, unless they were
+// imported from a source with another license. The attribution report lists,
+// NOTICE-style, the license, authors and source of a selection of contents.
+//
+
+// SiteLicense is the SPDX identifier of the default license of all contents.
+const SiteLicense = "CC-BY-SA-3.0"
+
+// KnownLicenses are the SPDX identifiers accepted without the "LicenseRef-" prefix.
+var KnownLicenses = []string{
+	"CC-BY-SA-3.0",
+	"CC-BY-SA-4.0",
+	"CC-BY-4.0",
+	"CC0-1.0",
+	"MIT",
+	"MIT-0",
+	"Apache-2.0",
+	"BSD-2-Clause",
+	"BSD-3-Clause",
+	"0BSD",
+	"ISC",
+	"Unlicense",
+	"GPL-2.0-only",
+	"GPL-2.0-or-later",
+	"GPL-3.0-only",
+	"GPL-3.0-or-later",
+	"LGPL-2.1-or-later",
+	"LGPL-3.0-or-later",
+	"MPL-2.0",
+	"GFDL-1.3-or-later",
+}
+
+var (
+	licenseRefRegexp      = regexp.MustCompile(`^LicenseRef-[A-Za-z0-9.\-]+$`)
+	licenseOperatorRegexp = regexp.MustCompile(` (AND|OR) `)
+)
+
+// ValidateLicense checks that license is empty, or a known SPDX identifier,
+// or a "LicenseRef-xxx" custom identifier, or a simple expression of those
+// joined with AND, OR.
+func ValidateLicense(license string) error {
+	if license == "" {
+		return nil
+	}
+	for _, part := range licenseOperatorRegexp.Split(license, -1) {
+		if !StringSliceContains(KnownLicenses, part) && !licenseRefRegexp.MatchString(part) {
+			return fmt.Errorf("unknown SPDX license identifier %q", part)
+		}
+	}
+	return nil
+}
+
+// EffectiveLicense is the license of the idiom statement.
+func (idiom *Idiom) EffectiveLicense() string {
+	if idiom.License == "" {
+		return SiteLicense
+	}
+	return idiom.License
+}
+
+// EffectiveLicense is the license of the impl snippet.
+func (impl *Impl) EffectiveLicense() string {
+	if impl.License == "" {
+		return SiteLicense
+	}
+	return impl.License
+}
+
+// AttributionEntry is the attribution of one idiom statement (ImplID 0), or of one impl.
+type AttributionEntry struct {
+	IdiomID       int
+	ImplID        int `json:",omitempty"`
+	Title         string
+	LanguageName  string `json:",omitempty"`
+	URL           string
+	License       string
+	Authors       []string
+	SourceName    string `json:",omitempty"`
+	SourceURL     string `json:",omitempty"`
+	RetrievalDate time.Time
+}
+
+// AttributionReport lists the attributions of idioms, and of their impls
+// in languages langs (all impls if langs is empty).
+// history gives the past versions of each idiom, by idiom ID: all their
+// authors and editors are credited, not only the current ones.
+// idiomURL and implURL give the URLs of the contents on this site.
+func AttributionReport(idioms []*Idiom, history map[int][]*IdiomHistory, langs []string, idiomURL func(*Idiom) string, implURL func(*Idiom, *Impl) string) []AttributionEntry {
+	var entries []AttributionEntry
+	for _, idiom := range idioms {
+		versions := make([]*IdiomHistory, len(history[idiom.Id]))
+		copy(versions, history[idiom.Id])
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i].Version < versions[j].Version
+		})
+		entries = append(entries, AttributionEntry{
+			IdiomID:       idiom.Id,
+			Title:         idiom.Title,
+			URL:           idiomURL(idiom),
+			License:       idiom.EffectiveLicense(),
+			Authors:       contributors(idiom, versions, 0),
+			SourceName:    idiom.AttributionSource,
+			SourceURL:     idiom.OriginalAttributionURL,
+			RetrievalDate: idiom.AttributionDate,
+		})
+		for i := range idiom.Implementations {
+			impl := &idiom.Implementations[i]
+			if len(langs) > 0 && !StringSliceContainsCaseInsensitive(langs, impl.LanguageName) {
+				continue
+			}
+			entries = append(entries, AttributionEntry{
+				IdiomID:       idiom.Id,
+				ImplID:        impl.Id,
+				Title:         idiom.Title,
+				LanguageName:  impl.LanguageName,
+				URL:           implURL(idiom, impl),
+				License:       impl.EffectiveLicense(),
+				Authors:       contributors(idiom, versions, impl.Id),
+				SourceName:    impl.AttributionSource,
+				SourceURL:     impl.OriginalAttributionURL,
+				RetrievalDate: impl.AttributionDate,
+			})
+		}
+	}
+	return entries
+}
+
+// contributors are the author and successive editors of the idiom statement
+// (implID 0) or of the impl implID, from the oldest of versions to the current idiom.
+func contributors(idiom *Idiom, versions []*IdiomHistory, implID int) []string {
+	var names []string
+	for _, idiomVersion := range append(versions, &IdiomHistory{Idiom: *idiom}) {
+		if implID == 0 {
+			names = append(names, idiomVersion.Author, idiomVersion.LastEditor)
+		} else if _, impl, found := idiomVersion.FindImplInIdiom(implID); found {
+			names = append(names, impl.Author, impl.LastEditor)
+		}
+	}
+	return authors(names...)
+}
+
+func authors(names ...string) []string {
+	var list []string
+	for _, name := range names {
+		if name != "" && !StringSliceContains(list, name) {
+			list = append(list, name)
+		}
+	}
+	return list
+}
+
+// WriteNotice writes the attribution report entries as a plain text NOTICE file.
+func WriteNotice(w io.Writer, entries []AttributionEntry, generated time.Time) error {
+	licenseCount := map[string]int{}
+	for _, e := range entries {
+		licenseCount[e.License]++
+	}
+	licenses := make([]string, 0, len(licenseCount))
+	for license := range licenseCount {
+		licenses = append(licenses, license)
+	}
+	sort.Strings(licenses)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Programming-Idioms attribution report\n")
+	fmt.Fprintf(&b, "Generated %s\n\n", generated.Format("2006-01-02"))
+	fmt.Fprintf(&b, "Unless stated otherwise, contents are licensed under %s.\n", SiteLicense)
+	fmt.Fprintf(&b, "Licenses in this report:\n")
+	for _, license := range licenses {
+		fmt.Fprintf(&b, "  %s (%d)\n", license, licenseCount[license])
+	}
+	for _, e := range entries {
+		b.WriteString("\n")
+		if e.ImplID == 0 {
+			fmt.Fprintf(&b, "Idiom #%d %s\n", e.IdiomID, e.Title)
+		} else {
+			fmt.Fprintf(&b, "Idiom #%d %s, %s implementation #%d\n", e.IdiomID, e.Title, PrintNiceLang(e.LanguageName), e.ImplID)
+		}
+		fmt.Fprintf(&b, "  URL: %s\n", e.URL)
+		fmt.Fprintf(&b, "  License: %s\n", e.License)
+		if len(e.Authors) > 0 {
+			fmt.Fprintf(&b, "  Authors: %s\n", strings.Join(e.Authors, ", "))
+		}
+		if e.SourceName != "" || e.SourceURL != "" {
+			source := strings.TrimSpace(e.SourceName + " " + e.SourceURL)
+			if !e.RetrievalDate.IsZero() {
+				source += ", retrieved " + e.RetrievalDate.Format("2006-01-02")
+			}
+			fmt.Fprintf(&b, "  Source: %s\n", source)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,111 @@
+package pig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateLicense(t *testing.T) {
+	for _, license := range []string{"", "MIT", "Apache-2.0 OR MIT", "LicenseRef-RosettaCode"} {
+		if err := ValidateLicense(license); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", license, err)
+		}
+	}
+	for _, license := range []string{"mit", "MIT OR", "Beerware", "LicenseRef-"} {
+		if err := ValidateLicense(license); err == nil {
+			t.Errorf("Expected %q to be invalid", license)
+		}
+	}
+}
+
+func TestAttributionReport(t *testing.T) {
+	retrieved := time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC)
+	idiom := &Idiom{
+		Id:     1,
+		Title:  "Print Hello World",
+		Author: "alice",
+		Implementations: []Impl{
+			{Id: 10, LanguageName: "Go", Author: "bob", LastEditor: "bob"},
+			{
+				Id:                     11,
+				LanguageName:           "Python",
+				Author:                 "carol",
+				LastEditor:             "dave",
+				License:                "MIT",
+				AttributionSource:      "Rosetta Code",
+				OriginalAttributionURL: "https://rosettacode.example/hello",
+				AttributionDate:        retrieved,
+			},
+		},
+	}
+	idiomURL := func(idiom *Idiom) string { return "/idiom/1" }
+	implURL := func(idiom *Idiom, impl *Impl) string { return "/impl/" + impl.LanguageName }
+
+	entries := AttributionReport([]*Idiom{idiom}, nil, []string{"python"}, idiomURL, implURL)
+	if len(entries) != 2 {
+		t.Fatalf("Expected idiom and 1 impl, got %v", entries)
+	}
+	if entries[0].License != SiteLicense || entries[0].ImplID != 0 {
+		t.Errorf("Unexpected idiom entry %v", entries[0])
+	}
+	if e := entries[1]; e.License != "MIT" || e.URL != "/impl/Python" || strings.Join(e.Authors, ",") != "carol,dave" {
+		t.Errorf("Unexpected impl entry %v", e)
+	}
+
+	if all := AttributionReport([]*Idiom{idiom}, nil, nil, idiomURL, implURL); len(all) != 3 {
+		t.Errorf("Expected 3 entries, got %d", len(all))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNotice(&buf, entries, retrieved); err != nil {
+		t.Fatal(err)
+	}
+	notice := buf.String()
+	for _, expected := range []string{
+		"MIT (1)",
+		"Idiom #1 Print Hello World, Python implementation #11",
+		"Source: Rosetta Code https://rosettacode.example/hello, retrieved 2020-03-04",
+	} {
+		if !strings.Contains(notice, expected) {
+			t.Errorf("Expected %q in notice:\n%s", expected, notice)
+		}
+	}
+}
+
+func TestAttributionReportHistory(t *testing.T) {
+	version := func(v int, idiomEditor, implEditor string) *IdiomHistory {
+		return &IdiomHistory{Idiom: Idiom{
+			Id:         1,
+			Version:    v,
+			Author:     "alice",
+			LastEditor: idiomEditor,
+			Implementations: []Impl{
+				{Id: 10, LanguageName: "Go", Author: "bob", LastEditor: implEditor},
+			},
+		}}
+	}
+	// Most recent first, as in the history list
+	history := map[int][]*IdiomHistory{
+		1: {
+			version(3, "alice", "dave"),
+			version(2, "alice", "carol"),
+			version(1, "alice", "bob"),
+		},
+	}
+	idiom := &version(4, "erin", "frank").Idiom
+	idiomURL := func(idiom *Idiom) string { return "/idiom/1" }
+	implURL := func(idiom *Idiom, impl *Impl) string { return "/impl/10" }
+
+	entries := AttributionReport([]*Idiom{idiom}, history, nil, idiomURL, implURL)
+	if len(entries) != 2 {
+		t.Fatalf("Expected idiom and 1 impl, got %v", entries)
+	}
+	if got := strings.Join(entries[0].Authors, ","); got != "alice,erin" {
+		t.Errorf("Expected idiom authors alice,erin, got %q", got)
+	}
+	if got := strings.Join(entries[1].Authors, ","); got != "bob,carol,dave,frank" {
+		t.Errorf("Expected impl authors bob,carol,dave,frank, got %q", got)
+	}
+}
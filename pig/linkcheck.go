@@ -0,0 +1,349 @@
+package pig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+//
+// The link checker verifies that the external URLs of the idioms and impls
+// (documentation, demo, origin, related URLs) still work.
+//
+// It is polite: requests to a same host are spaced by at least PerHostInterval.
+// The HTTP client is injectable, for tests against a local server.
+//
+// The URLs are user input: the checker must never reach the internal
+// network. Use a client made by NewPublicLinkClient, which refuses to
+// connect to non-public addresses, and the hosts of the URL and of each
+// redirect are checked before the request.
+//
+
+// LinkStatus is the result of the last check of a URL.
+type LinkStatus struct {
+	URL string
+	// StatusCode is the HTTP status of the final response, 0 if no response.
+	StatusCode int
+	// Error is the network error, if no response was received.
+	Error string `datastore:",noindex"`
+	// FinalURL is the URL reached after the redirects.
+	FinalURL string `datastore:",noindex"`
+	// Redirects are the successive Location of the redirects, if any.
+	Redirects []string `datastore:",noindex"`
+	CheckDate time.Time
+	Broken    bool
+}
+
+// LinkChecker checks URLs with per-host rate limiting.
+type LinkChecker struct {
+	// Client sends the requests. Its CheckRedirect is overridden.
+	// See NewPublicLinkClient.
+	Client *http.Client
+	// PerHostInterval is the minimum delay between 2 requests to a same host.
+	PerHostInterval time.Duration
+	// MaxRedirects is the maximum number of redirects followed.
+	MaxRedirects int
+	// UserAgent of the requests.
+	UserAgent string
+	// AllowNonPublic disables the check of the hosts, for tests only.
+	AllowNonPublic bool
+
+	mu       sync.Mutex
+	nextSlot map[string]time.Time
+}
+
+// NewLinkChecker creates a LinkChecker with client, which must have a Timeout.
+func NewLinkChecker(client *http.Client, perHostInterval time.Duration) *LinkChecker {
+	return &LinkChecker{
+		Client:          client,
+		PerHostInterval: perHostInterval,
+		MaxRedirects:    5,
+		UserAgent:       "Programming-Idioms link checker",
+		nextSlot:        map[string]time.Time{},
+	}
+}
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// ErrNonPublicAddress is returned for a host that resolves to a loopback,
+// private, link-local (e.g. cloud metadata) or otherwise non-public address.
+var ErrNonPublicAddress = errors.New("non-public address")
+
+var nonPublicNetworks = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",      // "this" network
+		"10.0.0.0/8",     // private
+		"100.64.0.0/10",  // carrier-grade NAT
+		"127.0.0.0/8",    // loopback
+		"169.254.0.0/16", // link-local, cloud metadata
+		"172.16.0.0/12",  // private
+		"192.0.0.0/24",   // IETF protocol assignments
+		"192.168.0.0/16", // private
+		"198.18.0.0/15",  // benchmarking
+		"224.0.0.0/3",    // multicast, reserved, broadcast
+		"::/128",         // unspecified
+		"::1/128",        // loopback
+		"64:ff9b::/96",   // NAT64, may embed a private IPv4
+		"fc00::/7",       // unique local
+		"fe80::/10",      // link-local
+		"ff00::/8",       // multicast
+	} {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// IsPublicIP is false for the addresses the link checker must not reach.
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, n := range nonPublicNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return ip != nil
+}
+
+// NewPublicLinkClient creates an HTTP client which refuses to connect
+// to non-public addresses. The check is done on the resolved address
+// of each connection, so DNS tricks can't bypass it.
+func NewPublicLinkClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicIP(net.ParseIP(host)) {
+				return ErrNonPublicAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No proxy: the dialer must see the real destination
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+		},
+	}
+}
+
+// checkHost returns ErrNonPublicAddress if host resolves to a non-public address.
+func (lc *LinkChecker) checkHost(ctx context.Context, host string) error {
+	if lc.AllowNonPublic {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return ErrNonPublicAddress
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return ErrNonPublicAddress
+		}
+	}
+	return nil
+}
+
+// isNonPublicAddressError tells if err comes from checkHost or from
+// the dialer of NewPublicLinkClient.
+func isNonPublicAddressError(err error) bool {
+	for err != nil {
+		if err == ErrNonPublicAddress {
+			return true
+		}
+		switch e := err.(type) {
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// Check requests rawURL with HEAD, then with GET when HEAD is not supported.
+func (lc *LinkChecker) Check(ctx context.Context, rawURL string) LinkStatus {
+	status := LinkStatus{
+		URL:       rawURL,
+		CheckDate: time.Now(),
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		status.Error = "malformed URL"
+		status.Broken = true
+		return status
+	}
+
+	resp, redirects, err := lc.do(ctx, http.MethodHead, rawURL)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented || resp.StatusCode == http.StatusForbidden) {
+		// Some servers don't like HEAD
+		resp, redirects, err = lc.do(ctx, http.MethodGet, rawURL)
+	}
+	if isNonPublicAddressError(err) {
+		// Don't tell anything about the internal network, not even the redirects
+		status.Error = ErrNonPublicAddress.Error()
+		status.Broken = true
+		return status
+	}
+	status.Redirects = redirects
+	if err != nil {
+		status.Error = err.Error()
+		status.Broken = true
+		return status
+	}
+	status.StatusCode = resp.StatusCode
+	status.FinalURL = resp.Request.URL.String()
+	status.Broken = resp.StatusCode >= 400
+	return status
+}
+
+func (lc *LinkChecker) do(ctx context.Context, method, rawURL string) (*http.Response, []string, error) {
+	var redirects []string
+	client := *lc.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := lc.checkHost(req.Context(), req.URL.Hostname()); err != nil {
+			return err
+		}
+		redirects = append(redirects, req.URL.String())
+		if len(via) > lc.MaxRedirects {
+			return errTooManyRedirects
+		}
+		return lc.wait(req.Context(), req.URL.Host)
+	}
+
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", lc.UserAgent)
+	if err := lc.checkHost(ctx, req.URL.Hostname()); err != nil {
+		return nil, nil, err
+	}
+	if err := lc.wait(ctx, req.URL.Host); err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, redirects, err
+	}
+	resp.Body.Close()
+	return resp, redirects, nil
+}
+
+// wait blocks until a request to host is allowed.
+func (lc *LinkChecker) wait(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+	lc.mu.Lock()
+	now := time.Now()
+	slot := lc.nextSlot[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	lc.nextSlot[host] = slot.Add(lc.PerHostInterval)
+	lc.mu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CheckAll checks the distinct urls, with at most concurrency requests at a time.
+func (lc *LinkChecker) CheckAll(ctx context.Context, urls []string, concurrency int) map[string]LinkStatus {
+	results := make(map[string]LinkStatus, len(urls))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	todo := make(chan string)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range todo {
+				status := lc.Check(ctx, u)
+				mu.Lock()
+				results[u] = status
+				mu.Unlock()
+			}
+		}()
+	}
+	seen := map[string]bool{}
+	for _, u := range urls {
+		if !seen[u] {
+			seen[u] = true
+			todo <- u
+		}
+	}
+	close(todo)
+	wg.Wait()
+	return results
+}
+
+// LinkRef is a URL found in an idiom or in an impl.
+type LinkRef struct {
+	IdiomID int
+	// ImplID is 0 for the URLs of the idiom statement.
+	ImplID       int
+	LanguageName string
+	// Field is the name of the field containing the URL.
+	Field string
+	URL   string
+}
+
+func (ref LinkRef) String() string {
+	return fmt.Sprintf("%d/%d %s %s", ref.IdiomID, ref.ImplID, ref.Field, ref.URL)
+}
+
+// CollectLinks returns all the external URLs of idiom.
+func CollectLinks(idiom *Idiom) []LinkRef {
+	var refs []LinkRef
+	add := func(implID int, lang, field, u string) {
+		if u = strings.TrimSpace(u); u != "" {
+			refs = append(refs, LinkRef{IdiomID: idiom.Id, ImplID: implID, LanguageName: lang, Field: field, URL: u})
+		}
+	}
+	add(0, "", "OriginalAttributionURL", idiom.OriginalAttributionURL)
+	for _, u := range idiom.RelatedURLs {
+		add(0, "", "RelatedURLs", u)
+	}
+	for _, impl := range idiom.Implementations {
+		add(impl.Id, impl.LanguageName, "DocumentationURL", impl.DocumentationURL)
+		add(impl.Id, impl.LanguageName, "DemoURL", impl.DemoURL)
+		add(impl.Id, impl.LanguageName, "OriginalAttributionURL", impl.OriginalAttributionURL)
+	}
+	return refs
+}
@@ -0,0 +1,134 @@
+package pig
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestLinkServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/nohead", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestLinkChecker(t *testing.T) {
+	server := newTestLinkServer()
+	defer server.Close()
+	lc := NewLinkChecker(server.Client(), 0)
+	lc.AllowNonPublic = true
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		path       string
+		statusCode int
+		broken     bool
+		redirects  int
+	}{
+		{"/ok", 200, false, 0},
+		{"/gone", 404, true, 0},
+		{"/moved", 200, false, 1},
+		{"/loop", 0, true, 6},
+		{"/nohead", 200, false, 0},
+	} {
+		status := lc.Check(ctx, server.URL+tc.path)
+		if status.StatusCode != tc.statusCode || status.Broken != tc.broken || len(status.Redirects) != tc.redirects {
+			t.Errorf("%s: unexpected status %+v", tc.path, status)
+		}
+	}
+	if status := lc.Check(ctx, "ftp://example.com/file"); !status.Broken {
+		t.Errorf("Expected malformed URL to be broken")
+	}
+}
+
+func TestLinkCheckerPerHostInterval(t *testing.T) {
+	server := newTestLinkServer()
+	defer server.Close()
+	interval := 50 * time.Millisecond
+	lc := NewLinkChecker(server.Client(), interval)
+	lc.AllowNonPublic = true
+
+	start := time.Now()
+	results := lc.CheckAll(context.Background(), []string{server.URL + "/ok", server.URL + "/gone", server.URL + "/ok", server.URL + "/nohead"}, 3)
+	if len(results) != 3 {
+		t.Errorf("Expected 3 distinct results, got %d", len(results))
+	}
+	// 4 requests (HEAD /nohead is retried with GET) to the same host
+	if elapsed := time.Since(start); elapsed < 3*interval {
+		t.Errorf("Requests were not rate limited, took %v", elapsed)
+	}
+}
+
+func TestLinkCheckerRefusesNonPublicAddresses(t *testing.T) {
+	server := newTestLinkServer()
+	defer server.Close()
+	ctx := context.Background()
+
+	// The host is checked before the request
+	lc := NewLinkChecker(server.Client(), 0)
+	for _, u := range []string{server.URL + "/moved", "http://169.254.169.254/computeMetadata/v1/", "http://[::1]/", "http://localhost/"} {
+		status := lc.Check(ctx, u)
+		if !status.Broken || status.Error != ErrNonPublicAddress.Error() || len(status.Redirects) != 0 || status.FinalURL != "" {
+			t.Errorf("%s: unexpected status %+v", u, status)
+		}
+	}
+
+	// The dialer refuses the connection, whatever the host resolution said
+	lc = NewLinkChecker(NewPublicLinkClient(time.Second), 0)
+	lc.AllowNonPublic = true
+	if status := lc.Check(ctx, server.URL+"/ok"); status.Error != ErrNonPublicAddress.Error() {
+		t.Errorf("Expected connection to be refused, got %+v", status)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for ip, public := range map[string]bool{
+		"8.8.8.8":         true,
+		"2001:4860::8888": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.20.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"::ffff:10.0.0.1": false,
+		"fd00:ec2::254":   false,
+		"fe80::1":         false,
+	} {
+		if got := IsPublicIP(net.ParseIP(ip)); got != public {
+			t.Errorf("IsPublicIP(%s): expected %v, got %v", ip, public, got)
+		}
+	}
+}
+
+func TestCollectLinks(t *testing.T) {
+	idiom := &Idiom{
+		Id:          1,
+		RelatedURLs: []string{"https://a.example"},
+		Implementations: []Impl{
+			{Id: 10, LanguageName: "Go", DocumentationURL: "https://golang.org/pkg", DemoURL: " "},
+		},
+	}
+	refs := CollectLinks(idiom)
+	if len(refs) != 2 || refs[1].ImplID != 10 || refs[1].Field != "DocumentationURL" {
+		t.Errorf("Unexpected links %v", refs)
+	}
+}
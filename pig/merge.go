@@ -0,0 +1,92 @@
+package pig
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//
+// Duplicate idioms are merged by an admin: the implementations of the
+// source idiom are moved into the target idiom, and the source idiom is
+// replaced by a tombstone which redirects to the target.
+//
+
+// IdiomTombstone replaces an idiom merged into another one.
+// Its Datastore key ID is the ID of the former idiom.
+type IdiomTombstone struct {
+	IdiomID int
+	// Title of the former idiom.
+	Title string
+	// MergedInto is the ID of the idiom which received the implementations.
+	MergedInto int
+	MergeDate  time.Time
+	Merger     string
+}
+
+// MergeDuplicate moves the contents of source, a duplicate of idiom, into idiom.
+//
+// The implementations keep their IDs, authors and dates.
+// The keywords, tags and related URLs are united.
+// relatedToSource are the idioms related to source: they become related to idiom,
+// with the same relation type, and they are modified accordingly.
+func (idiom *Idiom) MergeDuplicate(source *Idiom, relatedToSource []*Idiom) error {
+	if idiom.Id == source.Id {
+		return fmt.Errorf("can't merge idiom %d into itself", idiom.Id)
+	}
+
+	for _, impl := range source.Implementations {
+		if _, _, found := idiom.FindImplInIdiom(impl.Id); found {
+			return fmt.Errorf("impl %d is already in idiom %d", impl.Id, idiom.Id)
+		}
+	}
+	idiom.Implementations = append(idiom.Implementations, source.Implementations...)
+	idiom.ImplCount = len(idiom.Implementations)
+
+	idiom.ExtraKeywords = unionWords(idiom.ExtraKeywords, source.ExtraKeywords)
+	for _, tag := range source.Tags {
+		if !StringSliceContains(idiom.Tags, tag) {
+			idiom.Tags = append(idiom.Tags, tag)
+		}
+	}
+	idiom.TagSlugs = TagSlugsWithAncestors(idiom.Tags)
+	for i, url := range source.RelatedURLs {
+		if StringSliceContains(idiom.RelatedURLs, url) {
+			continue
+		}
+		label := "See also"
+		if i < len(source.RelatedURLLabels) {
+			label = source.RelatedURLLabels[i]
+		}
+		idiom.RelatedURLs = append(idiom.RelatedURLs, url)
+		idiom.RelatedURLLabels = append(idiom.RelatedURLLabels, label)
+	}
+
+	for _, other := range relatedToSource {
+		r, found := source.relationTo(other.Id)
+		other.RemoveRelatedIdiom(source.Id)
+		if !found || other.Id == idiom.Id {
+			continue
+		}
+		if _, already := idiom.relationTo(other.Id); !already {
+			idiom.relateTo(other, r.Type)
+			other.relateTo(idiom, r.Type.Inverse())
+		}
+		other.EditSummary = fmt.Sprintf("Related idiom #%d merged into idiom #%d", source.Id, idiom.Id)
+	}
+	idiom.RemoveRelatedIdiom(source.Id)
+
+	idiom.EditSummary = fmt.Sprintf("Merged duplicate idiom #%d [%v]", source.Id, source.Title)
+	return nil
+}
+
+// unionWords returns the words of a, followed by the words of b not in a.
+func unionWords(a, b string) string {
+	words := strings.Fields(a)
+	for _, w := range strings.Fields(b) {
+		if !StringSliceContainsCaseInsensitive(words, w) {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,71 @@
+package pig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeDuplicate(t *testing.T) {
+	target := &Idiom{
+		Id:            10,
+		Title:         "Reverse a string",
+		ExtraKeywords: "invert flip",
+		Tags:          []string{"Strings"},
+		RelatedURLs:   []string{"https://a.example"},
+		Implementations: []Impl{
+			{Id: 100, LanguageName: "Go", Author: "alice"},
+		},
+	}
+	source := &Idiom{
+		Id:               11,
+		Title:            "Reverse string",
+		ExtraKeywords:    "Flip backwards",
+		Tags:             []string{"Strings", "Strings > Unicode"},
+		RelatedURLs:      []string{"https://a.example", "https://b.example"},
+		RelatedURLLabels: []string{"A", "B"},
+		Implementations: []Impl{
+			{Id: 110, LanguageName: "Python", Author: "bob"},
+			{Id: 111, LanguageName: "Go", Author: "carol"},
+		},
+	}
+	other := &Idiom{Id: 12, Title: "Reverse a list"}
+	source.AddTypedRelation(other, RelationVariantOf)
+	source.AddRelation(target)
+
+	if err := target.MergeDuplicate(source, []*Idiom{other, target}); err != nil {
+		t.Fatal(err)
+	}
+
+	if target.ImplCount != 3 || target.Implementations[1].Author != "bob" || target.Implementations[2].Id != 111 {
+		t.Errorf("Unexpected impls %v", target.Implementations)
+	}
+	if target.ExtraKeywords != "invert flip backwards" {
+		t.Errorf("Unexpected keywords %q", target.ExtraKeywords)
+	}
+	if !reflect.DeepEqual(target.Tags, []string{"Strings", "Strings > Unicode"}) {
+		t.Errorf("Unexpected tags %v", target.Tags)
+	}
+	if !reflect.DeepEqual(target.RelatedURLs, []string{"https://a.example", "https://b.example"}) || target.RelatedURLLabels[len(target.RelatedURLLabels)-1] != "B" {
+		t.Errorf("Unexpected related URLs %v %v", target.RelatedURLs, target.RelatedURLLabels)
+	}
+	if !reflect.DeepEqual(target.RelatedIdioms(), []RelatedIdiom{{Id: 12, Title: "Reverse a list", Type: RelationVariantOf}}) {
+		t.Errorf("Unexpected relations %v", target.RelatedIdioms())
+	}
+	if !reflect.DeepEqual(other.RelatedIdiomIds, []int{10}) {
+		t.Errorf("Unexpected relations of other %v", other.RelatedIdiomIds)
+	}
+	if issues := CheckRelations([]*Idiom{target, other}); len(issues) != 0 {
+		t.Errorf("Unexpected relation issues %v", issues)
+	}
+}
+
+func TestMergeDuplicateErrors(t *testing.T) {
+	a := &Idiom{Id: 1, Implementations: []Impl{{Id: 5}}}
+	if err := a.MergeDuplicate(a, nil); err == nil {
+		t.Errorf("Expected error when merging into itself")
+	}
+	b := &Idiom{Id: 2, Implementations: []Impl{{Id: 5}}}
+	if err := a.MergeDuplicate(b, nil); err == nil {
+		t.Errorf("Expected error for impl in both idioms")
+	}
+}
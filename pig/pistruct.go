@@ -53,6 +53,16 @@ type Idiom struct {
 	// Please acknowledge sources (idiom statement, not snippet).
 	OriginalAttributionURL string
 
+	// License is the SPDX identifier of the idiom statement license, e.g. "MIT".
+	// Empty means SiteLicense.
+	License string
+
+	// AttributionSource is the name of the original source, e.g. "Rosetta Code".
+	AttributionSource string
+
+	// AttributionDate is when the statement was retrieved from the original source.
+	AttributionDate time.Time
+
 	// Picture representing the concept, if necessary
 	// DEPRECATED
 	Picture string
@@ -96,6 +106,10 @@ type Idiom struct {
 	// NoSQL-style : store directly some data from other objects
 	RelatedIdiomTitles []string
 
+	// RelatedIdiomTypes qualify the relations, see RelationType.
+	// Missing or empty means RelationSeeAlso.
+	RelatedIdiomTypes []RelationType `datastore:",noindex"`
+
 	// Protected when "only admin can edit"
 	Protected bool
 
@@ -108,6 +122,20 @@ type Idiom struct {
 
 	// RelatedURLLabels are nice text for Related URLs hyperlinks.
 	RelatedURLLabels []string
+
+	// Tags are hierarchical categories, e.g. "Strings > Formatting".
+	Tags []string
+
+	// (Denormalized) slugs of the Tags and of their ancestors, for datastore querying.
+	// e.g. "strings", "strings.formatting"
+	TagSlugs []string `json:"-"`
+
+	// ExampleInputs are optional sample values bound to the Variables,
+	// one "name = value" per line.
+	ExampleInputs []string
+
+	// ExampleOutputs are the expected results of the ExampleInputs (same length).
+	ExampleOutputs []string
 }
 
 // Impl is a specific implementation of one Idiom in one programming language.
@@ -143,6 +171,16 @@ type Impl struct {
 	// OriginalAttributionURL: please acknowledge sources.
 	OriginalAttributionURL string
 
+	// License is the SPDX identifier of the snippet license, e.g. "MIT".
+	// Empty means SiteLicense.
+	License string
+
+	// AttributionSource is the name of the original source, e.g. "Rosetta Code".
+	AttributionSource string
+
+	// AttributionDate is when the snippet was retrieved from the original source.
+	AttributionDate time.Time
+
 	// DemoURL is an optional link to an online demo
 	DemoURL string
 
@@ -178,6 +216,31 @@ type Impl struct {
 
 	// Protected when "only admin can edit"
 	Protected bool
+
+	// MinVersion is the optional minimum language version required, e.g. "3.10" for Python.
+	MinVersion string
+
+	// Dialect is the optional language standard or edition, e.g. "C++20", "ES2020", "Rust 2021 edition".
+	Dialect string
+
+	// VerifiedExamples is true if the author has checked that the snippet
+	// gives the expected outputs of the idiom examples.
+	VerifiedExamples bool
+
+	// ExpectedOutput is the optional standard output of the snippet,
+	// when wrapped into a runnable program.
+	ExpectedOutput string
+
+	// RunStatus is the result of the last automated execution of the snippet.
+	// It is reset when the impl is edited.
+	RunStatus RunStatus
+
+	// RunDate is the date of the last automated execution.
+	RunDate time.Time
+
+	// CodeBlockHTML is the CodeBlock colored server-side, for API clients.
+	// It is computed at render time, never persisted.
+	CodeBlockHTML string `datastore:"-" json:",omitempty"`
 }
 
 // IdiomRenderingDecoration is the "current user" vote on this Idiom, if any.
@@ -185,6 +248,8 @@ type Impl struct {
 type IdiomRenderingDecoration struct {
 	UpVoted   bool
 	DownVoted bool
+	// Locale is set when the texts were replaced by their translation.
+	Locale string
 }
 
 // ImplRenderingDecoration is the "current user" vote on this Impl, if any.
@@ -196,6 +261,10 @@ type ImplRenderingDecoration struct {
 	Matching bool
 	// SearchedLang is set to true if current impl lang is the user typed lang.
 	SearchedLang bool
+	// TooNew is set to true if current impl requires a newer language version than the user target version.
+	TooNew bool
+	// Variables are the idiom Variables, to be emphasized in the code.
+	Variables []string
 }
 
 // IdiomVoteLog is a history trace of an Idiom vote, from a specific user.
@@ -286,6 +355,48 @@ func (idiom *Idiom) FindRecentlyUpdatedImpl() *Impl {
 	return recentImpl
 }
 
+// LanguageFallback is, for a language missing in an Idiom,
+// the impls of the closest languages.
+type LanguageFallback struct {
+	// Lang is the missing language.
+	Lang string
+	// Impls are in the closest languages, by decreasing affinity. May be empty.
+	Impls []Impl
+}
+
+// HasImplIn tells if idiom has at least one impl in lang.
+func (idiom *Idiom) HasImplIn(lang string) bool {
+	for _, impl := range idiom.Implementations {
+		if impl.LanguageName == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// LanguageFallbacks computes a LanguageFallback for each of langs
+// not implemented in idiom. At most maxImpls impls are suggested for each.
+//
+// It returns copies of the Impls, not pointers.
+func (idiom *Idiom) LanguageFallbacks(langs []string, maxImpls int) []LanguageFallback {
+	var fallbacks []LanguageFallback
+	for _, lang := range langs {
+		if idiom.HasImplIn(lang) {
+			continue
+		}
+		fallback := LanguageFallback{Lang: lang}
+		for _, closest := range ClosestLanguages(lang) {
+			for _, impl := range idiom.Implementations {
+				if impl.LanguageName == closest && len(fallback.Impls) < maxImpls {
+					fallback.Impls = append(fallback.Impls, impl)
+				}
+			}
+		}
+		fallbacks = append(fallbacks, fallback)
+	}
+	return fallbacks
+}
+
 // ExtractIndexableWords compute the list of words contained in an Idiom.
 // First return value is the list of all matchable words.
 // Second return value is the list of matchable words from title only.
@@ -300,6 +411,9 @@ func (idiom *Idiom) ExtractIndexableWords() (w []string, wTitle []string, wLead
 	wKeywords := SplitForIndexing(idiom.ExtraKeywords, true)
 	wLead = append(wLead, wKeywords...)
 	w = append(w, wKeywords...)
+	for _, tag := range idiom.Tags {
+		w = append(w, SplitForIndexing(strings.Replace(tag, TagSeparator, " ", -1), true)...)
+	}
 
 	for i := range idiom.Implementations {
 		impl := &idiom.Implementations[i]
@@ -320,9 +434,7 @@ func (impl *Impl) ExtractIndexableWords() []string {
 	if len(impl.AuthorComment) >= 3 {
 		w = append(w, SplitForIndexing(impl.AuthorComment, true)...)
 	}
-	if langExtras, ok := langsExtraKeywords[impl.LanguageName]; ok {
-		w = append(w, langExtras...)
-	}
+	w = append(w, LanguageExtraKeywords(impl.LanguageName)...)
 	// Note: we don't index external URLs.
 	return w
 }
@@ -453,20 +565,6 @@ func containsInt(a []int, x int) bool {
 	return false
 }
 
-// AddRelation creates a bidirectional link between 2 related Idioms.
-func (idiom *Idiom) AddRelation(other *Idiom) {
-	if !containsInt(idiom.RelatedIdiomIds, other.Id) {
-		idiom.RelatedIdiomIds = append(idiom.RelatedIdiomIds, other.Id)
-		idiom.RelatedIdiomTitles = append(idiom.RelatedIdiomTitles, other.Title)
-		idiom.EditSummary = fmt.Sprintf("Linked to idiom #%d [%v]", other.Id, other.Title)
-	}
-	if !containsInt(other.RelatedIdiomIds, idiom.Id) {
-		other.RelatedIdiomIds = append(other.RelatedIdiomIds, idiom.Id)
-		other.RelatedIdiomTitles = append(other.RelatedIdiomTitles, idiom.Title)
-		other.EditSummary = fmt.Sprintf("Linked to idiom #%d [%v]", idiom.Id, idiom.Title)
-	}
-}
-
 // VariablesComma e.g. ["x", "result"] -> "x,result"
 func (idiom *Idiom) VariablesComma() string {
 	return strings.Join(idiom.Variables, ",")
@@ -95,3 +95,20 @@ var notContainedWords = []string{"", "john", "jack"}
 func TestIndexWords(t *testing.T) {
 	// TODO testwith full text api
 }
+
+func TestLanguageFallbacks(t *testing.T) {
+	idiom := sampleIdiom()
+	fallbacks := idiom.LanguageFallbacks([]string{"Java", "Kotlin", "Ruby", "Cobol"}, 3)
+	if len(fallbacks) != 3 {
+		t.Fatalf("Expected 3 fallbacks (Java is implemented), got %v", fallbacks)
+	}
+	if fallbacks[0].Lang != "Kotlin" || len(fallbacks[0].Impls) != 1 || fallbacks[0].Impls[0].Id != 17 {
+		t.Errorf("Expected Java impl 17 as fallback for Kotlin, got %v", fallbacks[0])
+	}
+	if fallbacks[1].Lang != "Ruby" || len(fallbacks[1].Impls) != 1 || fallbacks[1].Impls[0].Id != 18 {
+		t.Errorf("Expected Python impl 18 as fallback for Ruby, got %v", fallbacks[1])
+	}
+	if fallbacks[2].Lang != "Cobol" || len(fallbacks[2].Impls) != 0 {
+		t.Errorf("Expected no fallback impl for Cobol, got %v", fallbacks[2])
+	}
+}
@@ -0,0 +1,227 @@
+package pig
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//
+// "Open in playground" links, for the impls without a DemoURL.
+// The program is assembled from the impl imports and code with the
+// language RunTemplate, and encoded into the playground URL.
+// C and C++ have templates for the playgrounds only: they are not run here.
+// No network access here: the Go Playground needs a share request,
+// whose payload is built by GoPlaygroundPayload.
+//
+
+// playgroundTemplates are the languages which may be opened in a playground,
+// but which are not run by the SnippetRunner: they have no Build nor Run.
+var playgroundTemplates = map[string]RunTemplate{
+	"C": {
+		FileName: "main.c",
+		Source: `{{.Imports}}
+
+{{if .TopLevel}}{{.Code}}
+
+int main(void) {
+	return 0;
+}
+{{else}}int main(void) {
+{{.Code}}
+	return 0;
+}
+{{end}}`,
+		TopLevel: cTopLevel,
+	},
+	"Cpp": {
+		FileName: "main.cpp",
+		Source: `{{.Imports}}
+
+{{if .TopLevel}}{{.Code}}
+
+int main() {
+	return 0;
+}
+{{else}}int main() {
+{{.Code}}
+	return 0;
+}
+{{end}}`,
+		TopLevel: cTopLevel,
+	},
+}
+
+// cTopLevel matches the C and C++ declarations: types, macros, and function definitions.
+var cTopLevel = regexp.MustCompile(`(?m)^(typedef|struct|enum|class|template|static|#define)\b|^[A-Za-z_][\w:<>,\t *&]*[ \t*&]+[A-Za-z_]\w*\s*\([^;]*\)\s*(const\s*)?\{?\s*$`)
+
+// wrapForPlayground generates the program of impl, from the playgroundTemplates
+// or else from the RunTemplates.
+func wrapForPlayground(impl *Impl) (string, error) {
+	if rt, ok := playgroundTemplates[impl.LanguageName]; ok {
+		return rt.Wrap(impl)
+	}
+	_, source, err := WrapSnippet(impl)
+	return source, err
+}
+
+// PlaygroundLink opens an impl in an online playground.
+type PlaygroundLink struct {
+	// Name of the playground, e.g. "Rust Playground".
+	Name string
+	// URL to open. For the Go Playground, this is a local URL which sends
+	// the share request.
+	URL string
+}
+
+// GoPlaygroundShareURL receives the GoPlaygroundPayload, and returns the snippet ID.
+const GoPlaygroundShareURL = "https://play.golang.org/share"
+
+// GoPlaygroundSnippetURL is the prefix of the shared snippets URLs.
+const GoPlaygroundSnippetURL = "https://play.golang.org/p/"
+
+// GoPlaygroundShare is a version of a Go impl shared in the Go Playground.
+// Its Datastore key name is "{idiomID}-{implID}-{implVersion}": each
+// version is shared only once.
+type GoPlaygroundShare struct {
+	IdiomID     int
+	ImplID      int
+	ImplVersion int
+	SnippetID   string
+	ShareDate   time.Time
+}
+
+var goPlaygroundSnippetIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ValidGoPlaygroundSnippetID tells if id, returned by the share request, looks like a snippet ID.
+func ValidGoPlaygroundSnippetID(id string) bool {
+	return goPlaygroundSnippetIDRegexp.MatchString(id)
+}
+
+// GoPlaygroundPayload is the body of the share request for impl,
+// a Go program.
+func GoPlaygroundPayload(impl *Impl) (string, error) {
+	if impl.LanguageName != "Go" {
+		return "", fmt.Errorf("impl %d is not in Go", impl.Id)
+	}
+	return wrapForPlayground(impl)
+}
+
+var rustEditionRegexp = regexp.MustCompile(`20(15|18|21|24)`)
+
+// RustPlaygroundURL opens impl, a Rust program, in the Rust Playground.
+// The edition is read from the impl Dialect, and defaults to 2021.
+func RustPlaygroundURL(impl *Impl) (string, error) {
+	if impl.LanguageName != "Rust" {
+		return "", fmt.Errorf("impl %d is not in Rust", impl.Id)
+	}
+	source, err := wrapForPlayground(impl)
+	if err != nil {
+		return "", err
+	}
+	edition := "2021"
+	if e := rustEditionRegexp.FindString(impl.Dialect); e != "" {
+		edition = e
+	}
+	params := url.Values{}
+	params.Set("version", "stable")
+	params.Set("mode", "debug")
+	params.Set("edition", edition)
+	params.Set("code", source)
+	return "https://play.rust-lang.org/?" + params.Encode(), nil
+}
+
+// Compiler Explorer client state, see
+// https://github.com/compiler-explorer/compiler-explorer/blob/main/docs/API.md
+type ceClientState struct {
+	Sessions []ceSession `json:"sessions"`
+}
+
+type ceSession struct {
+	ID        int          `json:"id"`
+	Language  string       `json:"language"`
+	Source    string       `json:"source"`
+	Compilers []ceCompiler `json:"compilers"`
+	Executors []ceExecutor `json:"executors"`
+}
+
+type ceCompiler struct {
+	ID      string `json:"id"`
+	Options string `json:"options"`
+}
+
+type ceExecutor struct {
+	Compiler ceCompiler `json:"compiler"`
+}
+
+var cppStandardRegexp = regexp.MustCompile(`(?i)^c(\+\+|pp)\s*(\d\d)$`)
+
+// CompilerExplorerURL opens impl, a C or C++ program, in Compiler Explorer.
+// The C++ standard is read from the impl Dialect, e.g. "C++20".
+func CompilerExplorerURL(impl *Impl) (string, error) {
+	var language, compiler, options string
+	switch impl.LanguageName {
+	case "C":
+		language, compiler = "c", "cg132"
+	case "Cpp":
+		language, compiler, options = "c++", "g132", "-std=c++17"
+		if m := cppStandardRegexp.FindStringSubmatch(strings.TrimSpace(impl.Dialect)); m != nil {
+			options = "-std=c++" + m[2]
+		}
+	default:
+		return "", fmt.Errorf("impl %d is not in C or C++", impl.Id)
+	}
+	source, err := wrapForPlayground(impl)
+	if err != nil {
+		return "", err
+	}
+	c := ceCompiler{ID: compiler, Options: options}
+	state := ceClientState{
+		Sessions: []ceSession{{
+			ID:        1,
+			Language:  language,
+			Source:    source,
+			Compilers: []ceCompiler{c},
+			Executors: []ceExecutor{{Compiler: c}},
+		}},
+	}
+	js, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return "https://godbolt.org/clientstate/" + url.PathEscape(base64.StdEncoding.EncodeToString(js)), nil
+}
+
+// PlaygroundFor returns the playground link of impl, if its language has a playground:
+// a built-in one, or else the PlaygroundURLTemplate of the Language.
+// goShareURL is the local URL sending the Go Playground share request.
+func PlaygroundFor(impl *Impl, goShareURL string) (PlaygroundLink, bool) {
+	var link PlaygroundLink
+	var err error
+	switch impl.LanguageName {
+	case "Go":
+		link.Name, link.URL = "Go Playground", goShareURL
+	case "Rust":
+		link.Name = "Rust Playground"
+		link.URL, err = RustPlaygroundURL(impl)
+	case "C", "Cpp":
+		link.Name = "Compiler Explorer"
+		link.URL, err = CompilerExplorerURL(impl)
+	default:
+		lg := FindLanguage(impl.LanguageName)
+		if lg == nil || lg.PlaygroundURLTemplate == "" {
+			return link, false
+		}
+		source, errWrap := wrapForPlayground(impl)
+		if errWrap != nil {
+			// No RunTemplate for this language: the snippet is opened as is.
+			source = NoCR(strings.TrimSpace(impl.ImportsBlock + "\n\n" + impl.CodeBlock))
+		}
+		link.Name, link.URL = lg.Nice()+" playground", lg.PlaygroundURL(source)
+	}
+	return link, err == nil
+}
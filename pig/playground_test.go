@@ -0,0 +1,119 @@
+package pig
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGoPlaygroundPayload(t *testing.T) {
+	impl := &Impl{Id: 1, LanguageName: "Go", ImportsBlock: `import "fmt"`, CodeBlock: `fmt.Println("Hello")`}
+	payload, err := GoPlaygroundPayload(impl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "package main\n\nimport \"fmt\"\n\nfunc main() {\nfmt.Println(\"Hello\")\n}\n"
+	if payload != expected {
+		t.Errorf("Expected %q, got %q", expected, payload)
+	}
+	if _, err := GoPlaygroundPayload(&Impl{LanguageName: "Rust"}); err == nil {
+		t.Errorf("Expected error for a Rust impl")
+	}
+}
+
+func TestValidGoPlaygroundSnippetID(t *testing.T) {
+	for id, valid := range map[string]bool{
+		"xMsFJfU9IFN":           true,
+		"":                      false,
+		"<html>":                false,
+		"a/../b":                false,
+		strings.Repeat("a", 65): false,
+	} {
+		if ValidGoPlaygroundSnippetID(id) != valid {
+			t.Errorf("ValidGoPlaygroundSnippetID(%q): expected %v", id, valid)
+		}
+	}
+}
+
+func TestRustPlaygroundURL(t *testing.T) {
+	impl := &Impl{Id: 2, LanguageName: "Rust", CodeBlock: `println!("Hello");`, Dialect: "Rust 2018 edition"}
+	u, err := RustPlaygroundURL(impl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := parsed.Query()
+	if parsed.Host != "play.rust-lang.org" || q.Get("edition") != "2018" || !strings.Contains(q.Get("code"), "fn main() {\nprintln!(\"Hello\");\n}") {
+		t.Errorf("Unexpected URL %s", u)
+	}
+	again, _ := RustPlaygroundURL(impl)
+	if again != u {
+		t.Errorf("Generation is not deterministic")
+	}
+}
+
+func TestCompilerExplorerURL(t *testing.T) {
+	impl := &Impl{Id: 3, LanguageName: "Cpp", ImportsBlock: "#include <iostream>", CodeBlock: `std::cout << "Hello";`, Dialect: "C++20"}
+	u, err := CompilerExplorerURL(impl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const prefix = "https://godbolt.org/clientstate/"
+	if !strings.HasPrefix(u, prefix) {
+		t.Fatalf("Unexpected URL %s", u)
+	}
+	encoded, err := url.PathUnescape(strings.TrimPrefix(u, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"language":"c++"`, `"options":"-std=c++20"`, `int main() {`} {
+		if !strings.Contains(string(state), expected) {
+			t.Errorf("Expected %q in client state %s", expected, state)
+		}
+	}
+}
+
+func TestCTopLevel(t *testing.T) {
+	impl := &Impl{LanguageName: "C", CodeBlock: "int add(int a, int b) {\n\treturn a + b;\n}"}
+	source, err := wrapForPlayground(impl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(source, "}\n\nint main(void) {\n\treturn 0;\n}") {
+		t.Errorf("Expected function declaration outside of main:\n%s", source)
+	}
+	impl.CodeBlock = "if (x) {\n\tputs(\"x\");\n}"
+	source, _ = wrapForPlayground(impl)
+	if !strings.HasPrefix(strings.TrimSpace(source), "int main(void) {\nif (x)") {
+		t.Errorf("Expected statements inside main:\n%s", source)
+	}
+}
+
+func TestPlaygroundFor(t *testing.T) {
+	if link, ok := PlaygroundFor(&Impl{LanguageName: "Go"}, "/playground-go/1/2"); !ok || link.URL != "/playground-go/1/2" {
+		t.Errorf("Unexpected Go link %v", link)
+	}
+	if _, ok := PlaygroundFor(&Impl{LanguageName: "Python"}, ""); ok {
+		t.Errorf("Expected no playground for Python")
+	}
+	if _, ok := RunTemplates["C"]; ok {
+		t.Errorf("C snippets are opened in Compiler Explorer, not run")
+	}
+
+	defer SetLanguages(DefaultLanguages())
+	SetLanguages([]*Language{
+		{Name: "Lua", PlaygroundURLTemplate: "https://lua.example/?code=%s"},
+	})
+	link, ok := PlaygroundFor(&Impl{LanguageName: "Lua", CodeBlock: "print(1)"}, "")
+	if !ok || link.URL != "https://lua.example/?code=print%281%29" {
+		t.Errorf("Unexpected Lua link %v", link)
+	}
+}
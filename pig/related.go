@@ -0,0 +1,254 @@
+package pig
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+//
+// Related idioms suggestions are computed from 4 signals:
+// the text similarity of titles and lead paragraphs, the shared
+// keywords, the shared tags, and the co-occurrence of the idioms
+// in the same user sessions.
+// An admin accepts (the relation is created) or rejects each suggestion.
+//
+
+// RelatedSignals are the similarity measures of 2 idioms, each in [0, 1].
+type RelatedSignals struct {
+	Text         float64
+	Keywords     float64
+	Tags         float64
+	Cooccurrence float64
+}
+
+// Score is the weighted sum of the signals, in [0, 1].
+func (s RelatedSignals) Score() float64 {
+	return 0.45*s.Text + 0.15*s.Keywords + 0.2*s.Tags + 0.2*s.Cooccurrence
+}
+
+const (
+	// MinSuggestionScore is the lowest score of a suggested relation.
+	MinSuggestionScore = 0.15
+	// HighConfidenceScore is the lowest score of a suggestion shown
+	// to the visitors, before any admin decision.
+	HighConfidenceScore = 0.4
+)
+
+// cooccurrenceHalf is the number of co-occurrences giving a signal of 0.5.
+const cooccurrenceHalf = 5
+
+// IdiomPair is 2 idiom IDs, lowest first.
+type IdiomPair [2]int
+
+// NewIdiomPair returns the pair {a, b}, whatever the order of a and b.
+func NewIdiomPair(a, b int) IdiomPair {
+	if a > b {
+		a, b = b, a
+	}
+	return IdiomPair{a, b}
+}
+
+// RelatedCandidate is a suggested relation.
+type RelatedCandidate struct {
+	Pair    IdiomPair
+	Signals RelatedSignals
+	Score   float64
+}
+
+// RelationSuggestionStatus is the admin decision about a suggested relation.
+type RelationSuggestionStatus string
+
+const (
+	RelationSuggestionPending  RelationSuggestionStatus = "pending"
+	RelationSuggestionAccepted RelationSuggestionStatus = "accepted"
+	RelationSuggestionRejected RelationSuggestionStatus = "rejected"
+)
+
+// RelationSuggestion is the persistent form of a RelatedCandidate.
+// Its Datastore key name is made of the 2 idiom IDs, so that a rejected
+// suggestion is never suggested again.
+type RelationSuggestion struct {
+	// IdiomIDs are the 2 idioms, lowest ID first.
+	IdiomIDs []int
+	// Titles of the 2 idioms, at computation time.
+	Titles       []string `datastore:",noindex"`
+	Signals      RelatedSignals
+	Score        float64
+	Status       RelationSuggestionStatus
+	ComputedDate time.Time
+	DecisionDate time.Time
+	Decider      string
+}
+
+// Other returns the ID and the title of the idiom related to idiomID.
+func (s *RelationSuggestion) Other(idiomID int) (int, string) {
+	if len(s.IdiomIDs) != 2 || len(s.Titles) != 2 {
+		return 0, ""
+	}
+	if s.IdiomIDs[0] == idiomID {
+		return s.IdiomIDs[1], s.Titles[1]
+	}
+	return s.IdiomIDs[0], s.Titles[0]
+}
+
+// relatedStopWords are too common in idioms statements to be meaningful.
+var relatedStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true,
+	"into": true, "that": true, "this": true, "are": true, "its": true,
+	"all": true, "each": true, "given": true, "new": true, "not": true,
+	"one": true, "which": true, "when": true, "you": true,
+	"your": true, "use": true, "using": true, "then": true, "than": true,
+	"only": true, "must": true, "should": true, "may": true, "has": true,
+	"have": true, "been": true, "will": true, "their": true, "there": true,
+}
+
+// relatedWords returns the meaningful words of a text, by count.
+func relatedWords(text string, weight int, counts map[string]int) {
+	for _, w := range SplitForIndexing(text, true) {
+		if len(w) < 3 || relatedStopWords[w] || RegexpDigitsOnly.MatchString(w) {
+			continue
+		}
+		counts[w] += weight
+	}
+}
+
+// relatedWordSet returns the distinct meaningful words of a text.
+func relatedWordSet(text string) map[string]bool {
+	counts := map[string]int{}
+	relatedWords(text, 1, counts)
+	set := make(map[string]bool, len(counts))
+	for w := range counts {
+		set[w] = true
+	}
+	return set
+}
+
+func stringSet(a []string) map[string]bool {
+	set := make(map[string]bool, len(a))
+	for _, s := range a {
+		set[s] = true
+	}
+	return set
+}
+
+// jaccard is the size of the intersection divided by the size of the union.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for x := range a {
+		if b[x] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// tfidf computes, for each idiom, the normalized TF-IDF vector of its
+// title (weighted double) and lead paragraph.
+func tfidf(idioms []*Idiom) []map[string]float64 {
+	counts := make([]map[string]int, len(idioms))
+	df := map[string]int{}
+	for i, idiom := range idioms {
+		counts[i] = map[string]int{}
+		relatedWords(idiom.Title, 2, counts[i])
+		relatedWords(idiom.LeadParagraph, 1, counts[i])
+		for w := range counts[i] {
+			df[w]++
+		}
+	}
+	n := float64(len(idioms))
+	vectors := make([]map[string]float64, len(idioms))
+	for i := range idioms {
+		v := make(map[string]float64, len(counts[i]))
+		norm := 0.0
+		for w, c := range counts[i] {
+			x := float64(c) * math.Log(1+n/float64(df[w]))
+			v[w] = x
+			norm += x * x
+		}
+		norm = math.Sqrt(norm)
+		for w := range v {
+			v[w] /= norm
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+func cosine(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	sum := 0.0
+	for w, x := range a {
+		sum += x * b[w]
+	}
+	return sum
+}
+
+// SuggestRelations computes, for each idiom, its best related candidates
+// (at most maxPerIdiom), excluding the idioms already related.
+// cooccurrences counts the sessions in which both idioms of a pair were viewed.
+// The result is sorted by decreasing score.
+func SuggestRelations(idioms []*Idiom, cooccurrences map[IdiomPair]int, maxPerIdiom int) []RelatedCandidate {
+	vectors := tfidf(idioms)
+	keywords := make([]map[string]bool, len(idioms))
+	tags := make([]map[string]bool, len(idioms))
+	for i, idiom := range idioms {
+		keywords[i] = relatedWordSet(idiom.ExtraKeywords)
+		tags[i] = stringSet(TagSlugsWithAncestors(idiom.Tags))
+	}
+
+	perIdiom := make(map[int][]RelatedCandidate, len(idioms))
+	for i, a := range idioms {
+		for j := i + 1; j < len(idioms); j++ {
+			b := idioms[j]
+			if containsInt(a.RelatedIdiomIds, b.Id) || containsInt(b.RelatedIdiomIds, a.Id) {
+				continue
+			}
+			pair := NewIdiomPair(a.Id, b.Id)
+			c := float64(cooccurrences[pair])
+			signals := RelatedSignals{
+				Text:         cosine(vectors[i], vectors[j]),
+				Keywords:     jaccard(keywords[i], keywords[j]),
+				Tags:         jaccard(tags[i], tags[j]),
+				Cooccurrence: c / (c + cooccurrenceHalf),
+			}
+			score := signals.Score()
+			if score < MinSuggestionScore {
+				continue
+			}
+			candidate := RelatedCandidate{Pair: pair, Signals: signals, Score: score}
+			perIdiom[a.Id] = append(perIdiom[a.Id], candidate)
+			perIdiom[b.Id] = append(perIdiom[b.Id], candidate)
+		}
+	}
+
+	seen := map[IdiomPair]bool{}
+	var result []RelatedCandidate
+	for _, candidates := range perIdiom {
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].Score > candidates[j].Score
+		})
+		if len(candidates) > maxPerIdiom {
+			candidates = candidates[:maxPerIdiom]
+		}
+		for _, candidate := range candidates {
+			if !seen[candidate.Pair] {
+				seen[candidate.Pair] = true
+				result = append(result, candidate)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Pair[0] < result[j].Pair[0] ||
+			(result[i].Pair[0] == result[j].Pair[0] && result[i].Pair[1] < result[j].Pair[1])
+	})
+	return result
+}
@@ -0,0 +1,89 @@
+package pig
+
+import (
+	"testing"
+)
+
+func relatedTestIdioms() []*Idiom {
+	return []*Idiom{
+		{Id: 1, Title: "Sort a list of strings", LeadParagraph: "Sort the elements of the list _items alphabetically", Tags: []string{"Collections > Sorting"}},
+		{Id: 2, Title: "Sort a list of integers", LeadParagraph: "Sort the elements of the list _items in ascending order", Tags: []string{"Collections > Sorting"}},
+		{Id: 3, Title: "Open a network socket", LeadParagraph: "Connect to a TCP server", Tags: []string{"Network"}},
+		{Id: 4, Title: "Read a file", LeadParagraph: "Read the whole contents of a file into a string", ExtraKeywords: "io disk"},
+		{Id: 5, Title: "Write a file", LeadParagraph: "Write a string into a new file", ExtraKeywords: "io disk"},
+	}
+}
+
+func TestNewIdiomPair(t *testing.T) {
+	if p := NewIdiomPair(7, 3); p != (IdiomPair{3, 7}) {
+		t.Errorf("Expected {3 7}, got %v", p)
+	}
+}
+
+func TestSuggestRelations(t *testing.T) {
+	idioms := relatedTestIdioms()
+	candidates := SuggestRelations(idioms, nil, 3)
+	if len(candidates) == 0 {
+		t.Fatalf("Expected some candidates")
+	}
+	if candidates[0].Pair != (IdiomPair{1, 2}) {
+		t.Errorf("Expected the sorting idioms as best candidate, got %v", candidates[0])
+	}
+	for _, c := range candidates {
+		if c.Pair[0] == 3 || c.Pair[1] == 3 {
+			t.Errorf("Idiom 3 has nothing in common with the others, got %v", c)
+		}
+		if c.Score < MinSuggestionScore {
+			t.Errorf("Score of %v is too low", c)
+		}
+	}
+	for i := 1; i < len(candidates); i++ {
+		if candidates[i].Score > candidates[i-1].Score {
+			t.Errorf("Candidates are not sorted by score")
+		}
+	}
+}
+
+func TestSuggestRelationsExcludesExisting(t *testing.T) {
+	idioms := relatedTestIdioms()
+	idioms[0].AddRelation(idioms[1])
+	for _, c := range SuggestRelations(idioms, nil, 3) {
+		if c.Pair == (IdiomPair{1, 2}) {
+			t.Errorf("Idioms 1 and 2 are already related")
+		}
+	}
+}
+
+func TestSuggestRelationsCooccurrence(t *testing.T) {
+	idioms := relatedTestIdioms()
+	without := SuggestRelations(idioms, nil, 3)
+	with := SuggestRelations(idioms, map[IdiomPair]int{{3, 4}: 50}, 3)
+	find := func(candidates []RelatedCandidate, pair IdiomPair) *RelatedCandidate {
+		for i := range candidates {
+			if candidates[i].Pair == pair {
+				return &candidates[i]
+			}
+		}
+		return nil
+	}
+	if find(without, IdiomPair{3, 4}) != nil {
+		t.Errorf("Unexpected candidate {3 4} without co-occurrences")
+	}
+	c := find(with, IdiomPair{3, 4})
+	if c == nil {
+		t.Fatalf("Expected candidate {3 4} from co-occurrences")
+	}
+	if c.Signals.Cooccurrence < 0.9 {
+		t.Errorf("Expected a strong co-occurrence signal, got %v", c.Signals.Cooccurrence)
+	}
+}
+
+func TestRelationSuggestionOther(t *testing.T) {
+	s := RelationSuggestion{IdiomIDs: []int{4, 5}, Titles: []string{"Read a file", "Write a file"}}
+	if id, title := s.Other(4); id != 5 || title != "Write a file" {
+		t.Errorf("Unexpected %d %q", id, title)
+	}
+	if id, title := s.Other(5); id != 4 || title != "Read a file" {
+		t.Errorf("Unexpected %d %q", id, title)
+	}
+}
@@ -0,0 +1,421 @@
+package pig
+
+import (
+	"fmt"
+	"sort"
+)
+
+//
+// Relations between idioms are bidirectional: when A is related to B,
+// B is related to A, with the inverse RelationType.
+// Each idiom stores its relations in 3 parallel slices:
+// RelatedIdiomIds, RelatedIdiomTitles (denormalized) and RelatedIdiomTypes.
+//
+
+// RelationType qualifies the link from an idiom to a related idiom.
+type RelationType string
+
+const (
+	// RelationSeeAlso is the default, unqualified relation.
+	RelationSeeAlso RelationType = "see-also"
+	// RelationGeneralizationOf means the idiom is a more general version of the related idiom.
+	RelationGeneralizationOf RelationType = "generalization-of"
+	// RelationSpecializationOf is the inverse of RelationGeneralizationOf.
+	RelationSpecializationOf RelationType = "specialization-of"
+	// RelationVariantOf means the idioms solve the same problem slightly differently.
+	RelationVariantOf RelationType = "variant-of"
+	// RelationInverseOf means the idiom undoes what the related idiom does,
+	// e.g. "Parse a date" and "Format a date".
+	RelationInverseOf RelationType = "inverse-of"
+)
+
+// RelationTypes are all the relation types, in display order.
+var RelationTypes = []RelationType{
+	RelationSeeAlso,
+	RelationGeneralizationOf,
+	RelationSpecializationOf,
+	RelationVariantOf,
+	RelationInverseOf,
+}
+
+// ParseRelationType returns the RelationType named s.
+// The empty string is RelationSeeAlso.
+func ParseRelationType(s string) (RelationType, bool) {
+	if s == "" {
+		return RelationSeeAlso, true
+	}
+	for _, t := range RelationTypes {
+		if string(t) == s {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// Inverse is the type of the relation in the other direction.
+func (t RelationType) Inverse() RelationType {
+	switch t {
+	case RelationGeneralizationOf:
+		return RelationSpecializationOf
+	case RelationSpecializationOf:
+		return RelationGeneralizationOf
+	case "":
+		return RelationSeeAlso
+	}
+	return t
+}
+
+// Label is the human-readable name of the relation type.
+func (t RelationType) Label() string {
+	switch t {
+	case RelationGeneralizationOf:
+		return "Generalization of"
+	case RelationSpecializationOf:
+		return "Special case of"
+	case RelationVariantOf:
+		return "Variant of"
+	case RelationInverseOf:
+		return "Inverse of"
+	}
+	return "See also"
+}
+
+// RelatedIdiom is 1 relation of an idiom.
+type RelatedIdiom struct {
+	Id    int
+	Title string
+	Type  RelationType
+}
+
+// RelatedIdioms returns the relations of idiom, in their display order.
+func (idiom *Idiom) RelatedIdioms() []RelatedIdiom {
+	related := make([]RelatedIdiom, len(idiom.RelatedIdiomIds))
+	for i, id := range idiom.RelatedIdiomIds {
+		related[i] = RelatedIdiom{Id: id, Type: RelationSeeAlso}
+		if i < len(idiom.RelatedIdiomTitles) {
+			related[i].Title = idiom.RelatedIdiomTitles[i]
+		}
+		if i < len(idiom.RelatedIdiomTypes) && idiom.RelatedIdiomTypes[i] != "" {
+			related[i].Type = idiom.RelatedIdiomTypes[i]
+		}
+	}
+	return related
+}
+
+// setRelatedIdioms replaces the 3 parallel slices.
+func (idiom *Idiom) setRelatedIdioms(related []RelatedIdiom) {
+	idiom.RelatedIdiomIds = nil
+	idiom.RelatedIdiomTitles = nil
+	idiom.RelatedIdiomTypes = nil
+	for _, r := range related {
+		idiom.RelatedIdiomIds = append(idiom.RelatedIdiomIds, r.Id)
+		idiom.RelatedIdiomTitles = append(idiom.RelatedIdiomTitles, r.Title)
+		idiom.RelatedIdiomTypes = append(idiom.RelatedIdiomTypes, r.Type)
+	}
+}
+
+// relateTo adds or updates the relation to other, in 1 direction only.
+// It returns false if nothing changed.
+func (idiom *Idiom) relateTo(other *Idiom, t RelationType) bool {
+	related := idiom.RelatedIdioms()
+	for i := range related {
+		if related[i].Id == other.Id {
+			if related[i].Type == t && related[i].Title == other.Title {
+				return false
+			}
+			related[i].Type = t
+			related[i].Title = other.Title
+			idiom.setRelatedIdioms(related)
+			return true
+		}
+	}
+	idiom.setRelatedIdioms(append(related, RelatedIdiom{Id: other.Id, Title: other.Title, Type: t}))
+	return true
+}
+
+// AddRelation creates a bidirectional link between 2 related Idioms.
+// An existing relation is left unchanged.
+func (idiom *Idiom) AddRelation(other *Idiom) {
+	if !containsInt(idiom.RelatedIdiomIds, other.Id) {
+		idiom.relateTo(other, RelationSeeAlso)
+		idiom.EditSummary = fmt.Sprintf("Linked to idiom #%d [%v]", other.Id, other.Title)
+	}
+	if !containsInt(other.RelatedIdiomIds, idiom.Id) {
+		other.relateTo(idiom, RelationSeeAlso)
+		other.EditSummary = fmt.Sprintf("Linked to idiom #%d [%v]", idiom.Id, idiom.Title)
+	}
+}
+
+// AddTypedRelation creates or retypes the bidirectional link between 2 Idioms.
+// t is the type of the relation from idiom to other.
+func (idiom *Idiom) AddTypedRelation(other *Idiom, t RelationType) error {
+	if idiom.Id == other.Id {
+		return fmt.Errorf("idiom %d can't be related to itself", idiom.Id)
+	}
+	if _, ok := ParseRelationType(string(t)); !ok {
+		return fmt.Errorf("unknown relation type %q", t)
+	}
+	if idiom.relateTo(other, t) {
+		idiom.EditSummary = fmt.Sprintf("Linked to idiom #%d [%v] (%s)", other.Id, other.Title, t)
+	}
+	if other.relateTo(idiom, t.Inverse()) {
+		other.EditSummary = fmt.Sprintf("Linked to idiom #%d [%v] (%s)", idiom.Id, idiom.Title, t.Inverse())
+	}
+	return nil
+}
+
+// RemoveRelatedIdiom removes the relation to idiom relatedID, in 1 direction only.
+// It returns false if there was no such relation.
+func (idiom *Idiom) RemoveRelatedIdiom(relatedID int) bool {
+	related := idiom.RelatedIdioms()
+	kept := related[:0]
+	for _, r := range related {
+		if r.Id != relatedID {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == len(related) {
+		return false
+	}
+	idiom.setRelatedIdioms(kept)
+	return true
+}
+
+// RemoveRelation deletes the bidirectional link between 2 Idioms.
+func (idiom *Idiom) RemoveRelation(other *Idiom) {
+	if idiom.RemoveRelatedIdiom(other.Id) {
+		idiom.EditSummary = fmt.Sprintf("Unlinked from idiom #%d [%v]", other.Id, other.Title)
+	}
+	if other.RemoveRelatedIdiom(idiom.Id) {
+		other.EditSummary = fmt.Sprintf("Unlinked from idiom #%d [%v]", idiom.Id, idiom.Title)
+	}
+}
+
+// ReorderRelations sets the display order of the related idioms.
+// ids must contain exactly the current related idioms IDs.
+func (idiom *Idiom) ReorderRelations(ids []int) error {
+	related := idiom.RelatedIdioms()
+	if len(ids) != len(related) {
+		return fmt.Errorf("expected %d related idioms, got %d", len(related), len(ids))
+	}
+	rank := make(map[int]int, len(ids))
+	for i, id := range ids {
+		if _, dup := rank[id]; dup {
+			return fmt.Errorf("related idiom %d appears twice", id)
+		}
+		rank[id] = i
+	}
+	for _, r := range related {
+		if _, ok := rank[r.Id]; !ok {
+			return fmt.Errorf("related idiom %d is missing", r.Id)
+		}
+	}
+	sort.Slice(related, func(i, j int) bool {
+		return rank[related[i].Id] < rank[related[j].Id]
+	})
+	idiom.setRelatedIdioms(related)
+	return nil
+}
+
+// MoveRelation moves the related idiom relatedID by delta positions
+// (negative is up), within bounds.
+func (idiom *Idiom) MoveRelation(relatedID int, delta int) error {
+	ids := append([]int(nil), idiom.RelatedIdiomIds...)
+	from := -1
+	for i, id := range ids {
+		if id == relatedID {
+			from = i
+		}
+	}
+	if from == -1 {
+		return fmt.Errorf("idiom %d is not related to idiom %d", relatedID, idiom.Id)
+	}
+	to := from + delta
+	if to < 0 {
+		to = 0
+	}
+	if to > len(ids)-1 {
+		to = len(ids) - 1
+	}
+	ids = append(ids[:from], ids[from+1:]...)
+	ids = append(ids[:to], append([]int{relatedID}, ids[to:]...)...)
+	return idiom.ReorderRelations(ids)
+}
+
+// RenameRelatedIdiom updates the denormalized title of the related idiom relatedID.
+// It returns false if nothing changed.
+func (idiom *Idiom) RenameRelatedIdiom(relatedID int, title string) bool {
+	related := idiom.RelatedIdioms()
+	changed := false
+	for i := range related {
+		if related[i].Id == relatedID && related[i].Title != title {
+			related[i].Title = title
+			changed = true
+		}
+	}
+	if changed {
+		idiom.setRelatedIdioms(related)
+	}
+	return changed
+}
+
+// RelationIssueKind is a category of inconsistency in the relations.
+type RelationIssueKind string
+
+const (
+	// RelationMalformed means the parallel slices have different lengths.
+	RelationMalformed RelationIssueKind = "malformed"
+	// RelationSelf means an idiom is related to itself.
+	RelationSelf RelationIssueKind = "self"
+	// RelationDuplicate means an idiom is related twice to the same idiom.
+	RelationDuplicate RelationIssueKind = "duplicate"
+	// RelationDangling means the related idiom doesn't exist anymore.
+	RelationDangling RelationIssueKind = "dangling"
+	// RelationStaleTitle means the denormalized title is not the actual title.
+	RelationStaleTitle RelationIssueKind = "stale-title"
+	// RelationMissingReverse means the related idiom is not related back.
+	RelationMissingReverse RelationIssueKind = "missing-reverse"
+	// RelationTypeMismatch means the 2 directions don't have inverse types.
+	RelationTypeMismatch RelationIssueKind = "type-mismatch"
+)
+
+// RelationIssue is 1 inconsistency found by CheckRelations.
+type RelationIssue struct {
+	IdiomID   int
+	RelatedID int
+	Kind      RelationIssueKind
+	Detail    string
+}
+
+// CheckRelations finds the inconsistencies in the relations of all the idioms.
+func CheckRelations(idioms []*Idiom) []RelationIssue {
+	byID := make(map[int]*Idiom, len(idioms))
+	for _, idiom := range idioms {
+		byID[idiom.Id] = idiom
+	}
+	var issues []RelationIssue
+	for _, idiom := range idioms {
+		n := len(idiom.RelatedIdiomIds)
+		if len(idiom.RelatedIdiomTitles) != n || (len(idiom.RelatedIdiomTypes) != 0 && len(idiom.RelatedIdiomTypes) != n) {
+			issues = append(issues, RelationIssue{
+				IdiomID: idiom.Id,
+				Kind:    RelationMalformed,
+				Detail:  fmt.Sprintf("%d ids, %d titles, %d types", n, len(idiom.RelatedIdiomTitles), len(idiom.RelatedIdiomTypes)),
+			})
+		}
+		seen := map[int]bool{}
+		for _, r := range idiom.RelatedIdioms() {
+			issue := RelationIssue{IdiomID: idiom.Id, RelatedID: r.Id}
+			other := byID[r.Id]
+			switch {
+			case r.Id == idiom.Id:
+				issue.Kind = RelationSelf
+			case seen[r.Id]:
+				issue.Kind = RelationDuplicate
+			case other == nil:
+				issue.Kind = RelationDangling
+			case r.Title != other.Title:
+				issue.Kind = RelationStaleTitle
+				issue.Detail = fmt.Sprintf("%q instead of %q", r.Title, other.Title)
+			}
+			seen[r.Id] = true
+			if issue.Kind != "" {
+				issues = append(issues, issue)
+			}
+			if other == nil || other == idiom {
+				continue
+			}
+			reverse, found := other.relationTo(idiom.Id)
+			switch {
+			case !found:
+				issues = append(issues, RelationIssue{IdiomID: idiom.Id, RelatedID: r.Id, Kind: RelationMissingReverse})
+			case reverse.Type != r.Type.Inverse() && idiom.Id < other.Id:
+				// Reported once, from the lowest ID
+				issues = append(issues, RelationIssue{
+					IdiomID:   idiom.Id,
+					RelatedID: r.Id,
+					Kind:      RelationTypeMismatch,
+					Detail:    fmt.Sprintf("%s vs %s", r.Type, reverse.Type),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+func (idiom *Idiom) relationTo(relatedID int) (RelatedIdiom, bool) {
+	for _, r := range idiom.RelatedIdioms() {
+		if r.Id == relatedID {
+			return r, true
+		}
+	}
+	return RelatedIdiom{}, false
+}
+
+// RepairRelations fixes the inconsistencies reported by CheckRelations.
+// When the 2 directions disagree, the relation of the lowest idiom ID wins.
+// It returns the idioms that were modified.
+func RepairRelations(idioms []*Idiom) []*Idiom {
+	byID := make(map[int]*Idiom, len(idioms))
+	for _, idiom := range idioms {
+		byID[idiom.Id] = idiom
+	}
+	modified := map[int]bool{}
+
+	// Cleanup each idiom on its own
+	for _, idiom := range idioms {
+		related := idiom.RelatedIdioms()
+		seen := map[int]bool{}
+		var kept []RelatedIdiom
+		for _, r := range related {
+			other := byID[r.Id]
+			if r.Id == idiom.Id || seen[r.Id] || other == nil {
+				continue
+			}
+			seen[r.Id] = true
+			r.Title = other.Title
+			kept = append(kept, r)
+		}
+		n := len(idiom.RelatedIdiomIds)
+		malformed := len(idiom.RelatedIdiomTitles) != n || (len(idiom.RelatedIdiomTypes) != 0 && len(idiom.RelatedIdiomTypes) != n)
+		if malformed || !sameRelations(kept, related) {
+			modified[idiom.Id] = true
+		}
+		idiom.setRelatedIdioms(kept)
+	}
+
+	// Then make both directions agree
+	sorted := append([]*Idiom(nil), idioms...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Id < sorted[j].Id })
+	for _, idiom := range sorted {
+		for _, r := range idiom.RelatedIdioms() {
+			other := byID[r.Id]
+			reverse, found := other.relationTo(idiom.Id)
+			if found && (reverse.Type == r.Type.Inverse() || other.Id < idiom.Id) {
+				// Consistent, or already fixed from the lowest ID
+				continue
+			}
+			other.relateTo(idiom, r.Type.Inverse())
+			modified[other.Id] = true
+		}
+	}
+	var result []*Idiom
+	for _, idiom := range sorted {
+		if modified[idiom.Id] {
+			result = append(result, idiom)
+		}
+	}
+	return result
+}
+
+func sameRelations(a, b []RelatedIdiom) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package pig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func relationTestIdioms() (a, b, c *Idiom) {
+	a = &Idiom{Id: 1, Title: "Parse a date"}
+	b = &Idiom{Id: 2, Title: "Format a date"}
+	c = &Idiom{Id: 3, Title: "Parse an ISO 8601 date"}
+	return
+}
+
+func TestAddTypedRelation(t *testing.T) {
+	a, b, c := relationTestIdioms()
+	if err := a.AddTypedRelation(b, RelationInverseOf); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddTypedRelation(c, RelationGeneralizationOf); err != nil {
+		t.Fatal(err)
+	}
+	expected := []RelatedIdiom{
+		{Id: 2, Title: "Format a date", Type: RelationInverseOf},
+		{Id: 3, Title: "Parse an ISO 8601 date", Type: RelationGeneralizationOf},
+	}
+	if got := a.RelatedIdioms(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if r, _ := c.relationTo(1); r.Type != RelationSpecializationOf {
+		t.Errorf("Expected the inverse type, got %q", r.Type)
+	}
+
+	// Retyping keeps the position
+	if err := a.AddTypedRelation(b, RelationVariantOf); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.RelatedIdioms()[0]; got.Id != 2 || got.Type != RelationVariantOf {
+		t.Errorf("Unexpected %v", got)
+	}
+
+	if err := a.AddTypedRelation(a, RelationSeeAlso); err == nil {
+		t.Errorf("Expected error for self relation")
+	}
+	if err := a.AddTypedRelation(b, "cousin-of"); err == nil {
+		t.Errorf("Expected error for unknown type")
+	}
+}
+
+func TestAddRelationLegacyData(t *testing.T) {
+	a, _, c := relationTestIdioms()
+	// Stored before the relation types existed
+	a.RelatedIdiomIds = []int{2}
+	a.RelatedIdiomTitles = []string{"Format a date"}
+	a.AddRelation(c)
+	expected := []RelationType{RelationSeeAlso, RelationSeeAlso}
+	if !reflect.DeepEqual(a.RelatedIdiomTypes, expected) {
+		t.Errorf("Expected %v, got %v", expected, a.RelatedIdiomTypes)
+	}
+}
+
+func TestRemoveRelation(t *testing.T) {
+	a, b, c := relationTestIdioms()
+	a.AddRelation(b)
+	a.AddRelation(c)
+	a.RemoveRelation(b)
+	if !reflect.DeepEqual(a.RelatedIdiomIds, []int{3}) || !reflect.DeepEqual(a.RelatedIdiomTitles, []string{c.Title}) {
+		t.Errorf("Unexpected %v %q", a.RelatedIdiomIds, a.RelatedIdiomTitles)
+	}
+	if len(b.RelatedIdiomIds) != 0 {
+		t.Errorf("Expected no relation left in b, got %v", b.RelatedIdiomIds)
+	}
+	if a.RemoveRelatedIdiom(2) {
+		t.Errorf("Relation to 2 was already removed")
+	}
+}
+
+func TestReorderRelations(t *testing.T) {
+	a := &Idiom{Id: 1}
+	for id := 2; id <= 5; id++ {
+		a.AddRelation(&Idiom{Id: id})
+	}
+	if err := a.ReorderRelations([]int{5, 3, 2, 4}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.RelatedIdiomIds, []int{5, 3, 2, 4}) {
+		t.Errorf("Unexpected order %v", a.RelatedIdiomIds)
+	}
+	for _, bad := range [][]int{{5, 3, 2}, {5, 3, 2, 2}, {5, 3, 2, 6}} {
+		if err := a.ReorderRelations(bad); err == nil {
+			t.Errorf("Expected error for %v", bad)
+		}
+	}
+
+	if err := a.MoveRelation(4, -1); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.RelatedIdiomIds, []int{5, 3, 4, 2}) {
+		t.Errorf("Unexpected order %v", a.RelatedIdiomIds)
+	}
+	if err := a.MoveRelation(5, -1); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.RelatedIdiomIds, []int{5, 3, 4, 2}) {
+		t.Errorf("Moving the first up should be a no-op, got %v", a.RelatedIdiomIds)
+	}
+	if err := a.MoveRelation(9, 1); err == nil {
+		t.Errorf("Expected error for unrelated idiom")
+	}
+}
+
+func TestRenameRelatedIdiom(t *testing.T) {
+	a, b, _ := relationTestIdioms()
+	a.AddRelation(b)
+	if !a.RenameRelatedIdiom(2, "Format a date as a string") {
+		t.Errorf("Expected a change")
+	}
+	if a.RelatedIdiomTitles[0] != "Format a date as a string" {
+		t.Errorf("Unexpected %q", a.RelatedIdiomTitles[0])
+	}
+	if a.RenameRelatedIdiom(2, "Format a date as a string") {
+		t.Errorf("Expected no change")
+	}
+}
+
+func brokenRelations() []*Idiom {
+	a, b, c := relationTestIdioms()
+	a.RelatedIdiomIds = []int{2, 1, 2, 9}
+	a.RelatedIdiomTitles = []string{"Old title", "Parse a date", "Old title", "Deleted"}
+	b.RelatedIdiomIds = []int{3}
+	b.RelatedIdiomTitles = []string{c.Title}
+	c.RelatedIdiomIds = []int{2}
+	c.RelatedIdiomTitles = []string{b.Title}
+	c.RelatedIdiomTypes = []RelationType{RelationVariantOf}
+	return []*Idiom{a, b, c}
+}
+
+func TestCheckRelations(t *testing.T) {
+	kinds := map[RelationIssueKind]int{}
+	for _, issue := range CheckRelations(brokenRelations()) {
+		kinds[issue.Kind]++
+	}
+	expected := map[RelationIssueKind]int{
+		RelationSelf:           1,
+		RelationDuplicate:      1,
+		RelationDangling:       1,
+		RelationStaleTitle:     1,
+		RelationMissingReverse: 2, // 1->2, and the duplicate 1->2
+		RelationTypeMismatch:   1,
+	}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("Expected %v, got %v", expected, kinds)
+	}
+}
+
+func TestRepairRelations(t *testing.T) {
+	idioms := brokenRelations()
+	modified := RepairRelations(idioms)
+	if len(modified) != 3 {
+		t.Errorf("Expected 3 modified idioms, got %d", len(modified))
+	}
+	if issues := CheckRelations(idioms); len(issues) != 0 {
+		t.Errorf("Expected no issue left, got %v", issues)
+	}
+	a, b := idioms[0], idioms[1]
+	if !reflect.DeepEqual(a.RelatedIdioms(), []RelatedIdiom{{Id: 2, Title: "Format a date", Type: RelationSeeAlso}}) {
+		t.Errorf("Unexpected %v", a.RelatedIdioms())
+	}
+	// The lowest ID wins
+	if r, _ := b.relationTo(3); r.Type != RelationSeeAlso {
+		t.Errorf("Expected see-also, got %q", r.Type)
+	}
+	if len(RepairRelations(idioms)) != 0 {
+		t.Errorf("Repairing twice should be a no-op")
+	}
+}
@@ -0,0 +1,80 @@
+package pig
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// Sandbox runs the build and run steps of the snippets in a throwaway
+// container, isolated from the host: no network, read-only root filesystem,
+// unprivileged uid, no capabilities, and limits on processes, file sizes,
+// CPU and memory. With the Runtime "runsc", the container is a gVisor sandbox.
+type Sandbox struct {
+	// Engine is the container CLI, e.g. "docker" or "podman".
+	Engine string
+	// Runtime is the OCI runtime, e.g. "runsc". Empty means the engine default.
+	Runtime string
+	// Image provides the toolchains of the RunTemplates.
+	Image string
+	// User is the "uid:gid" of the snippets, which owns nothing in the image.
+	User string
+}
+
+// sandboxWorkDir is the mount point of the program directory, in the container.
+const sandboxWorkDir = "/work"
+
+// NewSandbox is a gVisor sandbox run by docker, as user nobody.
+func NewSandbox(image string) *Sandbox {
+	return &Sandbox{
+		Engine:  "docker",
+		Runtime: "runsc",
+		Image:   image,
+		User:    "65534:65534",
+	}
+}
+
+// Command is the engine command line running command in a container
+// named name, with dir as its writable working directory.
+func (sb *Sandbox) Command(name, dir string, limits RunLimits, command []string) []string {
+	nproc := strconv.Itoa(limits.MaxProcesses)
+	fsize := strconv.Itoa(limits.MaxFileSizeKB * 1024)
+	cpu := strconv.Itoa(limits.CPUSeconds)
+	memory := fmt.Sprintf("%dk", limits.MemoryKB)
+	args := []string{
+		sb.Engine, "run", "--rm",
+		"--name", name,
+		"--network", "none",
+		"--read-only",
+		"--tmpfs", "/tmp:rw,nosuid,size=256m",
+		"--user", sb.User,
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--pids-limit", nproc,
+		"--ulimit", "nproc=" + nproc + ":" + nproc,
+		"--ulimit", "fsize=" + fsize + ":" + fsize,
+		"--ulimit", "cpu=" + cpu + ":" + cpu,
+		"--memory", memory,
+		"--memory-swap", memory,
+		"--volume", dir + ":" + sandboxWorkDir + ":rw",
+		"--workdir", sandboxWorkDir,
+	}
+	if sb.Runtime != "" {
+		args = append(args, "--runtime", sb.Runtime)
+	}
+	for _, v := range snippetEnv(sandboxWorkDir, "/tmp") {
+		args = append(args, "--env", v)
+	}
+	args = append(args, sb.Image)
+	return append(args, command...)
+}
+
+// newContainerName is unique, so that a timed out container can be killed.
+func newContainerName() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "snippet-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,386 @@
+package pig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//
+// The snippet runner wraps the ImportsBlock and CodeBlock of an Impl into a
+// runnable program, builds and executes it in a Sandbox with CPU, memory,
+// process, file size and time limits, and compares its standard output
+// with the Impl ExpectedOutput.
+//
+// The snippets are untrusted: without a Sandbox, nothing is executed.
+//
+
+// RunStatus is the outcome of a snippet execution.
+type RunStatus string
+
+const (
+	// RunPass means the output matches the expected output.
+	RunPass RunStatus = "pass"
+	// RunFail means the program ran, but its output or exit code is wrong.
+	RunFail RunStatus = "fail"
+	// RunCompileError means the program could not be built.
+	RunCompileError RunStatus = "compile-error"
+	// RunTimeout means the program was killed because it took too long.
+	RunTimeout RunStatus = "timeout"
+	// RunUnchecked means the program ran fine, but the impl has no expected output.
+	RunUnchecked RunStatus = "unchecked"
+	// RunUnsupported means no template or no toolchain for this language.
+	RunUnsupported RunStatus = "unsupported"
+	// RunError means the harness itself failed.
+	RunError RunStatus = "error"
+)
+
+// IsBroken tells if the status is a failure of the snippet (not of the harness).
+func (s RunStatus) IsBroken() bool {
+	return s == RunFail || s == RunCompileError || s == RunTimeout
+}
+
+// RunLimits constrain the execution of a snippet.
+type RunLimits struct {
+	// CPUSeconds is the max CPU time of the program (RLIMIT_CPU).
+	CPUSeconds int
+	// MemoryKB is the max memory of the program.
+	MemoryKB int
+	// MaxProcesses is the max number of processes and threads (RLIMIT_NPROC).
+	MaxProcesses int
+	// MaxFileSizeKB is the max size of a file written by the program (RLIMIT_FSIZE).
+	MaxFileSizeKB int
+	// Timeout is the max wall time of the program.
+	Timeout time.Duration
+	// MaxOutputBytes truncates stdout and stderr.
+	MaxOutputBytes int
+
+	// The compilers need more than the snippets.
+	BuildCPUSeconds int
+	BuildMemoryKB   int
+	BuildTimeout    time.Duration
+}
+
+// build are the limits of the build step.
+func (l RunLimits) build() RunLimits {
+	l.CPUSeconds = l.BuildCPUSeconds
+	l.MemoryKB = l.BuildMemoryKB
+	l.Timeout = l.BuildTimeout
+	return l
+}
+
+// DefaultRunLimits are reasonable for small snippets.
+var DefaultRunLimits = RunLimits{
+	CPUSeconds:     5,
+	MemoryKB:       512 * 1024,
+	MaxProcesses:   64,
+	MaxFileSizeKB:  32 * 1024,
+	Timeout:        10 * time.Second,
+	MaxOutputBytes: 64 * 1024,
+
+	BuildCPUSeconds: 60,
+	BuildMemoryKB:   2 * 1024 * 1024,
+	BuildTimeout:    60 * time.Second,
+}
+
+// RunTemplate describes how to turn a snippet into a program, for one language.
+type RunTemplate struct {
+	// FileName of the generated source file.
+	FileName string
+	// Source is a text/template receiving a SnippetSource.
+	Source string
+	// Build is the optional compilation command. Its failure means RunCompileError.
+	Build []string
+	// Run is the execution command.
+	Run []string
+	// TopLevel matches the snippets which are declarations (e.g. a function)
+	// rather than statements.
+	TopLevel *regexp.Regexp
+}
+
+// SnippetSource is the data given to a RunTemplate Source.
+type SnippetSource struct {
+	Imports string
+	Code    string
+	// TopLevel is true when Code is made of declarations.
+	TopLevel bool
+}
+
+// RunTemplates are the supported languages, by LanguageName.
+var RunTemplates = map[string]RunTemplate{
+	"Go": {
+		FileName: "main.go",
+		Source: `package main
+
+{{.Imports}}
+
+{{if .TopLevel}}{{.Code}}
+
+func main() {}
+{{else}}func main() {
+{{.Code}}
+}
+{{end}}`,
+		Build:    []string{"go", "build", "-o", "prog", "main.go"},
+		Run:      []string{"./prog"},
+		TopLevel: regexp.MustCompile(`(?m)^(func|type|var|const) `),
+	},
+	"Python": {
+		FileName: "main.py",
+		Source:   "{{.Imports}}\n\n{{.Code}}\n",
+		Run:      []string{"python3", "main.py"},
+	},
+	"JS": {
+		FileName: "main.js",
+		Source:   "{{.Imports}}\n\n{{.Code}}\n",
+		Run:      []string{"node", "main.js"},
+	},
+	"Ruby": {
+		FileName: "main.rb",
+		Source:   "{{.Imports}}\n\n{{.Code}}\n",
+		Run:      []string{"ruby", "main.rb"},
+	},
+	"PHP": {
+		FileName: "main.php",
+		Source:   "<?php\n{{.Imports}}\n\n{{.Code}}\n",
+		Run:      []string{"php", "main.php"},
+	},
+	"Rust": {
+		FileName: "main.rs",
+		Source: `{{.Imports}}
+
+{{if .TopLevel}}{{.Code}}
+
+fn main() {}
+{{else}}fn main() {
+{{.Code}}
+}
+{{end}}`,
+		Build:    []string{"rustc", "-o", "prog", "main.rs"},
+		Run:      []string{"./prog"},
+		TopLevel: regexp.MustCompile(`(?m)^(pub )?(fn|struct|enum|impl|trait|type|const|static) `),
+	},
+}
+
+// WrapSnippet generates the source code of a runnable program for impl.
+func WrapSnippet(impl *Impl) (RunTemplate, string, error) {
+	rt, ok := RunTemplates[impl.LanguageName]
+	if !ok {
+		return rt, "", fmt.Errorf("no run template for language %q", impl.LanguageName)
+	}
+	source, err := rt.Wrap(impl)
+	return rt, source, err
+}
+
+// Wrap generates the source code of the program of impl.
+func (rt RunTemplate) Wrap(impl *Impl) (string, error) {
+	src := SnippetSource{
+		Imports: NoCR(impl.ImportsBlock),
+		Code:    NoCR(impl.CodeBlock),
+	}
+	if rt.TopLevel != nil {
+		src.TopLevel = rt.TopLevel.MatchString(src.Code)
+	}
+	t, err := template.New(impl.LanguageName).Parse(rt.Source)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, src)
+	return buf.String(), err
+}
+
+// SameOutput compares program outputs, ignoring trailing spaces and trailing newlines.
+func SameOutput(actual, expected string) bool {
+	return normalizeOutput(actual) == normalizeOutput(expected)
+}
+
+func normalizeOutput(s string) string {
+	lines := strings.Split(NoCR(s), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// RunResult is the outcome of SnippetRunner.Run.
+type RunResult struct {
+	Status   RunStatus
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
+}
+
+// SnippetRunner executes snippets in a Sandbox.
+type SnippetRunner struct {
+	Limits RunLimits
+	// Sandbox isolates the build and run steps from the host.
+	// When nil, the snippets are not executed, unless AllowHost.
+	Sandbox *Sandbox
+	// AllowHost runs the snippets directly on the host, with ulimit.
+	// This is only for trusted code, e.g. in tests.
+	AllowHost bool
+	// WorkDir is where the temporary program directories are created.
+	// Empty means the default temp directory.
+	WorkDir string
+}
+
+// NewSnippetRunner creates a runner with DefaultRunLimits.
+func NewSnippetRunner(sandbox *Sandbox) *SnippetRunner {
+	return &SnippetRunner{Limits: DefaultRunLimits, Sandbox: sandbox}
+}
+
+// Supports tells if impl language has a run template, and a way to run it.
+// In a Sandbox, the toolchains are expected to be in the Sandbox image.
+func (sr *SnippetRunner) Supports(lang string) bool {
+	rt, ok := RunTemplates[lang]
+	if !ok {
+		return false
+	}
+	if sr.Sandbox != nil {
+		_, err := exec.LookPath(sr.Sandbox.Engine)
+		return err == nil
+	}
+	if !sr.AllowHost {
+		return false
+	}
+	cmd := rt.Run
+	if len(rt.Build) > 0 {
+		cmd = rt.Build
+	}
+	_, err := exec.LookPath(cmd[0])
+	return err == nil
+}
+
+// Run builds and executes impl, and compares its output with impl.ExpectedOutput.
+func (sr *SnippetRunner) Run(ctx context.Context, impl *Impl) RunResult {
+	if !sr.Supports(impl.LanguageName) {
+		return RunResult{Status: RunUnsupported}
+	}
+	rt, source, err := WrapSnippet(impl)
+	if err != nil {
+		return RunResult{Status: RunError, Stderr: err.Error()}
+	}
+	// The private parent keeps other host users away from the
+	// work directory, which the sandbox user must be able to write.
+	parent, err := ioutil.TempDir(sr.WorkDir, "snippet-")
+	if err != nil {
+		return RunResult{Status: RunError, Stderr: err.Error()}
+	}
+	defer os.RemoveAll(parent)
+	dir := filepath.Join(parent, "work")
+	if err = os.Mkdir(dir, 0700); err == nil && sr.Sandbox != nil {
+		err = os.Chmod(dir, 0777)
+	}
+	if err != nil {
+		return RunResult{Status: RunError, Stderr: err.Error()}
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, rt.FileName), []byte(source), 0644)
+	if err != nil {
+		return RunResult{Status: RunError, Stderr: err.Error()}
+	}
+
+	start := time.Now()
+	if len(rt.Build) > 0 {
+		_, stderr, timedOut, err := sr.exec(ctx, dir, rt.Build, sr.Limits.build())
+		if timedOut {
+			return RunResult{Status: RunTimeout, Stderr: stderr, Duration: time.Since(start)}
+		}
+		if err != nil {
+			return RunResult{Status: RunCompileError, Stderr: stderr, Duration: time.Since(start)}
+		}
+	}
+	stdout, stderr, timedOut, err := sr.exec(ctx, dir, rt.Run, sr.Limits)
+	result := RunResult{
+		Stdout:   stdout,
+		Stderr:   stderr,
+		Duration: time.Since(start),
+	}
+	switch {
+	case timedOut:
+		result.Status = RunTimeout
+	case err != nil:
+		result.Status = RunFail
+	case impl.ExpectedOutput == "":
+		result.Status = RunUnchecked
+	case SameOutput(stdout, impl.ExpectedOutput):
+		result.Status = RunPass
+	default:
+		result.Status = RunFail
+	}
+	return result
+}
+
+// exec runs command in dir, in the Sandbox, with limits.
+func (sr *SnippetRunner) exec(ctx context.Context, dir string, command []string, limits RunLimits) (stdout, stderr string, timedOut bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	var containerName string
+	if sr.Sandbox != nil {
+		containerName, err = newContainerName()
+		if err != nil {
+			return "", "", false, err
+		}
+		args := sr.Sandbox.Command(containerName, dir, limits, command)
+		cmd = exec.CommandContext(ctx, args[0], args[1:]...)
+	} else {
+		script := fmt.Sprintf(`ulimit -t %d && ulimit -d %d && ulimit -f %d && exec "$@"`, limits.CPUSeconds, limits.MemoryKB, limits.MaxFileSizeKB)
+		args := append([]string{"-c", script, "sh"}, command...)
+		cmd = exec.CommandContext(ctx, "sh", args...)
+		cmd.Dir = dir
+		cmd.Env = append([]string{"PATH=" + os.Getenv("PATH")}, snippetEnv(dir, dir)...)
+		// Trusted code may share the build cache
+		cmd.Env = append(cmd.Env, "GOCACHE="+filepath.Join(os.TempDir(), "snippet-gocache"))
+	}
+	outBuf := &limitedBuffer{max: limits.MaxOutputBytes}
+	errBuf := &limitedBuffer{max: limits.MaxOutputBytes}
+	cmd.Stdout = outBuf
+	cmd.Stderr = errBuf
+	err = cmd.Run()
+	timedOut = ctx.Err() == context.DeadlineExceeded
+	if timedOut && containerName != "" {
+		// Killing the engine client doesn't stop the container.
+		exec.Command(sr.Sandbox.Engine, "rm", "--force", containerName).Run()
+	}
+	return outBuf.String(), errBuf.String(), timedOut, err
+}
+
+// snippetEnv is the minimal environment of the build and run steps.
+func snippetEnv(home, tmp string) []string {
+	return []string{
+		"HOME=" + home,
+		"TMPDIR=" + tmp,
+		"GOCACHE=" + filepath.Join(tmp, "gocache"),
+		"GOPATH=" + filepath.Join(tmp, "gopath"),
+		"GO111MODULE=off",
+		"GOFLAGS=",
+	}
+}
+
+// limitedBuffer silently discards the bytes beyond max.
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := b.max - b.Len(); room < len(p) {
+		if room < 0 {
+			room = 0
+		}
+		p = p[:room]
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
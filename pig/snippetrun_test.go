@@ -0,0 +1,136 @@
+package pig
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrapSnippetGo(t *testing.T) {
+	for _, tc := range []struct {
+		code     string
+		topLevel bool
+	}{
+		{`fmt.Println("Hello")`, false},
+		{"func double(x int) int {\n\treturn 2 * x\n}", true},
+	} {
+		impl := &Impl{LanguageName: "Go", ImportsBlock: `import "fmt"`, CodeBlock: tc.code}
+		_, source, err := WrapSnippet(impl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(source, "package main") || !strings.Contains(source, tc.code) {
+			t.Errorf("Unexpected source %q", source)
+		}
+		if got := strings.Contains(source, "func main() {}"); got != tc.topLevel {
+			t.Errorf("For %q, expected top-level %v, got source %q", tc.code, tc.topLevel, source)
+		}
+	}
+}
+
+func TestWrapSnippetUnknownLanguage(t *testing.T) {
+	_, _, err := WrapSnippet(&Impl{LanguageName: "Fortran"})
+	if err == nil {
+		t.Errorf("Expected error for language without run template")
+	}
+}
+
+func TestSameOutput(t *testing.T) {
+	for _, tc := range []struct {
+		actual, expected string
+		same             bool
+	}{
+		{"3\n", "3", true},
+		{"a  \r\nb\n\n", "a\nb", true},
+		{"a\n\nb", "a\nb", false},
+		{"3", "4", false},
+	} {
+		if got := SameOutput(tc.actual, tc.expected); got != tc.same {
+			t.Errorf("SameOutput(%q, %q): expected %v, got %v", tc.actual, tc.expected, tc.same, got)
+		}
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := &limitedBuffer{max: 5}
+	b.Write([]byte("abc"))
+	n, err := b.Write([]byte("defgh"))
+	if n != 5 || err != nil {
+		t.Errorf("Expected full write to be reported, got %d, %v", n, err)
+	}
+	if b.String() != "abcde" {
+		t.Errorf("Expected truncated output, got %q", b.String())
+	}
+}
+
+func TestRunGoSnippet(t *testing.T) {
+	// The snippets below are trusted
+	runner := NewSnippetRunner(nil)
+	runner.AllowHost = true
+	if !runner.Supports("Go") {
+		t.Skip("Go toolchain not installed")
+	}
+	if testing.Short() {
+		t.Skip("Skipping snippet execution in short mode")
+	}
+	runner.Limits.Timeout = 60 * time.Second
+	runner.Limits.BuildTimeout = 5 * time.Minute
+	runner.Limits.BuildCPUSeconds = 300
+	ctx := context.Background()
+	for _, tc := range []struct {
+		impl     Impl
+		expected RunStatus
+	}{
+		{Impl{LanguageName: "Go", ImportsBlock: `import "fmt"`, CodeBlock: `fmt.Println(1 + 2)`, ExpectedOutput: "3"}, RunPass},
+		{Impl{LanguageName: "Go", ImportsBlock: `import "fmt"`, CodeBlock: `fmt.Println(1 + 2)`, ExpectedOutput: "4"}, RunFail},
+		{Impl{LanguageName: "Go", ImportsBlock: `import "fmt"`, CodeBlock: `fmt.Println(1 + 2)`}, RunUnchecked},
+		{Impl{LanguageName: "Go", CodeBlock: `x := `}, RunCompileError},
+		{Impl{LanguageName: "Go", CodeBlock: `panic("boom")`}, RunFail},
+		{Impl{LanguageName: "Go", ImportsBlock: `import "fmt"`, CodeBlock: "b := make([]byte, 1<<30)\nb[len(b)-1] = 1\nfmt.Println(len(b))", ExpectedOutput: "1073741824"}, RunFail},
+	} {
+		result := runner.Run(ctx, &tc.impl)
+		if result.Status != tc.expected {
+			t.Errorf("Running %q: expected %v, got %v (stderr %q)", tc.impl.CodeBlock, tc.expected, result.Status, result.Stderr)
+		}
+	}
+}
+
+func TestRunUnsupported(t *testing.T) {
+	result := NewSnippetRunner(nil).Run(context.Background(), &Impl{LanguageName: "Fortran"})
+	if result.Status != RunUnsupported {
+		t.Errorf("Expected %v, got %v", RunUnsupported, result.Status)
+	}
+}
+
+func TestRunWithoutSandbox(t *testing.T) {
+	impl := &Impl{LanguageName: "Python", CodeBlock: `print("pwned")`}
+	result := NewSnippetRunner(nil).Run(context.Background(), impl)
+	if result.Status != RunUnsupported {
+		t.Errorf("Expected %v without a sandbox, got %v", RunUnsupported, result.Status)
+	}
+}
+
+func TestSandboxCommand(t *testing.T) {
+	sb := NewSandbox("snippets:latest")
+	args := sb.Command("snippet-1", "/tmp/snippet-x/work", DefaultRunLimits, []string{"./prog"})
+	line := strings.Join(args, " ")
+	for _, expected := range []string{
+		"docker run --rm --name snippet-1 ",
+		" --network none ",
+		" --read-only ",
+		" --user 65534:65534 ",
+		" --cap-drop ALL ",
+		" --pids-limit 64 ",
+		" --ulimit nproc=64:64 ",
+		" --ulimit fsize=33554432:33554432 ",
+		" --memory 524288k ",
+		" --volume /tmp/snippet-x/work:/work:rw ",
+		" --runtime runsc ",
+		" snippets:latest ./prog",
+	} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("Expected %q in %q", expected, line)
+		}
+	}
+}
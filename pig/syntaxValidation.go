@@ -0,0 +1,153 @@
+package pig
+
+import (
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+//
+// Syntax validators detect obviously broken snippets, at save time.
+// They are keyed by LanguageName. Go snippets are checked with go/parser.
+// The languages having a Lexer but no registered validator are checked
+// for balanced brackets.
+//
+
+// SyntaxValidator returns an error describing the first syntax problem
+// found in the snippet, or nil if the snippet looks valid.
+type SyntaxValidator func(imports, code string) error
+
+var syntaxValidators = map[string]SyntaxValidator{}
+
+// RegisterSyntaxValidator sets the validator of a language, replacing any previous one.
+func RegisterSyntaxValidator(lang string, v SyntaxValidator) {
+	syntaxValidators[lang] = v
+}
+
+// findSyntaxValidator returns the validator of lang, or nil.
+func findSyntaxValidator(lang string) SyntaxValidator {
+	if v, ok := syntaxValidators[lang]; ok {
+		return v
+	}
+	if lexer := FindLexer(lang); lexer != nil {
+		return bracketsValidator(lexer)
+	}
+	return nil
+}
+
+// HasSyntaxValidator tells if snippets in lang can be validated.
+func HasSyntaxValidator(lang string) bool {
+	return findSyntaxValidator(lang) != nil
+}
+
+// ValidateSyntax checks the snippet with the validator of lang.
+// Languages without validator are always valid.
+func ValidateSyntax(lang, imports, code string) error {
+	v := findSyntaxValidator(lang)
+	if v == nil {
+		return nil
+	}
+	return v(NoCR(imports), NoCR(code))
+}
+
+// ValidateImplSyntax checks impl with the validator of its language.
+func ValidateImplSyntax(impl *Impl) error {
+	return ValidateSyntax(impl.LanguageName, impl.ImportsBlock, impl.CodeBlock)
+}
+
+func init() {
+	RegisterSyntaxValidator("Go", validateGoSyntax)
+}
+
+// goTopLevel matches the Go snippets which are declarations rather than statements.
+var goTopLevel = regexp.MustCompile(`(?m)^(func|type|var|const|import)\b`)
+
+// validateGoSyntax accepts a snippet made of either top-level declarations,
+// or statements.
+func validateGoSyntax(imports, code string) error {
+	header := "package p\n"
+	if strings.TrimSpace(imports) != "" {
+		if _, err := parser.ParseFile(token.NewFileSet(), "", header+imports, parser.ImportsOnly); err != nil {
+			return fmt.Errorf("imports: %v", goSyntaxError(err, 1))
+		}
+		header += imports + "\n"
+	}
+	offset := strings.Count(header, "\n")
+
+	_, fileErr := parser.ParseFile(token.NewFileSet(), "", header+code, 0)
+	if fileErr == nil {
+		return nil
+	}
+	_, stmtErr := parser.ParseFile(token.NewFileSet(), "", header+"func _() {\n"+code+"\n}", 0)
+	if stmtErr == nil {
+		return nil
+	}
+	if goTopLevel.MatchString(code) {
+		return goSyntaxError(fileErr, offset)
+	}
+	return goSyntaxError(stmtErr, offset+1)
+}
+
+// goSyntaxError keeps only the first error, with a line number relative to the snippet.
+func goSyntaxError(err error, lineOffset int) error {
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		e := list[0]
+		line := e.Pos.Line - lineOffset
+		if line < 1 {
+			line = 1
+		}
+		return fmt.Errorf("line %d: %s", line, e.Msg)
+	}
+	return err
+}
+
+var closingBrackets = map[rune]rune{')': '(', ']': '[', '}': '{'}
+
+// bracketsValidator checks that the brackets are balanced, outside of
+// the comments and string literals recognized by lexer.
+func bracketsValidator(lexer *Lexer) SyntaxValidator {
+	return func(imports, code string) error {
+		if err := checkBrackets(lexer, imports); err != nil {
+			return fmt.Errorf("imports: %v", err)
+		}
+		return checkBrackets(lexer, code)
+	}
+}
+
+func checkBrackets(lexer *Lexer, code string) (err error) {
+	type opening struct {
+		bracket rune
+		line    int
+	}
+	var stack []opening
+	line := 1
+	lexer.tokenize(code, func(class, text string) {
+		if err != nil {
+			return
+		}
+		if class == "pun" {
+			for _, c := range text {
+				switch c {
+				case '(', '[', '{':
+					stack = append(stack, opening{c, line})
+				case ')', ']', '}':
+					if len(stack) == 0 || stack[len(stack)-1].bracket != closingBrackets[c] {
+						err = fmt.Errorf("line %d: unexpected %q", line, c)
+						return
+					}
+					stack = stack[:len(stack)-1]
+				}
+			}
+			return
+		}
+		line += strings.Count(text, "\n")
+	})
+	if err == nil && len(stack) > 0 {
+		last := stack[len(stack)-1]
+		err = fmt.Errorf("line %d: unclosed %q", last.line, last.bracket)
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package pig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateSyntaxGo(t *testing.T) {
+	for _, tc := range []struct {
+		imports, code string
+		valid         bool
+		errContains   string
+	}{
+		{`import "fmt"`, `fmt.Println("Hello")`, true, ""},
+		{"import (\n\t\"fmt\"\n\t\"os\"\n)", "x := 3\nfmt.Fprintln(os.Stderr, x)", true, ""},
+		{"", "func double(x int) int {\n\treturn 2 * x\n}", true, ""},
+		{"", "type Point struct {\n\tX, Y float64\n}", true, ""},
+		{"", "for i := range items {\n\tfmt.Println(i)\n", false, ""},
+		{"", "x := \ny := 2", false, "line 2"},
+		{"", "a := 1\nb := )", false, "line 2"},
+		{"", "func f() {\n\treturn 1 +\n}", false, "line 3"},
+		{`import fmt`, `fmt.Println("Hello")`, false, "imports"},
+	} {
+		err := ValidateSyntax("Go", tc.imports, tc.code)
+		if (err == nil) != tc.valid {
+			t.Errorf("ValidateSyntax(%q, %q): expected valid=%v, got %v", tc.imports, tc.code, tc.valid, err)
+			continue
+		}
+		if err != nil && !strings.Contains(err.Error(), tc.errContains) {
+			t.Errorf("ValidateSyntax(%q, %q): expected error containing %q, got %v", tc.imports, tc.code, tc.errContains, err)
+		}
+	}
+}
+
+func TestValidateSyntaxBrackets(t *testing.T) {
+	for _, tc := range []struct {
+		lang, code string
+		valid      bool
+	}{
+		{"Java", "int[] a = {1, 2};\nSystem.out.println(a[0]);", true},
+		{"Java", "if (x > 0) {\n  y = f(x;\n}", false},
+		{"Java", "String s = \"(\"; // )", true},
+		{"Python", "print((1, 2)", false},
+		{"Python", "s = \"\"\"\n((\n\"\"\"", true},
+		{"C", "/* { */ int x = 1;", true},
+		{"Rust", "fn f<'a>(s: &'a str) -> &'a str { s }", true},
+		{"JS", "let f = () => { return [1, 2] };\n}", false},
+	} {
+		err := ValidateSyntax(tc.lang, "", tc.code)
+		if (err == nil) != tc.valid {
+			t.Errorf("ValidateSyntax(%q, %q): expected valid=%v, got %v", tc.lang, tc.code, tc.valid, err)
+		}
+	}
+}
+
+func TestValidateSyntaxUnknownLanguage(t *testing.T) {
+	if HasSyntaxValidator("Fortran") {
+		t.Errorf("Fortran should not have a validator")
+	}
+	if err := ValidateSyntax("Fortran", "", "((("); err != nil {
+		t.Errorf("Expected no validation for unknown language, got %v", err)
+	}
+}
+
+func TestRegisterSyntaxValidator(t *testing.T) {
+	defer delete(syntaxValidators, "Haskell")
+	RegisterSyntaxValidator("Haskell", func(imports, code string) error {
+		if strings.Contains(code, "undefined") {
+			return errors.New("no undefined, please")
+		}
+		return nil
+	})
+	if !HasSyntaxValidator("Haskell") {
+		t.Errorf("Haskell validator should be registered")
+	}
+	if err := ValidateImplSyntax(&Impl{LanguageName: "Haskell", CodeBlock: "f = undefined"}); err == nil {
+		t.Errorf("Expected Haskell validation error")
+	}
+}
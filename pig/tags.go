@@ -0,0 +1,135 @@
+package pig
+
+import (
+	"strings"
+	"unicode"
+)
+
+//
+// Tags are hierarchical categories of idioms.
+// "Strings > Formatting" is a subtag of "Strings".
+//
+// Each tag has a slug, for URLs and querying: "strings.formatting"
+//
+
+// TagSeparator separates the segments of a tag path.
+const TagSeparator = " > "
+
+// NormalizeTag cleans the spaces around and inside the segments of tag.
+// Ex: "  strings>Formatting " -> "strings > Formatting"
+func NormalizeTag(tag string) string {
+	segments := strings.Split(tag, ">")
+	kept := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		segment = strings.Join(strings.Fields(segment), " ")
+		if segment != "" {
+			kept = append(kept, segment)
+		}
+	}
+	return strings.Join(kept, TagSeparator)
+}
+
+// ParseTags reads a comma-separated list of tags.
+// Empty tags and duplicates are discarded.
+func ParseTags(s string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, chunk := range strings.Split(s, ",") {
+		tag := NormalizeTag(chunk)
+		slug := TagSlug(tag)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// TagSegments splits "Strings > Formatting" into [Strings Formatting].
+func TagSegments(tag string) []string {
+	tag = NormalizeTag(tag)
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, TagSeparator)
+}
+
+// TagRoot is the top-level segment of tag.
+func TagRoot(tag string) string {
+	segments := TagSegments(tag)
+	if len(segments) == 0 {
+		return ""
+	}
+	return segments[0]
+}
+
+// TagSlug is the URL-friendly form of tag.
+// Ex: "I/O > Files" -> "io.files", "Error handling" -> "error-handling"
+func TagSlug(tag string) string {
+	segments := TagSegments(tag)
+	slugs := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		var sb strings.Builder
+		dash := false
+		for _, c := range strings.ToLower(segment) {
+			switch {
+			case unicode.IsLetter(c) || unicode.IsDigit(c):
+				if dash && sb.Len() > 0 {
+					sb.WriteRune('-')
+				}
+				sb.WriteRune(c)
+				dash = false
+			case c == ' ' || c == '-' || c == '_':
+				dash = true
+			}
+		}
+		if sb.Len() > 0 {
+			slugs = append(slugs, sb.String())
+		}
+	}
+	return strings.Join(slugs, ".")
+}
+
+// TagAncestors returns the tag paths from the root down to tag included.
+// Ex: "A > B > C" -> ["A", "A > B", "A > B > C"]
+func TagAncestors(tag string) []string {
+	segments := TagSegments(tag)
+	paths := make([]string, len(segments))
+	for i := range segments {
+		paths[i] = strings.Join(segments[:i+1], TagSeparator)
+	}
+	return paths
+}
+
+// TagSlugsWithAncestors returns the slugs of tags and of all their ancestors,
+// without duplicates.
+func TagSlugsWithAncestors(tags []string) []string {
+	var slugs []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		for _, ancestor := range TagAncestors(tag) {
+			slug := TagSlug(ancestor)
+			if slug != "" && !seen[slug] {
+				seen[slug] = true
+				slugs = append(slugs, slug)
+			}
+		}
+	}
+	return slugs
+}
+
+// IsSubTagSlug tells if slug is equal to, or a descendant of, ancestorSlug.
+func IsSubTagSlug(slug, ancestorSlug string) bool {
+	return slug == ancestorSlug || strings.HasPrefix(slug, ancestorSlug+".")
+}
+
+// HasTag tells if idiom is tagged with slug, or with one of its subtags.
+func (idiom *Idiom) HasTag(slug string) bool {
+	for _, tag := range idiom.Tags {
+		if IsSubTagSlug(TagSlug(tag), slug) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package pig
+
+import (
+	"reflect"
+	"testing"
+)
+
+var tagSlugTests = []struct {
+	in  string
+	out string
+}{
+	{"", ""},
+	{"Concurrency", "concurrency"},
+	{"Strings > Formatting", "strings.formatting"},
+	{" strings>formatting ", "strings.formatting"},
+	{"I/O > Files", "io.files"},
+	{"Error  handling", "error-handling"},
+	{"Collections > Maps - hash", "collections.maps-hash"},
+	{"> >", ""},
+}
+
+func TestTagSlug(t *testing.T) {
+	for i, tt := range tagSlugTests {
+		if out := TagSlug(tt.in); out != tt.out {
+			t.Errorf("%d. TagSlug(%q) => %q, want %q", i, tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tags := ParseTags(" strings>Formatting, Concurrency,, concurrency , I/O >  Files")
+	expected := []string{"strings > Formatting", "Concurrency", "I/O > Files"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected %q, got %q", expected, tags)
+	}
+}
+
+func TestTagSlugsWithAncestors(t *testing.T) {
+	slugs := TagSlugsWithAncestors([]string{"Collections > Maps > Ordered", "Collections > Lists"})
+	expected := []string{"collections", "collections.maps", "collections.maps.ordered", "collections.lists"}
+	if !reflect.DeepEqual(slugs, expected) {
+		t.Errorf("Expected %q, got %q", expected, slugs)
+	}
+}
+
+func TestHasTag(t *testing.T) {
+	idiom := sampleIdiom()
+	idiom.Tags = []string{"Strings > Formatting"}
+	for slug, expected := range map[string]bool{
+		"strings":            true,
+		"strings.formatting": true,
+		"strings.format":     false,
+		"str":                false,
+		"concurrency":        false,
+	} {
+		if got := idiom.HasTag(slug); got != expected {
+			t.Errorf("HasTag(%q) => %v, want %v", slug, got, expected)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package pig
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+)
+
+//
+// The idioms are written in English. A Translation holds the title,
+// the lead paragraph and the impl comments of 1 idiom in 1 other
+// human language (a locale). The missing texts fall back to English.
+//
+
+// DefaultLocale is the human language of the idioms original texts.
+const DefaultLocale = "en"
+
+// Locales are the human languages into which the idioms may be translated,
+// with their own name.
+var Locales = map[string]string{
+	"de": "Deutsch",
+	"es": "Español",
+	"fr": "Français",
+	"pt": "Português",
+	"zh": "中文",
+}
+
+// LocaleCodes returns the codes of the Locales, sorted.
+func LocaleCodes() []string {
+	codes := make([]string, 0, len(Locales))
+	for code := range Locales {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// NormLocale returns the code of a supported locale, or "" if s is not supported.
+// "fr", "FR", "fr-CA" and "fr_FR" all give "fr".
+// The DefaultLocale is supported.
+func NormLocale(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexAny(s, "-_"); i != -1 {
+		s = s[:i]
+	}
+	if s == DefaultLocale {
+		return s
+	}
+	if _, ok := Locales[s]; ok {
+		return s
+	}
+	return ""
+}
+
+// Translation is the translation of an idiom into a locale.
+// The Datastore key name is made of the idiom ID and the locale.
+type Translation struct {
+	IdiomID int
+	Locale  string
+
+	Title         string
+	LeadParagraph string `datastore:",noindex"`
+
+	// ImplIDs and ImplComments are parallel slices:
+	// the translated AuthorComment of each impl.
+	ImplIDs      []int    `datastore:",noindex"`
+	ImplComments []string `datastore:",noindex"`
+
+	// SourceHash is the TranslationSourceHash of the idiom, when it was translated.
+	SourceHash string `datastore:",noindex"`
+
+	Version     int
+	VersionDate time.Time
+	LastEditor  string
+	EditSummary string `datastore:",noindex"`
+}
+
+// TranslationHistory stores the old versions of Translations.
+type TranslationHistory struct {
+	Translation
+}
+
+// ImplComment returns the translated comment of impl implID, or "".
+func (t *Translation) ImplComment(implID int) string {
+	for i, id := range t.ImplIDs {
+		if id == implID {
+			return t.ImplComments[i]
+		}
+	}
+	return ""
+}
+
+// SetImplComment sets the translated comment of impl implID.
+// An empty comment removes the translation.
+func (t *Translation) SetImplComment(implID int, comment string) {
+	for i, id := range t.ImplIDs {
+		if id == implID {
+			if comment == "" {
+				t.ImplIDs = append(t.ImplIDs[:i], t.ImplIDs[i+1:]...)
+				t.ImplComments = append(t.ImplComments[:i], t.ImplComments[i+1:]...)
+			} else {
+				t.ImplComments[i] = comment
+			}
+			return
+		}
+	}
+	if comment != "" {
+		t.ImplIDs = append(t.ImplIDs, implID)
+		t.ImplComments = append(t.ImplComments, comment)
+	}
+}
+
+// Apply replaces the English texts of idiom with their translations.
+// Texts not translated are left in English.
+func (t *Translation) Apply(idiom *Idiom) {
+	if t == nil {
+		return
+	}
+	idiom.Deco.Locale = t.Locale
+	if t.Title != "" {
+		idiom.Title = t.Title
+	}
+	if t.LeadParagraph != "" {
+		idiom.LeadParagraph = t.LeadParagraph
+	}
+	for i := range idiom.Implementations {
+		impl := &idiom.Implementations[i]
+		if comment := t.ImplComment(impl.Id); comment != "" {
+			impl.AuthorComment = comment
+		}
+	}
+}
+
+// TranslationSourceHash is a fingerprint of the English texts of idiom,
+// to detect the translations made from an older version.
+func TranslationSourceHash(idiom *Idiom) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\n%s\n", idiom.Title, idiom.LeadParagraph)
+	for _, impl := range idiom.Implementations {
+		if impl.AuthorComment != "" {
+			fmt.Fprintf(h, "%d\n%s\n", impl.Id, impl.AuthorComment)
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// ExtractIndexableWords computes the list of words contained in a Translation.
+func (t *Translation) ExtractIndexableWords() []string {
+	w := SplitForIndexing(t.Title, true)
+	w = append(w, SplitForIndexing(t.LeadParagraph, true)...)
+	for _, comment := range t.ImplComments {
+		w = append(w, SplitForIndexing(comment, true)...)
+	}
+	return w
+}
+
+// TranslationStatus tells whether an idiom needs some translation work.
+type TranslationStatus string
+
+const (
+	// TranslationUpToDate means nothing to do.
+	TranslationUpToDate TranslationStatus = ""
+	// TranslationMissing means the idiom was never translated.
+	TranslationMissing TranslationStatus = "missing"
+	// TranslationIncomplete means some texts are not translated.
+	TranslationIncomplete TranslationStatus = "incomplete"
+	// TranslationOutdated means the English texts have changed since the translation.
+	TranslationOutdated TranslationStatus = "outdated"
+)
+
+// CheckTranslation tells if t is a complete and up-to-date translation of idiom.
+// t may be nil.
+func CheckTranslation(idiom *Idiom, t *Translation) TranslationStatus {
+	if t == nil {
+		return TranslationMissing
+	}
+	if t.Title == "" || (t.LeadParagraph == "" && idiom.LeadParagraph != "") {
+		return TranslationIncomplete
+	}
+	for _, impl := range idiom.Implementations {
+		if impl.AuthorComment != "" && t.ImplComment(impl.Id) == "" {
+			return TranslationIncomplete
+		}
+	}
+	if t.SourceHash != TranslationSourceHash(idiom) {
+		return TranslationOutdated
+	}
+	return TranslationUpToDate
+}
@@ -0,0 +1,117 @@
+package pig
+
+import (
+	"reflect"
+	"testing"
+)
+
+var normLocaleTests = []struct {
+	in  string
+	out string
+}{
+	{"fr", "fr"},
+	{"FR", "fr"},
+	{"fr-CA", "fr"},
+	{"zh_CN", "zh"},
+	{" es ", "es"},
+	{"en", "en"},
+	{"en-US", "en"},
+	{"xx", ""},
+	{"", ""},
+}
+
+func TestNormLocale(t *testing.T) {
+	for i, tt := range normLocaleTests {
+		if out := NormLocale(tt.in); out != tt.out {
+			t.Errorf("%d. NormLocale(%q) => %q, want %q", i, tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestSetImplComment(t *testing.T) {
+	var tr Translation
+	tr.SetImplComment(3, "trois")
+	tr.SetImplComment(5, "cinq")
+	tr.SetImplComment(3, "Trois")
+	tr.SetImplComment(7, "")
+	if !reflect.DeepEqual(tr.ImplIDs, []int{3, 5}) || !reflect.DeepEqual(tr.ImplComments, []string{"Trois", "cinq"}) {
+		t.Errorf("Unexpected %v %q", tr.ImplIDs, tr.ImplComments)
+	}
+	tr.SetImplComment(3, "")
+	if !reflect.DeepEqual(tr.ImplIDs, []int{5}) || !reflect.DeepEqual(tr.ImplComments, []string{"cinq"}) {
+		t.Errorf("Unexpected %v %q", tr.ImplIDs, tr.ImplComments)
+	}
+	if c := tr.ImplComment(5); c != "cinq" {
+		t.Errorf("Expected cinq, got %q", c)
+	}
+	if c := tr.ImplComment(3); c != "" {
+		t.Errorf("Expected no comment, got %q", c)
+	}
+}
+
+func translatableIdiom() *Idiom {
+	return &Idiom{
+		Id:            1,
+		Title:         "Print Hello World",
+		LeadParagraph: "Print a literal string on standard output",
+		Implementations: []Impl{
+			{Id: 10, LanguageName: "Go", AuthorComment: "Println adds a newline"},
+			{Id: 11, LanguageName: "Python"},
+		},
+	}
+}
+
+func TestTranslationApply(t *testing.T) {
+	idiom := translatableIdiom()
+	tr := &Translation{
+		IdiomID: 1,
+		Locale:  "fr",
+		Title:   "Afficher Hello World",
+	}
+	tr.Apply(idiom)
+	if idiom.Title != "Afficher Hello World" {
+		t.Errorf("Title not translated: %q", idiom.Title)
+	}
+	if idiom.LeadParagraph != "Print a literal string on standard output" {
+		t.Errorf("Lead paragraph should fall back to English, got %q", idiom.LeadParagraph)
+	}
+	if idiom.Deco.Locale != "fr" {
+		t.Errorf("Expected Deco.Locale fr, got %q", idiom.Deco.Locale)
+	}
+
+	var none *Translation
+	idiom = translatableIdiom()
+	none.Apply(idiom)
+	if idiom.Title != "Print Hello World" || idiom.Deco.Locale != "" {
+		t.Errorf("nil Translation should not change idiom")
+	}
+}
+
+func TestCheckTranslation(t *testing.T) {
+	idiom := translatableIdiom()
+	if status := CheckTranslation(idiom, nil); status != TranslationMissing {
+		t.Errorf("Expected missing, got %q", status)
+	}
+
+	tr := &Translation{
+		Title:         "Afficher Hello World",
+		LeadParagraph: "Afficher une chaîne littérale sur la sortie standard",
+		SourceHash:    TranslationSourceHash(idiom),
+	}
+	if status := CheckTranslation(idiom, tr); status != TranslationIncomplete {
+		t.Errorf("Expected incomplete (impl comment), got %q", status)
+	}
+	tr.SetImplComment(10, "Println ajoute un saut de ligne")
+	if status := CheckTranslation(idiom, tr); status != TranslationUpToDate {
+		t.Errorf("Expected up-to-date, got %q", status)
+	}
+
+	idiom.Implementations[1].AuthorComment = "print is a function in Python 3"
+	if status := CheckTranslation(idiom, tr); status != TranslationIncomplete {
+		t.Errorf("Expected incomplete (new impl comment), got %q", status)
+	}
+	tr.SetImplComment(11, "print est une fonction en Python 3")
+	if status := CheckTranslation(idiom, tr); status != TranslationOutdated {
+		t.Errorf("Expected outdated, got %q", status)
+	}
+}
@@ -0,0 +1,197 @@
+package pig
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//
+// The Variables of an idiom are the names that every impl snippet should contain.
+// The linter checks each impl, ignoring comments and string literals
+// when the language has a Lexer.
+//
+// Names match loosely: "itemCount", "item_count" and "ItemCount" are the same
+// variable, because naming conventions differ between languages.
+//
+
+// VariablesLint is the report of LintImplVariables.
+type VariablesLint struct {
+	// Missing are the declared variables not found in the snippet.
+	Missing []string
+	// Conflicts are the declared variables spelled in several ways in the snippet.
+	Conflicts []VariableConflict
+}
+
+// VariableConflict is a declared variable and its different spellings found in a snippet.
+type VariableConflict struct {
+	Variable  string
+	Spellings []string
+}
+
+// OK tells if the snippet is consistent with the declared variables.
+func (lint VariablesLint) OK() bool {
+	return len(lint.Missing) == 0 && len(lint.Conflicts) == 0
+}
+
+// String is a human-readable summary of the problems.
+func (lint VariablesLint) String() string {
+	var parts []string
+	if len(lint.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(lint.Missing, ", "))
+	}
+	for _, c := range lint.Conflicts {
+		parts = append(parts, c.Variable+" spelled "+strings.Join(c.Spellings, " and "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// rxIdentifier matches identifiers, including the sigils of PHP, Perl, Ruby.
+var rxIdentifier = regexp.MustCompile(`[$@%]*[\pL_][\pL\pN_]*`)
+
+// looseName is the form used to match identifiers with variables.
+func looseName(name string) string {
+	name = strings.TrimLeft(name, "$@%")
+	return strings.ToLower(strings.Replace(name, "_", "", -1))
+}
+
+// CleanVariables trims and dedups the declared variables of an idiom.
+// A leading underscore (markup emphasis) is removed.
+func CleanVariables(variables []string) []string {
+	var clean []string
+	seen := map[string]bool{}
+	for _, v := range variables {
+		v = strings.TrimLeft(strings.TrimSpace(v), "_")
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		clean = append(clean, v)
+	}
+	return clean
+}
+
+// CodeIdentifiers returns the distinct identifiers of code, in order of appearance.
+// Comments, strings and keywords are skipped when lang has a Lexer.
+func CodeIdentifiers(lang, code string) []string {
+	var identifiers []string
+	seen := map[string]bool{}
+	collect := func(text string) {
+		for _, id := range rxIdentifier.FindAllString(text, -1) {
+			if !seen[id] {
+				seen[id] = true
+				identifiers = append(identifiers, id)
+			}
+		}
+	}
+	lx := FindLexer(lang)
+	if lx == nil {
+		collect(code)
+		return identifiers
+	}
+	lx.tokenize(code, func(class, text string) {
+		if class == "" || class == "typ" {
+			collect(text)
+		}
+	})
+	return identifiers
+}
+
+// LintImplVariables checks that impl contains each of the variables.
+func LintImplVariables(variables []string, impl *Impl) VariablesLint {
+	var lint VariablesLint
+	variables = CleanVariables(variables)
+	if len(variables) == 0 {
+		return lint
+	}
+	spellings := map[string][]string{}
+	for _, id := range CodeIdentifiers(impl.LanguageName, impl.CodeBlock) {
+		loose := looseName(id)
+		spellings[loose] = append(spellings[loose], strings.TrimLeft(id, "$@%"))
+	}
+	for _, v := range variables {
+		found := dedupStrings(spellings[looseName(v)])
+		switch len(found) {
+		case 0:
+			lint.Missing = append(lint.Missing, v)
+		case 1:
+		default:
+			sort.Strings(found)
+			lint.Conflicts = append(lint.Conflicts, VariableConflict{Variable: v, Spellings: found})
+		}
+	}
+	return lint
+}
+
+// LintVariables checks all the impls of idiom. The result contains
+// only the impls having problems, by impl ID.
+func (idiom *Idiom) LintVariables() map[int]VariablesLint {
+	problems := map[int]VariablesLint{}
+	for i := range idiom.Implementations {
+		impl := &idiom.Implementations[i]
+		if lint := LintImplVariables(idiom.Variables, impl); !lint.OK() {
+			problems[impl.Id] = lint
+		}
+	}
+	return problems
+}
+
+func dedupStrings(a []string) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, s := range a {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// HighlightHTMLWithVariables is like HighlightHTML, and additionally wraps
+// the occurrences of the variables in spans of class "variable".
+func HighlightHTMLWithVariables(lang, code string, variables []string) (h string, ok bool) {
+	variables = CleanVariables(variables)
+	if len(variables) == 0 {
+		return HighlightHTML(lang, code)
+	}
+	lx := FindLexer(lang)
+	if lx == nil {
+		return "", false
+	}
+	wanted := map[string]bool{}
+	for _, v := range variables {
+		wanted[looseName(v)] = true
+	}
+	emphasize := func(text string) string {
+		var buf strings.Builder
+		last := 0
+		for _, loc := range rxIdentifier.FindAllStringIndex(text, -1) {
+			buf.WriteString(html.EscapeString(text[last:loc[0]]))
+			id := text[loc[0]:loc[1]]
+			if wanted[looseName(id)] {
+				buf.WriteString(`<span class="variable">` + html.EscapeString(id) + `</span>`)
+			} else {
+				buf.WriteString(html.EscapeString(id))
+			}
+			last = loc[1]
+		}
+		buf.WriteString(html.EscapeString(text[last:]))
+		return buf.String()
+	}
+	var buf strings.Builder
+	lx.tokenize(code, func(class, text string) {
+		switch class {
+		case "":
+			buf.WriteString(emphasize(text))
+		case "typ":
+			buf.WriteString(`<span class="typ">` + emphasize(text) + `</span>`)
+		default:
+			buf.WriteString(`<span class="` + class + `">`)
+			buf.WriteString(html.EscapeString(text))
+			buf.WriteString(`</span>`)
+		}
+	})
+	return buf.String(), true
+}
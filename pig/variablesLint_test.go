@@ -0,0 +1,78 @@
+package pig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCodeIdentifiers(t *testing.T) {
+	got := CodeIdentifiers("Go", "// x is ignored\ny := len(\"z\") + foo.Bar")
+	expected := []string{"y", "len", "foo", "Bar"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	got = CodeIdentifiers("PHP", "$items = array();")
+	if len(got) == 0 || got[0] != "$items" {
+		t.Errorf("Expected PHP sigil to be kept, got %v", got)
+	}
+}
+
+func TestLintImplVariables(t *testing.T) {
+	for _, tc := range []struct {
+		variables []string
+		lang      string
+		code      string
+		missing   []string
+		conflicts int
+	}{
+		{[]string{"x", "items"}, "Go", "for _, x := range items {}", nil, 0},
+		{[]string{"x", "items"}, "Go", "for _, y := range items {}", []string{"x"}, 0},
+		{[]string{"x"}, "Go", "// x\ny := \"x\"", []string{"x"}, 0},
+		{[]string{"itemCount"}, "Python", "item_count = 3", nil, 0},
+		{[]string{"_s"}, "PHP", "echo $s;", nil, 0},
+		{[]string{"s"}, "Go", "s := strings.ToUpper(S)", nil, 1},
+		{[]string{"x"}, "Fortran", "x = 1", nil, 0},
+		{nil, "Go", "anything()", nil, 0},
+	} {
+		lint := LintImplVariables(tc.variables, &Impl{LanguageName: tc.lang, CodeBlock: tc.code})
+		if strings.Join(lint.Missing, ",") != strings.Join(tc.missing, ",") {
+			t.Errorf("%v in %q: expected missing %v, got %v", tc.variables, tc.code, tc.missing, lint.Missing)
+		}
+		if len(lint.Conflicts) != tc.conflicts {
+			t.Errorf("%v in %q: expected %d conflicts, got %v", tc.variables, tc.code, tc.conflicts, lint.Conflicts)
+		}
+		if lint.OK() != (len(tc.missing) == 0 && tc.conflicts == 0) {
+			t.Errorf("%v in %q: unexpected OK() %v", tc.variables, tc.code, lint.OK())
+		}
+	}
+}
+
+func TestIdiomLintVariables(t *testing.T) {
+	idiom := Idiom{
+		Variables: []string{"x"},
+		Implementations: []Impl{
+			{Id: 1, LanguageName: "Go", CodeBlock: "x++"},
+			{Id: 2, LanguageName: "Go", CodeBlock: "y++"},
+		},
+	}
+	problems := idiom.LintVariables()
+	if len(problems) != 1 || problems[2].OK() {
+		t.Errorf("Expected only impl 2 to be reported, got %v", problems)
+	}
+}
+
+func TestHighlightHTMLWithVariables(t *testing.T) {
+	h, ok := HighlightHTMLWithVariables("Go", `x := "x" + y`, []string{"x"})
+	if !ok {
+		t.Fatalf("Go should be highlighted")
+	}
+	if strings.Count(h, `<span class="variable">x</span>`) != 1 {
+		t.Errorf("Expected exactly one variable span (not in the string), got %q", h)
+	}
+	if !strings.Contains(h, `<span class="str">&#34;x&#34;</span>`) {
+		t.Errorf("Expected the string to stay a string, got %q", h)
+	}
+	if _, ok := HighlightHTMLWithVariables("Fortran", "x", []string{"x"}); ok {
+		t.Errorf("Fortran has no lexer")
+	}
+}
@@ -0,0 +1,65 @@
+package pig
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//
+// This file is about language versions, e.g. "3.10" for Python, "1.18" for Go.
+//
+
+var (
+	regexpVersionNumbers = regexp.MustCompile(`\d+`)
+	regexpVersionScheme  = regexp.MustCompile(`^\D*`)
+	regexpNonLetters     = regexp.MustCompile(`[^a-z]+`)
+)
+
+// versionScheme is the lowercase letters before the first number,
+// e.g. "rust" for "Rust 2021", "es" for "ES2015", "" for "1.70".
+func versionScheme(version string) string {
+	prefix := strings.ToLower(regexpVersionScheme.FindString(version))
+	return regexpNonLetters.ReplaceAllString(prefix, "")
+}
+
+// CompareVersions returns -1, 0 or +1 if version a is lower than, equal to,
+// or greater than version b.
+// Only the numbers matter: "Java 21" > "java 17", "3.10" > "3.9", "1.18" == "1.18.0".
+// Versions of different schemes (e.g. "Rust 2021" and "1.70"), and versions
+// without any number, are not comparable, and regarded as equal.
+func CompareVersions(a, b string) int {
+	if versionScheme(a) != versionScheme(b) {
+		return 0
+	}
+	na := regexpVersionNumbers.FindAllString(a, -1)
+	nb := regexpVersionNumbers.FindAllString(b, -1)
+	if len(na) == 0 || len(nb) == 0 {
+		return 0
+	}
+	for i := 0; i < len(na) || i < len(nb); i++ {
+		x, y := 0, 0
+		if i < len(na) {
+			x, _ = strconv.Atoi(na[i])
+		}
+		if i < len(nb) {
+			y, _ = strconv.Atoi(nb[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// RequiresNewerThan is true if impl has a MinVersion strictly greater than
+// targetVersion. An empty targetVersion means "latest".
+func (impl *Impl) RequiresNewerThan(targetVersion string) bool {
+	if impl.MinVersion == "" || targetVersion == "" {
+		return false
+	}
+	return CompareVersions(impl.MinVersion, targetVersion) > 0
+}
@@ -0,0 +1,139 @@
+package pig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldDiff is the diff of a single field, between two versions.
+// Code fields have Lines, prose fields have Words.
+type FieldDiff struct {
+	// Field is a path such as "title" or "impl/42/code".
+	Field string
+	Label string      `json:"-"`
+	Lines []DiffLine  `json:",omitempty"`
+	Words []DiffChunk `json:",omitempty"`
+
+	left, right string
+}
+
+// Hunks are the line changes of the field, for the unified view.
+func (f FieldDiff) Hunks() []DiffHunk {
+	return Hunks(f.lineDiff(), DiffContext)
+}
+
+// lineDiff is Lines, also for the prose fields.
+func (f FieldDiff) lineDiff() []DiffLine {
+	if f.Lines != nil {
+		return f.Lines
+	}
+	return DiffLines(f.left, f.right)
+}
+
+// appendLinesDiff appends the line diff of the field to fields, if it has changed.
+func appendLinesDiff(fields []FieldDiff, field, label, left, right string) []FieldDiff {
+	if left == right {
+		return fields
+	}
+	return append(fields, FieldDiff{Field: field, Label: label, Lines: DiffLines(left, right), left: left, right: right})
+}
+
+// appendWordsDiff appends the word diff of the field to fields, if it has changed.
+func appendWordsDiff(fields []FieldDiff, field, label, left, right string) []FieldDiff {
+	if left == right {
+		return fields
+	}
+	return append(fields, FieldDiff{Field: field, Label: label, Words: DiffWords(left, right), left: left, right: right})
+}
+
+// ImplDiff is the diff of an impl, between two versions of its idiom.
+type ImplDiff struct {
+	ImplID       int
+	LanguageName string
+	// Created or Deleted between the two versions
+	Created bool `json:",omitempty"`
+	Deleted bool `json:",omitempty"`
+	Fields  []FieldDiff
+}
+
+// VersionDiff is the diff between two versions of an idiom.
+type VersionDiff struct {
+	IdiomID     int
+	FromVersion int
+	ToVersion   int
+	Fields      []FieldDiff
+	Impls       []ImplDiff
+}
+
+// DiffVersions compares the statements and the impls of left and right,
+// which are two versions of the same idiom.
+// Only the changed fields are included.
+func DiffVersions(left, right *Idiom) *VersionDiff {
+	d := &VersionDiff{
+		IdiomID:     right.Id,
+		FromVersion: left.Version,
+		ToVersion:   right.Version,
+	}
+	d.Fields = appendWordsDiff(d.Fields, "title", "Title", left.Title, right.Title)
+	d.Fields = appendWordsDiff(d.Fields, "lead", "Lead paragraph", left.LeadParagraph, right.LeadParagraph)
+	d.Fields = appendWordsDiff(d.Fields, "keywords", "Extra keywords", left.ExtraKeywords, right.ExtraKeywords)
+
+	leftImpls := map[int]Impl{}
+	rightImpls := map[int]Impl{}
+	var implIDs []int
+	for _, impl := range left.Implementations {
+		leftImpls[impl.Id] = impl
+		implIDs = append(implIDs, impl.Id)
+	}
+	for _, impl := range right.Implementations {
+		if _, ok := leftImpls[impl.Id]; !ok {
+			implIDs = append(implIDs, impl.Id)
+		}
+		rightImpls[impl.Id] = impl
+	}
+	// Recently created first, as in the side-by-side view
+	sort.Sort(sort.Reverse(sort.IntSlice(implIDs)))
+
+	for _, implID := range implIDs {
+		l, inLeft := leftImpls[implID]
+		r, inRight := rightImpls[implID]
+		implDiff := ImplDiff{
+			ImplID:       implID,
+			LanguageName: r.LanguageName,
+			Created:      !inLeft,
+			Deleted:      !inRight,
+		}
+		if !inRight {
+			implDiff.LanguageName = l.LanguageName
+		}
+		prefix := fmt.Sprintf("impl/%d/", implID)
+		implDiff.Fields = appendLinesDiff(implDiff.Fields, prefix+"imports", "Imports", l.ImportsBlock, r.ImportsBlock)
+		implDiff.Fields = appendLinesDiff(implDiff.Fields, prefix+"code", "Code", l.CodeBlock, r.CodeBlock)
+		implDiff.Fields = appendWordsDiff(implDiff.Fields, prefix+"comment", "Comments bubble", l.AuthorComment, r.AuthorComment)
+		if len(implDiff.Fields) > 0 || implDiff.Created || implDiff.Deleted {
+			d.Impls = append(d.Impls, implDiff)
+		}
+	}
+	return d
+}
+
+// Unified formats all the changes in the unified diff format,
+// one "file" per field.
+func (d *VersionDiff) Unified() string {
+	var b strings.Builder
+	write := func(f FieldDiff) {
+		leftName := fmt.Sprintf("idiom/%d/v%d/%s", d.IdiomID, d.FromVersion, f.Field)
+		rightName := fmt.Sprintf("idiom/%d/v%d/%s", d.IdiomID, d.ToVersion, f.Field)
+		b.WriteString(UnifiedDiff(leftName, rightName, f.lineDiff(), DiffContext))
+	}
+	for _, f := range d.Fields {
+		write(f)
+	}
+	for _, impl := range d.Impls {
+		for _, f := range impl.Fields {
+			write(f)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package pig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffVersions(t *testing.T) {
+	left := &Idiom{Id: 5, Version: 2, Title: "Sort a list", LeadParagraph: "Sort x",
+		Implementations: []Impl{
+			{Id: 10, LanguageName: "Go", ImportsBlock: `import "sort"`, CodeBlock: "sort.Ints(x)"},
+			{Id: 11, LanguageName: "Rust", CodeBlock: "x.sort();"},
+		}}
+	right := &Idiom{Id: 5, Version: 3, Title: "Sort a slice", LeadParagraph: "Sort x",
+		Implementations: []Impl{
+			{Id: 10, LanguageName: "Go", ImportsBlock: `import "sort"`, CodeBlock: "sort.Ints(x)\n// in place"},
+			{Id: 11, LanguageName: "Rust", CodeBlock: "x.sort();"},
+			{Id: 12, LanguageName: "Python", CodeBlock: "x.sort()"},
+		}}
+	d := DiffVersions(left, right)
+	if len(d.Fields) != 1 || d.Fields[0].Field != "title" || len(d.Fields[0].Words) != 3 {
+		t.Errorf("Unexpected idiom fields diff %v", d.Fields)
+	}
+	if len(d.Impls) != 2 {
+		t.Fatalf("Expected 2 changed impls, got %v", d.Impls)
+	}
+	if created := d.Impls[0]; created.ImplID != 12 || !created.Created || len(created.Fields) != 1 {
+		t.Errorf("Unexpected impl diff %v", created)
+	}
+	if edited := d.Impls[1]; edited.ImplID != 10 || edited.Created || len(edited.Fields) != 1 || edited.Fields[0].Field != "impl/10/code" {
+		t.Errorf("Unexpected impl diff %v", edited)
+	}
+
+	unified := d.Unified()
+	for _, expected := range []string{
+		"--- idiom/5/v2/title\n+++ idiom/5/v3/title\n@@ -1 +1 @@\n-Sort a list\n+Sort a slice\n",
+		"--- idiom/5/v2/impl/10/code\n+++ idiom/5/v3/impl/10/code\n@@ -1 +1,2 @@\n sort.Ints(x)\n+// in place\n",
+		"+++ idiom/5/v3/impl/12/code\n@@ -0,0 +1 @@\n+x.sort()\n",
+	} {
+		if !strings.Contains(unified, expected) {
+			t.Errorf("Expected %q in unified diff:\n%s", expected, unified)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package pig
+
+import (
+	"testing"
+)
+
+var compareVersionsTests = []struct {
+	a, b string
+	out  int
+}{
+	// Not comparable
+	{"", "", 0},
+	{"", "3.10", 0},
+	{"latest", "3.10", 0},
+	{"Rust 2021", "1.70", 0},
+	{"1.70", "Rust 2021", 0},
+	{"ES2015", "1.8", 0},
+	{"Java 21", "21", 0},
+	// Equal
+	{"3.10", "3.10", 0},
+	{"1.18", "1.18.0", 0},
+	{"Rust 2021", "rust 2021", 0},
+	// Lower
+	{"3.9", "3.10", -1},
+	{"1.17", "1.18", -1},
+	{"ES2015", "ES2020", -1},
+	{"Rust 2018", "Rust 2021", -1},
+	// Greater
+	{"3.10", "3.9", 1},
+	{"C++20", "C++17", 1},
+	{"1.18.1", "1.18", 1},
+	{"Java 21", "java 17", 1},
+}
+
+func TestCompareVersions(t *testing.T) {
+	for i, tt := range compareVersionsTests {
+		if out := CompareVersions(tt.a, tt.b); out != tt.out {
+			t.Errorf("%d. CompareVersions(%q, %q) => %d, want %d", i, tt.a, tt.b, out, tt.out)
+		}
+	}
+}
+
+func TestRequiresNewerThan(t *testing.T) {
+	impl := &Impl{MinVersion: "1.18"}
+	if !impl.RequiresNewerThan("1.17") {
+		t.Errorf("impl requiring 1.18 should be too new for 1.17")
+	}
+	if impl.RequiresNewerThan("1.18") || impl.RequiresNewerThan("1.21") || impl.RequiresNewerThan("") {
+		t.Errorf("impl requiring 1.18 should be fine for 1.18, 1.21, and latest")
+	}
+	impl = &Impl{}
+	if impl.RequiresNewerThan("1.0") {
+		t.Errorf("impl without MinVersion should never be too new")
+	}
+}
@@ -43,7 +43,11 @@ func ajaxRefreshToggles(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
-	return refreshToggles(ctx)
+	err = refreshToggles(ctx)
+	if err != nil {
+		return err
+	}
+	return refreshLanguages(ctx)
 }
 
 func ajaxSetToggle(w http.ResponseWriter, r *http.Request) error {
@@ -91,7 +95,17 @@ func ajaxCreateRelation(w http.ResponseWriter, r *http.Request) error {
 		return PiErrorf(http.StatusNotFound, "%v", err)
 	}
 
-	idiomA.AddRelation(idiomB)
+	if typeStr := r.FormValue("type"); typeStr == "" {
+		idiomA.AddRelation(idiomB)
+	} else {
+		relationType, ok := ParseRelationType(typeStr)
+		if !ok {
+			return PiErrorf(http.StatusBadRequest, "Unknown relation type %q", typeStr)
+		}
+		if err := idiomA.AddTypedRelation(idiomB, relationType); err != nil {
+			return PiErrorf(http.StatusBadRequest, "%v", err)
+		}
+	}
 	if err := dao.saveExistingIdiom(ctx, keyA, idiomA); err != nil {
 		return PiErrorf(http.StatusNotFound, "%v", err)
 	}
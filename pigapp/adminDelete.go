@@ -19,7 +19,14 @@ func idiomDelete(w http.ResponseWriter, r *http.Request) error {
 		why = fmt.Sprintf("Admin deletes idiom %d", idiomID)
 	}
 
-	err := dao.deleteIdiom(ctx, idiomID, why)
+	_, idiom, err := dao.getIdiom(ctx, idiomID)
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+	err = dao.deleteIdiom(ctx, idiomID, why)
+	if err == nil {
+		propagateDeletion(ctx, idiom)
+	}
 
 	htmlCacheEvict(ctx, "/about-block-all-idioms")
 
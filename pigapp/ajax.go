@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
-	"strings"
 	"time"
 
 	. "github.com/Deleplace/programming-idioms/pig"
@@ -113,25 +112,33 @@ func ajaxImplVote(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// demoSiteSuggest is a placeholder for the demo URL of an impl in lang:
+// a gist, or the playground of the language.
 func demoSiteSuggest(lang string) string {
-	suggestion := ""
-	switch strings.ToLower(lang) {
-	case "js":
-		suggestion = "https://jsfiddle.net/nick/..."
-	case "go":
-		suggestion = "https://play.golang.org/p/..."
-	}
 	if rand.Intn(10) < 4 {
 		return "https://gist.github.com/..."
 	}
-	return suggestion
+	if lg := FindLanguage(lang); lg != nil {
+		return lg.PlaygroundURL("...")
+	}
+	return ""
+}
+
+// docSiteSuggest is a placeholder for the documentation URL of an impl in lang.
+func docSiteSuggest(lang string) string {
+	if lg := FindLanguage(lang); lg != nil {
+		return lg.DocURL("...")
+	}
+	return ""
 }
 
 func ajaxDemoSiteSuggest(w http.ResponseWriter, r *http.Request) error {
 	lang := r.FormValue("lang")
-	suggestion := demoSiteSuggest(lang)
 	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprint(w, Response{"suggestion": suggestion})
+	fmt.Fprint(w, Response{
+		"suggestion":    demoSiteSuggest(lang),
+		"docSuggestion": docSiteSuggest(lang),
+	})
 	return nil
 }
 
@@ -147,6 +154,6 @@ func typeaheadLanguages(w http.ResponseWriter, r *http.Request) error {
 
 func supportedLanguages(w http.ResponseWriter, r *http.Request) error {
 	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprint(w, Response{"languages": AllNiceLangs})
+	fmt.Fprint(w, Response{"languages": AllNiceLanguages()})
 	return nil
 }
@@ -45,6 +45,11 @@ func retrieveAllIdioms(r *http.Request, orderByFav bool) ([]*Idiom, error) {
 		return nil, err
 	}
 
+	userProfile := readUserProfile(r)
+	for _, idiom := range idioms {
+		applyCheckedOnly(idiom, userProfile, 0)
+	}
+
 	if orderByFav {
 		favlangs := lookForFavoriteLanguages(r)
 		includeNonFav := seeNonFavorite(r)
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	. "github.com/Deleplace/programming-idioms/pig"
@@ -44,9 +45,21 @@ func jsonIdiom(w http.ResponseWriter, r *http.Request) error {
 
 	_, idiom, err := dao.getIdiom(ctx, idiomID)
 	if err != nil {
+		if target, err := mergedIdiom(ctx, idiomID); err == nil {
+			newURL := fmt.Sprintf("%s/api/idiom/%d", hostPrefix(), target.Id)
+			if r.URL.RawQuery != "" {
+				newURL += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, newURL, http.StatusMovedPermanently)
+			return nil
+		}
 		// TODO distinguish "not found" from "server error"
 		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
 	}
+	userProfile := apiUserProfile(r)
+	applyCheckedOnly(idiom, userProfile, 0)
+	applyTargetVersions(idiom, userProfile, 0)
+	applyServerSideHighlighting(idiom)
 	// TODO cache the JSON form
 	return printJSON(w, idiom, true)
 }
@@ -60,6 +73,12 @@ func jsonAllIdioms(w http.ResponseWriter, r *http.Request) error {
 		log.Errorf(ctx, "%v", err)
 		return PiErrorf(http.StatusInternalServerError, "Could not retrieve idioms.")
 	}
+	userProfile := apiUserProfile(r)
+	for _, idiom := range idioms {
+		applyCheckedOnly(idiom, userProfile, 0)
+		applyTargetVersions(idiom, userProfile, 0)
+		applyServerSideHighlighting(idiom)
+	}
 	// TODO cache the JSON form
 	return printJSON(w, idioms, true)
 }
@@ -73,5 +92,25 @@ func jsonSearch(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
+	userProfile := apiUserProfile(r)
+	for _, idiom := range hits {
+		applyCheckedOnly(idiom, userProfile, 0)
+		applyTargetVersions(idiom, userProfile, 0)
+		applyServerSideHighlighting(idiom)
+	}
 	return printJSON(w, hits, true)
 }
+
+// apiUserProfile is the user profile from cookies, where the target versions
+// may be overridden by the parameter "versions", e.g. ?versions=Python:3.8_Go:1.17
+//
+// As the JSON doesn't contain the rendering decorations, the impls requiring
+// a newer version are always removed.
+func apiUserProfile(r *http.Request) UserProfile {
+	userProfile := readUserProfile(r)
+	if versions := r.FormValue("versions"); versions != "" {
+		userProfile.TargetVersions = parseTargetVersions(versions)
+	}
+	userProfile.HideTooNew = true
+	return userProfile
+}
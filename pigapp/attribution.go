@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"google.golang.org/appengine/log"
+)
+
+// Handle /attribution
+// The NOTICE-style attribution report, as plain text.
+// Optional parameters are idioms (comma-separated idiom IDs, default all),
+// langs (comma-separated languages, default all), and download to get
+// a NOTICE file.
+func attributionNotice(w http.ResponseWriter, r *http.Request) error {
+	entries, err := attributionEntries(r)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if r.FormValue("download") != "" {
+		w.Header().Set("Content-Disposition", `attachment; filename="NOTICE"`)
+	}
+	return WriteNotice(w, entries, time.Now())
+}
+
+// Handle /api/attribution
+// Same as /attribution, as JSON.
+func jsonAttribution(w http.ResponseWriter, r *http.Request) error {
+	entries, err := attributionEntries(r)
+	if err != nil {
+		return err
+	}
+	return printJSON(w, entries, true)
+}
+
+func attributionEntries(r *http.Request) ([]AttributionEntry, error) {
+	ctx := r.Context()
+	idioms, err := attributionIdioms(ctx, r.FormValue("idioms"))
+	if err != nil {
+		return nil, err
+	}
+	var langs []string
+	for _, lang := range strings.Split(r.FormValue("langs"), ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			langs = append(langs, NormLang(lang))
+		}
+	}
+	idiomURL := func(idiom *Idiom) string {
+		return NiceIdiomURL(idiom)
+	}
+	implURL := func(idiom *Idiom, impl *Impl) string {
+		return NiceImplURL(idiom, impl.Id, impl.LanguageName)
+	}
+	history, err := attributionHistory(ctx, idioms)
+	if err != nil {
+		return nil, err
+	}
+	return AttributionReport(idioms, history, langs, idiomURL, implURL), nil
+}
+
+// attributionHistoryParallelism is the number of concurrent history queries.
+const attributionHistoryParallelism = 10
+
+// attributionHistory loads the past versions of the idioms, to credit
+// all their contributors.
+func attributionHistory(ctx context.Context, idioms []*Idiom) (map[int][]*IdiomHistory, error) {
+	history := make(map[int][]*IdiomHistory, len(idioms))
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	sem := make(chan bool, attributionHistoryParallelism)
+	for _, idiom := range idioms {
+		wg.Add(1)
+		sem <- true
+		go func(idiomID int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, versions, err := dao.getDenseHistoryList(ctx, idiomID)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			history[idiomID] = versions
+		}(idiom.Id)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		log.Errorf(ctx, "Loading history for attribution: %v", firstErr)
+		return nil, PiErrorf(http.StatusInternalServerError, "Could not retrieve the idioms history.")
+	}
+	return history, nil
+}
+
+// attributionIdioms are the idioms of the comma-separated idiomIDs,
+// or all the idioms if idiomIDs is empty.
+func attributionIdioms(ctx context.Context, idiomIDs string) ([]*Idiom, error) {
+	if strings.TrimSpace(idiomIDs) == "" {
+		_, idioms, err := dao.getAllIdioms(ctx, 0, "Id")
+		return idioms, err
+	}
+	var idioms []*Idiom
+	for _, idStr := range strings.Split(idiomIDs, ",") {
+		idStr = strings.TrimSpace(idStr)
+		id := String2Int(idStr)
+		if id <= 0 {
+			return nil, PiErrorf(http.StatusBadRequest, "%q is not a valid idiom id.", idStr)
+		}
+		_, idiom, err := dao.getIdiom(ctx, id)
+		if err != nil {
+			return nil, PiErrorf(http.StatusNotFound, "Could not find idiom %q", idStr)
+		}
+		idioms = append(idioms, idiom)
+	}
+	return idioms, nil
+}
+
+// readLicenseFields reads the optional license and attribution fields
+// of the idiom or impl form, prefix being "idiom_" or "impl_".
+func readLicenseFields(r *http.Request, prefix string) (license, source string, date time.Time, err error) {
+	license = strings.TrimSpace(Truncate(r.FormValue(prefix+"license"), 100))
+	source = strings.TrimSpace(Truncate(r.FormValue(prefix+"attribution_source"), 100))
+	if err = ValidateLicense(license); err != nil {
+		return "", "", time.Time{}, PiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	if dateStr := strings.TrimSpace(r.FormValue(prefix + "attribution_date")); dateStr != "" {
+		date, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return "", "", time.Time{}, PiErrorf(http.StatusBadRequest, "Can't accept date [%s], expected YYYY-MM-DD", dateStr)
+		}
+	}
+	return license, source, date, nil
+}
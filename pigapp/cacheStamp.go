@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+
+	"context"
+
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/memcache"
+)
+
+//
+// A cache stamp is a memcache counter shared by all instances.
+// An instance keeps some data in memory along with the stamp it was
+// built at, and rebuilds it when the stamp has changed.
+//
+
+// cacheStamp reads the current value of the stamp.
+// A missing stamp (e.g. after a memcache flush) is recreated with
+// a new value, so that the in-memory data is rebuilt anyway.
+func cacheStamp(ctx context.Context, key string) (uint64, error) {
+	return memcache.Increment(ctx, key, 0, uint64(time.Now().UnixNano()))
+}
+
+// bumpCacheStamp tells all instances that their in-memory data is stale.
+func bumpCacheStamp(ctx context.Context, key string) {
+	_, err := memcache.Increment(ctx, key, 1, uint64(time.Now().UnixNano()))
+	logIf(err, log.Errorf, ctx, "bumping cache stamp "+key)
+}
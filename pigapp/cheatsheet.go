@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	. "github.com/Deleplace/programming-idioms/pig"
 	"github.com/gorilla/mux"
@@ -20,8 +23,23 @@ type CheatSheetFacade struct {
 	UserProfile     UserProfile
 	Lang            string
 	CheatsheetLines []cheatSheetLineDoc
+	// TooNew contains the IDs of the impls requiring a newer version than the user target version.
+	TooNew map[gaesearch.Atom]bool
+	// Fallbacks are impls in the closest languages, for the idioms not implemented in Lang.
+	Fallbacks []cheatSheetLineDoc
+	// Sections are the lines grouped by tag, when requested with ?by=tag
+	Sections []CheatSheetSection
 }
 
+// CheatSheetSection is a titled group of cheatsheet lines.
+type CheatSheetSection struct {
+	Title string
+	Lines []cheatSheetLineDoc
+}
+
+// maxFallbackLanguages limits the number of extra queries for the Fallbacks.
+const maxFallbackLanguages = 2
+
 func cheatsheet(w http.ResponseWriter, r *http.Request) error {
 	vars := mux.Vars(r)
 	lang := vars["lang"]
@@ -35,15 +53,26 @@ func cheatsheet(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return PiErrorf(http.StatusInternalServerError, "%v", err)
 	}
+	var fallbacks []cheatSheetLineDoc
+	if toggles["languageFallbacks"] {
+		fallbacks, err = cheatsheetFallbacks(ctx, lang, cheatsheetLines, limit)
+		if err != nil {
+			return PiErrorf(http.StatusInternalServerError, "%v", err)
+		}
+	}
+	userProfile := readUserProfile(r)
+	cheatsheetLines = applyCheatsheetCheckedOnly(cheatsheetLines, userProfile)
+	fallbacks = applyCheatsheetCheckedOnly(fallbacks, userProfile)
+	cheatsheetLines, tooNew := applyCheatsheetTargetVersions(cheatsheetLines, userProfile)
 
-	// Don't repeat idiom ID, title, lead on consecutive rows.
-	// Note: this *may* not play well with the JS text filter.
-	for i := 1; i < len(cheatsheetLines); i++ {
-		if cheatsheetLines[i].IdiomID == cheatsheetLines[i-1].IdiomID {
-			cheatsheetLines[i].IdiomID = ""
-			cheatsheetLines[i].IdiomTitle = ""
-			cheatsheetLines[i].IdiomLeadParagraph = "Alternative implementation"
+	var sections []CheatSheetSection
+	if r.FormValue("by") == "tag" {
+		sections = cheatsheetSectionsByTag(cheatsheetLines)
+		for _, section := range sections {
+			collapseRepeatedIdioms(section.Lines)
 		}
+	} else {
+		collapseRepeatedIdioms(cheatsheetLines)
 	}
 
 	data := CheatSheetFacade{
@@ -51,9 +80,12 @@ func cheatsheet(w http.ResponseWriter, r *http.Request) error {
 			PageTitle: PrintNiceLang(lang) + " cheat sheet",
 			Toggles:   toggles,
 		},
-		UserProfile:     readUserProfile(r),
+		UserProfile:     userProfile,
 		Lang:            lang,
 		CheatsheetLines: cheatsheetLines,
+		TooNew:          tooNew,
+		Fallbacks:       fallbacks,
+		Sections:        sections,
 	}
 
 	if err := templates.ExecuteTemplate(w, "page-cheatsheet", data); err != nil {
@@ -62,6 +94,95 @@ func cheatsheet(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// collapseRepeatedIdioms doesn't repeat idiom ID, title, lead on consecutive rows.
+// Note: this *may* not play well with the JS text filter.
+func collapseRepeatedIdioms(lines []cheatSheetLineDoc) {
+	for i := 1; i < len(lines); i++ {
+		if lines[i].IdiomID == lines[i-1].IdiomID {
+			lines[i].IdiomID = ""
+			lines[i].IdiomTitle = ""
+			lines[i].IdiomLeadParagraph = "Alternative implementation"
+		}
+	}
+}
+
+// cheatsheetSectionsByTag groups the lines by the top-level category of the
+// first tag of their idiom. Untagged idioms go to the last section "Other".
+func cheatsheetSectionsByTag(lines []cheatSheetLineDoc) []CheatSheetSection {
+	const other = "Other"
+	var sections []CheatSheetSection
+	index := map[string]int{}
+	for _, line := range lines {
+		title := TagRoot(strings.Split(string(line.IdiomTags), ",")[0])
+		if title == "" {
+			title = other
+		}
+		i, ok := index[title]
+		if !ok {
+			i = len(sections)
+			index[title] = i
+			sections = append(sections, CheatSheetSection{Title: title})
+		}
+		sections[i].Lines = append(sections[i].Lines, line)
+	}
+	sort.SliceStable(sections, func(i, j int) bool {
+		if (sections[i].Title == other) != (sections[j].Title == other) {
+			return sections[j].Title == other
+		}
+		return sections[i].Title < sections[j].Title
+	})
+	return sections
+}
+
+// applyCheatsheetTargetVersions flags (or removes, if the user wants so) the lines
+// requiring a newer language version than the user target version.
+func applyCheatsheetTargetVersions(lines []cheatSheetLineDoc, userProfile UserProfile) ([]cheatSheetLineDoc, map[gaesearch.Atom]bool) {
+	tooNew := map[gaesearch.Atom]bool{}
+	if len(userProfile.TargetVersions) == 0 {
+		return lines, tooNew
+	}
+	kept := lines[:0]
+	for _, line := range lines {
+		if implTooNew(userProfile, string(line.Lang), string(line.ImplMinVersion)) {
+			if userProfile.HideTooNew {
+				continue
+			}
+			tooNew[line.ImplID] = true
+		}
+		kept = append(kept, line)
+	}
+	return kept, tooNew
+}
+
+// cheatsheetFallbacks returns, for each idiom not implemented in lang,
+// one impl in the closest language available.
+func cheatsheetFallbacks(ctx context.Context, lang string, lines []cheatSheetLineDoc, limit int) ([]cheatSheetLineDoc, error) {
+	implemented := map[gaesearch.Atom]bool{}
+	for _, line := range lines {
+		implemented[line.IdiomID] = true
+	}
+	closestLangs := ClosestLanguages(lang)
+	if len(closestLangs) > maxFallbackLanguages {
+		closestLangs = closestLangs[:maxFallbackLanguages]
+	}
+	var fallbacks cheatSheetLineDocs
+	for _, closest := range closestLangs {
+		closestLines, err := dao.getCheatSheet(ctx, closest, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range closestLines {
+			if implemented[line.IdiomID] {
+				continue
+			}
+			implemented[line.IdiomID] = true
+			fallbacks = append(fallbacks, line)
+		}
+	}
+	sort.Sort(fallbacks)
+	return fallbacks, nil
+}
+
 // useful for calling markup2CSS on cheatSheetLineDoc fields
 func atom2string(atom gaesearch.Atom) string {
 	return string(atom)
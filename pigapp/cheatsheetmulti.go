@@ -7,6 +7,7 @@ import (
 
 	. "github.com/Deleplace/programming-idioms/pig"
 	"github.com/gorilla/mux"
+	gaesearch "google.golang.org/appengine/search"
 )
 
 // CheatSheetMultipleFacade is the Facade for the Cheat Sheets with multiple languages.
@@ -15,6 +16,8 @@ type CheatSheetMultipleFacade struct {
 	UserProfile UserProfile
 	Langs       []string
 	Lines       []cheatSheetLineMulti
+	// TooNew contains the IDs of the impls requiring a newer version than the user target version.
+	TooNew map[gaesearch.Atom]bool
 }
 
 type cheatSheetLineMulti struct {
@@ -52,7 +55,7 @@ func cheatsheetDouble(w http.ResponseWriter, r *http.Request) error {
 	for i := range langs {
 		lang := NormLang(langs[i])
 		if !StringSliceContains(AllLanguages(), lang) {
-			return PiErrorf(http.StatusBadRequest, "Sorry, [%v] is currently not a supported language. Supported languages are %v.", langs[i], AllNiceLangs)
+			return PiErrorf(http.StatusBadRequest, "Sorry, [%v] is currently not a supported language. Supported languages are %v.", langs[i], AllNiceLanguages())
 		}
 		langs[i] = lang
 	}
@@ -61,6 +64,8 @@ func cheatsheetDouble(w http.ResponseWriter, r *http.Request) error {
 	limit := 1000
 
 	var lines []cheatSheetLineMulti
+	userProfile := readUserProfile(r)
+	tooNew := map[gaesearch.Atom]bool{}
 
 	byIdiomID := map[int][]cheatSheetLineDocs{}
 	idiomTitles := map[int]string{}
@@ -70,6 +75,11 @@ func cheatsheetDouble(w http.ResponseWriter, r *http.Request) error {
 		if err != nil {
 			return PiErrorf(http.StatusInternalServerError, "%v", err)
 		}
+		cheatsheetLines = applyCheatsheetCheckedOnly(cheatsheetLines, userProfile)
+		cheatsheetLines, langTooNew := applyCheatsheetTargetVersions(cheatsheetLines, userProfile)
+		for implID := range langTooNew {
+			tooNew[implID] = true
+		}
 		for _, line := range cheatsheetLines {
 			idiomID, err := strconv.Atoi(string(line.IdiomID))
 			if err != nil {
@@ -116,9 +126,10 @@ func cheatsheetDouble(w http.ResponseWriter, r *http.Request) error {
 			Toggles:   toggles,
 			ExtraCss:  []string{hostPrefix() + themeDirectory() + "/css/pages/cheatsheetmulti.css"},
 		},
-		UserProfile: readUserProfile(r),
+		UserProfile: userProfile,
 		Langs:       langs,
 		Lines:       lines,
+		TooNew:      tooNew,
 	}
 
 	if err := templates.ExecuteTemplate(w, "page-cheatsheet-multi", data); err != nil {
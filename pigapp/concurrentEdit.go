@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"google.golang.org/appengine/log"
+)
+
+// mergeField is a field of the idiom or impl edit forms,
+// merged after a concurrent modification.
+// Checkboxes are "on" or empty, lists are joined as in the forms.
+type mergeField struct {
+	name  string
+	label string
+}
+
+var implMergeFields = []mergeField{
+	{"impl_imports", "Imports"},
+	{"impl_code", "Code"},
+	{"impl_comment", "Comments"},
+	{"impl_attribution_url", "Original source"},
+	{"impl_demo_url", "Demo URL"},
+	{"impl_doc_url", "Documentation URL"},
+	{"impl_min_version", "Minimum version"},
+	{"impl_dialect", "Dialect"},
+	{"impl_expected_output", "Expected output"},
+	{"impl_verified_examples", "Verified examples"},
+}
+
+// implAdminMergeFields are in the impl form of the admins only.
+var implAdminMergeFields = []mergeField{
+	{"impl_protected", "Protected"},
+	{"impl_picture_url", "Picture URL"},
+	{"impl_license", "License"},
+	{"impl_attribution_source", "Attribution source"},
+	{"impl_attribution_date", "Attribution date"},
+}
+
+func implMergeValues(impl *Impl, isAdmin bool) map[string]string {
+	values := map[string]string{
+		"impl_imports":           impl.ImportsBlock,
+		"impl_code":              impl.CodeBlock,
+		"impl_comment":           impl.AuthorComment,
+		"impl_attribution_url":   impl.OriginalAttributionURL,
+		"impl_demo_url":          impl.DemoURL,
+		"impl_doc_url":           impl.DocumentationURL,
+		"impl_min_version":       impl.MinVersion,
+		"impl_dialect":           impl.Dialect,
+		"impl_expected_output":   impl.ExpectedOutput,
+		"impl_verified_examples": checkboxValue(impl.VerifiedExamples),
+	}
+	if isAdmin {
+		values["impl_protected"] = checkboxValue(impl.Protected)
+		values["impl_picture_url"] = impl.PictureURL
+		values["impl_license"] = impl.License
+		values["impl_attribution_source"] = impl.AttributionSource
+		values["impl_attribution_date"] = dateValue(impl.AttributionDate)
+	}
+	return values
+}
+
+var idiomMergeFields = func() []mergeField {
+	fields := []mergeField{
+		{"idiom_title", "Title"},
+		{"idiom_lead", "Lead paragraph"},
+		{"idiom_keywords", "Extra keywords"},
+		{"idiom_tags", "Tags"},
+	}
+	for i := 1; i <= maxExamples; i++ {
+		fields = append(fields,
+			mergeField{fmt.Sprintf("example_input_%d", i), fmt.Sprintf("Example %d input", i)},
+			mergeField{fmt.Sprintf("example_output_%d", i), fmt.Sprintf("Example %d output", i)},
+		)
+	}
+	return fields
+}()
+
+// idiomAdminMergeFields are in the idiom form of the admins only.
+var idiomAdminMergeFields = func() []mergeField {
+	fields := []mergeField{
+		{"idiom_protected", "Protected"},
+		{"idiom_license", "License"},
+		{"idiom_attribution_source", "Attribution source"},
+		{"idiom_attribution_date", "Attribution date"},
+		{"idiom_variables", "Variables"},
+	}
+	for i := 1; i <= maxRelatedURLs; i++ {
+		fields = append(fields,
+			mergeField{fmt.Sprintf("related_url_%d", i), fmt.Sprintf("Related URL %d", i)},
+			mergeField{fmt.Sprintf("related_url_label_%d", i), fmt.Sprintf("Related URL %d label", i)},
+		)
+	}
+	return fields
+}()
+
+func idiomMergeValues(idiom *Idiom, isAdmin bool) map[string]string {
+	values := map[string]string{
+		"idiom_title":    idiom.Title,
+		"idiom_lead":     idiom.LeadParagraph,
+		"idiom_keywords": idiom.ExtraKeywords,
+		"idiom_tags":     strings.Join(idiom.Tags, ", "),
+	}
+	for _, slot := range exampleSlots(idiom) {
+		values[fmt.Sprintf("example_input_%d", slot.N)] = slot.Input
+		values[fmt.Sprintf("example_output_%d", slot.N)] = slot.Output
+	}
+	if isAdmin {
+		values["idiom_protected"] = checkboxValue(idiom.Protected)
+		values["idiom_license"] = idiom.License
+		values["idiom_attribution_source"] = idiom.AttributionSource
+		values["idiom_attribution_date"] = dateValue(idiom.AttributionDate)
+		values["idiom_variables"] = strings.Join(idiom.Variables, ",")
+		for i := 0; i < maxRelatedURLs; i++ {
+			if i < len(idiom.RelatedURLs) && i < len(idiom.RelatedURLLabels) {
+				values[fmt.Sprintf("related_url_%d", i+1)] = idiom.RelatedURLs[i]
+				values[fmt.Sprintf("related_url_label_%d", i+1)] = idiom.RelatedURLLabels[i]
+			} else {
+				values[fmt.Sprintf("related_url_%d", i+1)] = ""
+				values[fmt.Sprintf("related_url_label_%d", i+1)] = ""
+			}
+		}
+	}
+	return values
+}
+
+func checkboxValue(checked bool) string {
+	if checked {
+		return "on"
+	}
+	return ""
+}
+
+func dateValue(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
+// mergeFields merges each field of mine and current.
+// base is nil when the version the contributor started from is unknown.
+func mergeFields(fields []mergeField, base, current, mine map[string]string) FieldMerges {
+	merges := make(FieldMerges, len(fields))
+	for i, f := range fields {
+		if base == nil {
+			merges[i] = MergeFieldUnknownBase(f.name, current[f.name], mine[f.name])
+		} else {
+			merges[i] = MergeField(f.name, base[f.name], current[f.name], mine[f.name])
+		}
+		merges[i].Label = f.label
+	}
+	return merges
+}
+
+// mergeImplEdit merges the submitted impl mine with impl, which has been
+// concurrently modified since version impl_version.
+// The admin fields are merged only for an admin.
+func mergeImplEdit(ctx context.Context, r *http.Request, idiom *Idiom, impl *Impl, mine *Impl, isAdmin bool) FieldMerges {
+	fields := implMergeFields
+	if isAdmin {
+		fields = append(append([]mergeField{}, fields...), implAdminMergeFields...)
+	}
+	var base map[string]string
+	if baseImpl := historicImpl(ctx, r, idiom.Id, impl.Id, String2Int(r.FormValue("impl_version"))); baseImpl != nil {
+		base = implMergeValues(baseImpl, isAdmin)
+	}
+	return mergeFields(fields, base, implMergeValues(impl, isAdmin), implMergeValues(mine, isAdmin))
+}
+
+// historicImpl finds the impl implID at version implVersion in the history
+// of the idiom, or returns nil.
+func historicImpl(ctx context.Context, r *http.Request, idiomID, implID, implVersion int) *Impl {
+	// The impl edit form tells which idiom version it was based on.
+	if idiomVersion := String2Int(r.FormValue("idiom_version")); idiomVersion != -1 {
+		if _, history, err := dao.getIdiomHistory(ctx, idiomID, idiomVersion); err == nil {
+			if _, impl, found := history.FindImplInIdiom(implID); found && impl.Version == implVersion {
+				return impl
+			}
+		}
+	}
+	// Otherwise, e.g. for a draft, look for it in the whole history.
+	_, historyList, err := dao.getDenseHistoryList(ctx, idiomID)
+	if err != nil {
+		log.Warningf(ctx, "Could not load history of idiom %d: %v", idiomID, err)
+		return nil
+	}
+	for _, history := range historyList {
+		if _, impl, found := history.FindImplInIdiom(implID); found && impl.Version == implVersion {
+			return impl
+		}
+	}
+	log.Warningf(ctx, "Could not find version %d of impl %d in history of idiom %d", implVersion, implID, idiomID)
+	return nil
+}
+
+// mergeIdiomEdit merges the submitted statement mine with idiom, which has been
+// concurrently modified since version idiom_version.
+// The admin fields are merged only for an admin.
+func mergeIdiomEdit(ctx context.Context, r *http.Request, idiom *Idiom, mine *Idiom, isAdmin bool) FieldMerges {
+	fields := idiomMergeFields
+	if isAdmin {
+		fields = append(append([]mergeField{}, fields...), idiomAdminMergeFields...)
+	}
+	var base map[string]string
+	idiomVersion := String2Int(r.FormValue("idiom_version"))
+	if _, history, err := dao.getIdiomHistory(ctx, idiom.Id, idiomVersion); err == nil {
+		base = idiomMergeValues(&history.Idiom, isAdmin)
+	} else {
+		log.Warningf(ctx, "Could not find version %d of idiom %d: %v", idiomVersion, idiom.Id, err)
+	}
+	return mergeFields(fields, base, idiomMergeValues(idiom, isAdmin), idiomMergeValues(mine, isAdmin))
+}
+
+// ConcurrentEditFacade is the Facade for the page of conflicts of a concurrent modification.
+type ConcurrentEditFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Idiom       *Idiom
+	// Impl is nil for an idiom statement edit.
+	Impl   *Impl
+	Merges FieldMerges
+	// Action is the save URL.
+	Action string
+	// Hidden are the other submitted form values, sent again as is.
+	Hidden url.Values
+}
+
+// showMergeConflicts lets the contributor resolve the conflicting fields,
+// and save again against the current version.
+func showMergeConflicts(w http.ResponseWriter, r *http.Request, idiom *Idiom, impl *Impl, merges FieldMerges) error {
+	ctx := r.Context()
+	merged := merges.Merged()
+	hidden := url.Values{}
+	for name, values := range r.PostForm {
+		if _, isMerged := merged[name]; !isMerged {
+			hidden[name] = values
+		}
+	}
+	hidden.Set("idiom_version", strconv.Itoa(idiom.Version))
+	action := hostPrefix() + "/idiom-save"
+	title := "Concurrent modification of idiom " + idiom.Title
+	if impl != nil {
+		hidden.Set("impl_version", strconv.Itoa(impl.Version))
+		action = hostPrefix() + "/impl-save"
+		title = fmt.Sprintf("Concurrent modification of %s implementation of %s", PrintNiceLang(impl.LanguageName), idiom.Title)
+	}
+	log.Infof(ctx, "Concurrent modification of idiom %d: conflicts to be resolved by [%s]", idiom.Id, r.FormValue("user_nickname"))
+
+	myToggles := copyToggles(toggles)
+	myToggles["editing"] = true
+	data := &ConcurrentEditFacade{
+		PageMeta: PageMeta{
+			PageTitle:             title,
+			Toggles:               myToggles,
+			PreventIndexingRobots: true,
+		},
+		UserProfile: readUserProfile(r),
+		Idiom:       idiom,
+		Impl:        impl,
+		Merges:      merges,
+		Action:      action,
+		Hidden:      hidden,
+	}
+	w.WriteHeader(http.StatusConflict)
+	return templates.ExecuteTemplate(w, "page-concurrent-edit", data)
+}
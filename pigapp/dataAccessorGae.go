@@ -222,6 +222,7 @@ func (a *GaeDatastoreAccessor) saveNewIdiom(ctx context.Context, idiom *Idiom) (
 	idiom.Version = 1
 	idiom.VersionDate = now
 	idiom.ImplCount = len(idiom.Implementations)
+	idiom.TagSlugs = TagSlugsWithAncestors(idiom.Tags)
 	for i := range idiom.Implementations {
 		idiom.Implementations[i].CreationDate = now
 		idiom.Implementations[i].Version = 1
@@ -247,6 +248,7 @@ func (a *GaeDatastoreAccessor) saveExistingIdiom(ctx context.Context, key *datas
 	idiom.Version = idiom.Version + 1
 	idiom.VersionDate = time.Now()
 	idiom.ImplCount = len(idiom.Implementations)
+	idiom.TagSlugs = TagSlugsWithAncestors(idiom.Tags)
 	_, err := datastore.Put(ctx, key, idiom)
 
 	// Index full-text : asynchronously
@@ -287,6 +289,78 @@ func (a *GaeDatastoreAccessor) stealthIncrementImplRating(ctx context.Context, i
 	return key, idiom, impl.Rating, err
 }
 
+// stealthSetChecked doesn't update Version and VersionDate.
+// implID 0 means the idiom statement itself.
+func (a *GaeDatastoreAccessor) stealthSetChecked(ctx context.Context, idiomID, implID int, checked bool) (*datastore.Key, *Idiom, error) {
+	key, idiom, err := dao.getIdiom(ctx, idiomID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if implID == 0 {
+		idiom.Checked = checked
+	} else {
+		_, impl, found := idiom.FindImplInIdiom(implID)
+		if !found {
+			return nil, nil, fmt.Errorf("Could not find impl %v in idiom %v", implID, idiomID)
+		}
+		impl.Checked = checked
+	}
+
+	_, err = datastore.Put(ctx, key, idiom)
+	if err == nil && implID != 0 {
+		// The cheatsheets know which impls are checked : reindex asynchronously
+		indexDelayer.Call(ctx, key)
+	}
+	return key, idiom, err
+}
+
+// stealthSetRunStatus records the result of an automated execution of an impl.
+// It doesn't update Version and VersionDate.
+func (a *GaeDatastoreAccessor) stealthSetRunStatus(ctx context.Context, idiomID, implID int, status RunStatus, date time.Time) (*datastore.Key, *Idiom, error) {
+	key, idiom, err := dao.getIdiom(ctx, idiomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, impl, found := idiom.FindImplInIdiom(implID)
+	if !found {
+		return nil, nil, fmt.Errorf("Could not find impl %v in idiom %v", implID, idiomID)
+	}
+	impl.RunStatus = status
+	impl.RunDate = date
+
+	_, err = datastore.Put(ctx, key, idiom)
+	return key, idiom, err
+}
+
+// stealthRenameRelated updates the denormalized title of a related idiom.
+// It doesn't update Version and VersionDate.
+func (a *GaeDatastoreAccessor) stealthRenameRelated(ctx context.Context, idiomID, relatedID int, title string) (*datastore.Key, *Idiom, error) {
+	key, idiom, err := dao.getIdiom(ctx, idiomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !idiom.RenameRelatedIdiom(relatedID, title) {
+		return key, idiom, nil
+	}
+	_, err = datastore.Put(ctx, key, idiom)
+	return key, idiom, err
+}
+
+// stealthRemoveRelated removes the relation to a deleted idiom.
+// It doesn't update Version and VersionDate.
+func (a *GaeDatastoreAccessor) stealthRemoveRelated(ctx context.Context, idiomID, relatedID int) (*datastore.Key, *Idiom, error) {
+	key, idiom, err := dao.getIdiom(ctx, idiomID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !idiom.RemoveRelatedIdiom(relatedID) {
+		return key, idiom, nil
+	}
+	_, err = datastore.Put(ctx, key, idiom)
+	return key, idiom, err
+}
+
 func newHistoryKey(ctx context.Context) *datastore.Key {
 	return datastore.NewIncompleteKey(ctx, "IdiomHistory", nil)
 }
@@ -314,6 +388,14 @@ func (a *GaeDatastoreAccessor) getAllIdioms(ctx context.Context, limit int, orde
 	return keys, idioms, err
 }
 
+// getIdiomsByTag returns the idioms having the tag slug, or any of its sub-tags.
+func (a *GaeDatastoreAccessor) getIdiomsByTag(ctx context.Context, slug string) ([]*datastore.Key, []*Idiom, error) {
+	q := datastore.NewQuery("Idiom").Filter("TagSlugs =", slug).Order("Id")
+	idioms := make([]*Idiom, 0, 20)
+	keys, err := q.GetAll(ctx, &idioms)
+	return keys, idioms, err
+}
+
 func (a *GaeDatastoreAccessor) deleteAllIdioms(ctx context.Context) error {
 	keys, err := datastore.NewQuery("Idiom").KeysOnly().GetAll(ctx, nil)
 	if err != nil {
@@ -634,6 +716,43 @@ func (a *GaeDatastoreAccessor) saveAppConfigProperty(ctx context.Context, prop A
 	return err
 }
 
+var languageNotFound = fmt.Errorf("Found zero Language in the datastore.")
+
+func newLanguageKey(ctx context.Context, name string) *datastore.Key {
+	return datastore.NewKey(ctx, "Language", name, 0, nil)
+}
+
+func (a *GaeDatastoreAccessor) getLanguages(ctx context.Context) ([]*Language, error) {
+	q := datastore.NewQuery("Language").Order("Name")
+	langs := make([]*Language, 0, 50)
+	_, err := q.GetAll(ctx, &langs)
+	if err != nil {
+		return nil, err
+	}
+	if len(langs) == 0 {
+		return nil, languageNotFound
+	}
+	return langs, nil
+}
+
+func (a *GaeDatastoreAccessor) saveLanguages(ctx context.Context, langs []*Language) error {
+	keys := make([]*datastore.Key, len(langs))
+	for i, lg := range langs {
+		keys[i] = newLanguageKey(ctx, lg.Name)
+	}
+	_, err := datastore.PutMulti(ctx, keys, langs)
+	return err
+}
+
+func (a *GaeDatastoreAccessor) saveLanguage(ctx context.Context, lang *Language) error {
+	_, err := datastore.Put(ctx, newLanguageKey(ctx, lang.Name), lang)
+	return err
+}
+
+func (a *GaeDatastoreAccessor) deleteLanguage(ctx context.Context, name string) error {
+	return datastore.Delete(ctx, newLanguageKey(ctx, name))
+}
+
 func (a *GaeDatastoreAccessor) saveNewMessage(ctx context.Context, message *MessageForUser) (*datastore.Key, error) {
 	return datastore.Put(ctx, datastore.NewIncompleteKey(ctx, "MessageForUser", nil), message)
 }
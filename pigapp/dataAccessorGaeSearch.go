@@ -82,6 +82,25 @@ type cheatSheetLineDoc struct {
 	ImplCodeBlock gaesearch.Atom
 	// ImplCodeBlock is the comment of this impl.
 	ImplCodeBlockComment gaesearch.Atom
+	// ImplMinVersion is the minimum language version required by this impl.
+	ImplMinVersion gaesearch.Atom
+	// ImplDialect is the language standard or edition of this impl.
+	ImplDialect gaesearch.Atom
+	// IdiomTags are the tags of the idiom this impl belongs to, separated by commas.
+	IdiomTags gaesearch.Atom
+	// ImplChecked is "true" if this impl was approved by a reviewer.
+	ImplChecked gaesearch.Atom
+}
+
+// searchableTranslationDoc is the searchable unit for 1 translation.
+// We choose "idiomID_locale" as docID.
+type searchableTranslationDoc struct {
+	// IdiomID is the ID of the translated idiom.
+	IdiomID gaesearch.Atom
+	// Locale is the human language of the translation.
+	Locale gaesearch.Atom
+	// Bulk is a simple concatenation of (normalized) words, space-separated
+	Bulk string
 }
 
 type cheatSheetLineDocs []cheatSheetLineDoc
@@ -182,6 +201,10 @@ func indexIdiomCheatsheets(ctx context.Context, idiom *Idiom) error {
 			ImplImportsBlock:     gaesearch.Atom(impl.ImportsBlock),
 			ImplCodeBlock:        gaesearch.Atom(impl.CodeBlock),
 			ImplCodeBlockComment: gaesearch.Atom(impl.AuthorComment),
+			ImplMinVersion:       gaesearch.Atom(impl.MinVersion),
+			ImplDialect:          gaesearch.Atom(impl.Dialect),
+			IdiomTags:            gaesearch.Atom(strings.Join(idiom.Tags, ", ")),
+			ImplChecked:          gaesearch.Atom(strconv.FormatBool(impl.Checked)),
 		}
 	}
 	_, err = index.PutMulti(ctx, docIDs, docs)
@@ -508,6 +531,55 @@ func executeIdiomKeyTextSearchQuery(ctx context.Context, query string, limit int
 	return idiomKeyStrings, nil
 }
 
+func indexTranslationFullText(ctx context.Context, t *Translation) error {
+	index, err := gaesearch.Open("translations")
+	if err != nil {
+		return err
+	}
+	docID := fmt.Sprintf("%d_%s", t.IdiomID, t.Locale)
+	doc := &searchableTranslationDoc{
+		IdiomID: gaesearch.Atom(strconv.Itoa(t.IdiomID)),
+		Locale:  gaesearch.Atom(t.Locale),
+		Bulk:    strings.Join(t.ExtractIndexableWords(), " "),
+	}
+	_, err = index.Put(ctx, docID, doc)
+	return err
+}
+
+// searchTranslatedIdiomIDs returns the IDs of the idioms whose translation
+// in locale contains all the words.
+func searchTranslatedIdiomIDs(ctx context.Context, locale string, words []string, limit int) ([]int, error) {
+	if len(words) == 0 || limit == 0 {
+		return nil, nil
+	}
+	index, err := gaesearch.Open("translations")
+	if err != nil {
+		return nil, err
+	}
+	query := "Locale:" + locale + " AND Bulk:(~" + strings.Join(words, " AND ~") + ")"
+	// This is an *IDsOnly* search, where docID == idiomID_locale
+	it := index.Search(ctx, query, &gaesearch.SearchOptions{
+		Limit:   limit,
+		IDsOnly: true,
+	})
+	var idiomIDs []int
+	for {
+		docID, err := it.Next(nil)
+		if err == gaesearch.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		idiomID, err := strconv.Atoi(strings.TrimSuffix(docID, "_"+locale))
+		if err != nil {
+			return nil, err
+		}
+		idiomIDs = append(idiomIDs, idiomID)
+	}
+	return idiomIDs, nil
+}
+
 func executeIdiomTextSearchQuery(ctx context.Context, query string, limit int) ([]*Idiom, error) {
 	// log.Infof(ctx, query)
 	index, err := gaesearch.Open("idioms")
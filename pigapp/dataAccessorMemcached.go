@@ -176,6 +176,7 @@ func init() {
 	gob.Register(Toggles{})
 	gob.Register(&ApplicationConfig{})
 	gob.Register([]*MessageForUser{})
+	gob.Register([]*Language{})
 }
 
 // KeyAndEntity is a specific pair wrapper.
@@ -234,6 +235,9 @@ func (a *MemcacheDatastoreAccessor) uncacheIdiom(ctx context.Context, idiom *Idi
 	// Cached HTML pages.
 	htmlUncacheIdiomAndImpls(ctx, idiom)
 
+	// Fingerprints of the search by code.
+	bumpCacheStamp(ctx, codeIndexStamp)
+
 	return err
 }
 
@@ -295,6 +299,7 @@ func (a *MemcacheDatastoreAccessor) saveNewIdiom(ctx context.Context, idiom *Idi
 		"about-block-language-coverage",
 		"getAllIdioms(399,-ImplCount)",
 	})
+	bumpCacheStamp(ctx, codeIndexStamp)
 	return key, err
 }
 
@@ -315,6 +320,7 @@ func (a *MemcacheDatastoreAccessor) saveExistingIdiom(ctx context.Context, key *
 		"about-block-language-coverage",
 		"getAllIdioms(399,-ImplCount)",
 	})
+	bumpCacheStamp(ctx, codeIndexStamp)
 	return err
 }
 
@@ -332,6 +338,46 @@ func (a *MemcacheDatastoreAccessor) stealthIncrementImplRating(ctx context.Conte
 	return
 }
 
+func (a *MemcacheDatastoreAccessor) stealthSetChecked(ctx context.Context, idiomID, implID int, checked bool) (*datastore.Key, *Idiom, error) {
+	key, idiom, err := a.GaeDatastoreAccessor.stealthSetChecked(ctx, idiomID, implID, checked)
+	if err != nil {
+		return key, idiom, err
+	}
+	err2 := a.recacheIdiom(ctx, key, idiom, true)
+	logIf(err2, log.Errorf, ctx, "updating checked flag")
+	return key, idiom, err
+}
+
+func (a *MemcacheDatastoreAccessor) stealthSetRunStatus(ctx context.Context, idiomID, implID int, status RunStatus, date time.Time) (*datastore.Key, *Idiom, error) {
+	key, idiom, err := a.GaeDatastoreAccessor.stealthSetRunStatus(ctx, idiomID, implID, status, date)
+	if err != nil {
+		return key, idiom, err
+	}
+	err2 := a.recacheIdiom(ctx, key, idiom, true)
+	logIf(err2, log.Errorf, ctx, "updating run status")
+	return key, idiom, err
+}
+
+func (a *MemcacheDatastoreAccessor) stealthRenameRelated(ctx context.Context, idiomID, relatedID int, title string) (*datastore.Key, *Idiom, error) {
+	key, idiom, err := a.GaeDatastoreAccessor.stealthRenameRelated(ctx, idiomID, relatedID, title)
+	if err != nil {
+		return key, idiom, err
+	}
+	err2 := a.recacheIdiom(ctx, key, idiom, true)
+	logIf(err2, log.Errorf, ctx, "updating related idiom title")
+	return key, idiom, err
+}
+
+func (a *MemcacheDatastoreAccessor) stealthRemoveRelated(ctx context.Context, idiomID, relatedID int) (*datastore.Key, *Idiom, error) {
+	key, idiom, err := a.GaeDatastoreAccessor.stealthRemoveRelated(ctx, idiomID, relatedID)
+	if err != nil {
+		return key, idiom, err
+	}
+	err2 := a.recacheIdiom(ctx, key, idiom, true)
+	logIf(err2, log.Errorf, ctx, "removing related idiom")
+	return key, idiom, err
+}
+
 func (a *MemcacheDatastoreAccessor) getAllIdioms(ctx context.Context, limit int, order string) ([]*datastore.Key, []*Idiom, error) {
 	cacheKey := fmt.Sprintf("getAllIdioms(%v,%v)", limit, order)
 	data, cacheerr := a.readZipCache(ctx, cacheKey)
@@ -529,6 +575,61 @@ func (a *MemcacheDatastoreAccessor) saveAppConfigProperty(ctx context.Context, p
 	// TODO force toggles refresh for all instances, after memcache flush
 }
 
+func (a *MemcacheDatastoreAccessor) getLanguages(ctx context.Context) ([]*Language, error) {
+	cacheKey := "getLanguages()"
+	data, cacheerr := a.readCache(ctx, cacheKey)
+	if cacheerr != nil {
+		log.Errorf(ctx, "%v", cacheerr)
+		// Ouch. Well, skip the cache if it's broken
+		return a.GaeDatastoreAccessor.getLanguages(ctx)
+	}
+	if data == nil {
+		// Not in the cache. Then fetch the real datastore data. And cache it.
+		langs, err := a.GaeDatastoreAccessor.getLanguages(ctx)
+		if err == nil {
+			log.Infof(ctx, "Retrieved Languages from Datastore")
+			err2 := a.cacheValue(ctx, cacheKey, langs, 24*time.Hour)
+			logIf(err2, log.Errorf, ctx, "caching languages")
+		}
+		return langs, err
+	}
+	// Found in cache :)
+	langs := data.([]*Language)
+	return langs, nil
+}
+
+// uncacheLanguages evicts the languages from memcache, and from the
+// memory of all the instances.
+func (a *MemcacheDatastoreAccessor) uncacheLanguages(ctx context.Context) error {
+	err := memcache.Delete(ctx, "getLanguages()")
+	if err != nil && err != memcache.ErrCacheMiss {
+		return err
+	}
+	bumpCacheStamp(ctx, languagesStampKey)
+	return nil
+}
+
+func (a *MemcacheDatastoreAccessor) saveLanguages(ctx context.Context, langs []*Language) error {
+	if err := a.GaeDatastoreAccessor.saveLanguages(ctx, langs); err != nil {
+		return err
+	}
+	return a.uncacheLanguages(ctx)
+}
+
+func (a *MemcacheDatastoreAccessor) saveLanguage(ctx context.Context, lang *Language) error {
+	if err := a.GaeDatastoreAccessor.saveLanguage(ctx, lang); err != nil {
+		return err
+	}
+	return a.uncacheLanguages(ctx)
+}
+
+func (a *MemcacheDatastoreAccessor) deleteLanguage(ctx context.Context, name string) error {
+	if err := a.GaeDatastoreAccessor.deleteLanguage(ctx, name); err != nil {
+		return err
+	}
+	return a.uncacheLanguages(ctx)
+}
+
 func (a *MemcacheDatastoreAccessor) deleteCache(ctx context.Context) error {
 	return memcache.Flush(ctx)
 }
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/user"
+)
+
+//
+// Discussion threads about an idiom, or about one of its impls.
+// Each Comment remembers the versions it was written against.
+// The admins may hide or delete comments.
+//
+
+const nbRecentComments = 50
+
+func discussionURL(idiomID, implID int) string {
+	if implID == 0 {
+		return fmt.Sprintf("%s/discussion/%d", hostPrefix(), idiomID)
+	}
+	return fmt.Sprintf("%s/discussion/%d/impl/%d", hostPrefix(), idiomID, implID)
+}
+
+// DiscussionFacade is the Facade for the discussion thread of an idiom or an impl.
+type DiscussionFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Idiom       *Idiom
+	// Impl is nil for the discussion about the idiom statement.
+	Impl     *Impl
+	Comments []CommentFacade
+}
+
+// CommentFacade is a Comment, with its key and display flags.
+type CommentFacade struct {
+	Comment
+	Key *datastore.Key
+	// Outdated if the impl has changed since the comment.
+	Outdated bool
+	// Idiom and Impl are set only in the recent discussions list.
+	Idiom *Idiom
+	Impl  *Impl
+}
+
+// Handle /discussion/{idiomId}
+// and /discussion/{idiomId}/impl/{implId}
+func discussion(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	idiomIDStr := vars["idiomId"]
+	_, idiom, err := dao.getIdiom(ctx, String2Int(idiomIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+	implID := 0
+	var impl *Impl
+	if implIDStr := vars["implId"]; implIDStr != "" {
+		implID = String2Int(implIDStr)
+		var found bool
+		if _, impl, found = idiom.FindImplInIdiom(implID); !found {
+			return PiErrorf(http.StatusNotFound, "Could not find impl %q in idiom %q", implIDStr, idiomIDStr)
+		}
+	}
+
+	userProfile := readUserProfile(r)
+	var comments []Comment
+	keys, err := datastore.NewQuery("Comment").
+		Filter("IdiomID =", idiom.Id).
+		Filter("ImplID =", implID).
+		Order("CreationDate").
+		GetAll(ctx, &comments)
+	if err != nil {
+		return err
+	}
+	thread := make([]CommentFacade, 0, len(comments))
+	for i, c := range comments {
+		if c.Hidden && !userProfile.IsAdmin {
+			continue
+		}
+		thread = append(thread, CommentFacade{
+			Comment:  c,
+			Key:      keys[i],
+			Outdated: c.Outdated(idiom),
+		})
+	}
+
+	title := "Discussion: " + idiom.Title
+	if impl != nil {
+		title = "Discussion: " + idiom.Title + " in " + PrintNiceLang(impl.LanguageName)
+	}
+	data := &DiscussionFacade{
+		PageMeta: PageMeta{
+			PageTitle: title,
+			Toggles:   toggles,
+		},
+		UserProfile: userProfile,
+		Idiom:       idiom,
+		Impl:        impl,
+		Comments:    thread,
+	}
+	return templates.ExecuteTemplate(w, "page-discussion", data)
+}
+
+// Handle /discussion-post
+// with idiom_id, optional impl_id, comment_text, user_nickname.
+func discussionPost(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+	ctx := r.Context()
+
+	author, authenticated := Truncate(r.FormValue("user_nickname"), 30), false
+	if u := user.Current(ctx); u != nil {
+		author, authenticated = u.String(), true
+	} else if author == "" {
+		return PiErrorf(http.StatusBadRequest, "Username is mandatory. No anonymous comment.")
+	} else {
+		setNicknameCookie(w, author)
+	}
+
+	text := strings.TrimSpace(r.FormValue("comment_text"))
+	if err := ValidateCommentText(text); err != nil {
+		return PiErrorf(http.StatusBadRequest, "%v", err)
+	}
+
+	idiomIDStr := r.FormValue("idiom_id")
+	_, idiom, err := dao.getIdiom(ctx, String2Int(idiomIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+	comment := Comment{
+		IdiomID:       idiom.Id,
+		IdiomVersion:  idiom.Version,
+		Author:        author,
+		Authenticated: authenticated,
+		Text:          text,
+		CreationDate:  time.Now(),
+	}
+	if implIDStr := r.FormValue("impl_id"); implIDStr != "" && implIDStr != "0" {
+		_, impl, found := idiom.FindImplInIdiom(String2Int(implIDStr))
+		if !found {
+			return PiErrorf(http.StatusNotFound, "Could not find impl %q in idiom %q", implIDStr, idiomIDStr)
+		}
+		comment.ImplID = impl.Id
+		comment.ImplVersion = impl.Version
+	}
+
+	key, err := datastore.Put(ctx, datastore.NewIncompleteKey(ctx, "Comment", nil), &comment)
+	if err != nil {
+		log.Errorf(ctx, "saving Comment: %v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not save comment :(")
+	}
+	log.Infof(ctx, "[%s] commented on idiom %d impl %d: %q", author, comment.IdiomID, comment.ImplID, Flatten(Shorten(text, 30)))
+	notifyComment(ctx, &comment, idiom)
+
+	http.Redirect(w, r, fmt.Sprintf("%s#comment-%d", discussionURL(comment.IdiomID, comment.ImplID), key.IntID()), http.StatusFound)
+	return nil
+}
+
+// notifyComment sends a message to the author of the commented idiom or impl.
+func notifyComment(ctx context.Context, comment *Comment, idiom *Idiom) {
+	what := fmt.Sprintf("idiom #%d \"%s\"", idiom.Id, html.EscapeString(idiom.Title))
+	if comment.ImplID != 0 {
+		_, impl, _ := idiom.FindImplInIdiom(comment.ImplID)
+		what = fmt.Sprintf("%s implementation of %s", PrintNiceLang(impl.LanguageName), what)
+	}
+	for _, username := range CommentRecipients(comment, idiom) {
+		msg := MessageForUser{
+			Username: username,
+			Message: fmt.Sprintf("[%s] commented on your %s: <a href=\"%s\">see the discussion</a>.",
+				html.EscapeString(comment.Author), what, discussionURL(comment.IdiomID, comment.ImplID)),
+			CreationDate: time.Now(),
+		}
+		_, err := dao.saveNewMessage(ctx, &msg)
+		logIf(err, log.Errorf, ctx, "saving comment notification")
+	}
+}
+
+// Handle /admin-comment-moderate-ajax
+// action is "hide", "unhide" or "delete".
+func adminCommentModerateAjax(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	keyStr := r.FormValue("key")
+	key, err := datastore.DecodeKey(keyStr)
+	if err != nil {
+		return PiErrorf(http.StatusBadRequest, "Could not decode key %q", keyStr)
+	}
+	var comment Comment
+	err = datastore.Get(ctx, key, &comment)
+	if err == datastore.ErrNoSuchEntity {
+		return PiErrorf(http.StatusNotFound, "Comment %q no longer exists", keyStr)
+	}
+	if err != nil {
+		log.Errorf(ctx, "retrieving Comment: %v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve comment :(")
+	}
+
+	action := r.FormValue("action")
+	switch action {
+	case "hide", "unhide":
+		comment.Hidden = action == "hide"
+		comment.Moderator = "admin"
+		if u := user.Current(ctx); u != nil {
+			comment.Moderator = u.String()
+		}
+		comment.ModerationDate = time.Now()
+		_, err = datastore.Put(ctx, key, &comment)
+	case "delete":
+		err = datastore.Delete(ctx, key)
+	default:
+		return PiErrorf(http.StatusBadRequest, "Unknown action %q", action)
+	}
+	if err != nil {
+		log.Errorf(ctx, "moderating Comment: %v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not %s comment :(", action)
+	}
+	log.Infof(ctx, "Comment %s of [%s] on idiom %d: %s", keyStr, comment.Author, comment.IdiomID, action)
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, Response{"message": fmt.Sprintf("Comment: %s done", action)})
+	return nil
+}
+
+// recentComments returns the most recent visible comments, with their idiom.
+func recentComments(ctx context.Context, n int) ([]CommentFacade, error) {
+	var comments []Comment
+	keys, err := datastore.NewQuery("Comment").
+		Filter("Hidden =", false).
+		Order("-CreationDate").
+		Limit(n).
+		GetAll(ctx, &comments)
+	if err != nil {
+		return nil, err
+	}
+	idioms := map[int]*Idiom{}
+	list := make([]CommentFacade, 0, len(comments))
+	for i, c := range comments {
+		idiom, ok := idioms[c.IdiomID]
+		if !ok {
+			_, idiom, err = dao.getIdiom(ctx, c.IdiomID)
+			if err != nil {
+				// Idiom deleted since
+				log.Warningf(ctx, "comment on idiom %d: %v", c.IdiomID, err)
+				idiom = nil
+			}
+			idioms[c.IdiomID] = idiom
+		}
+		if idiom == nil {
+			continue
+		}
+		_, impl, _ := idiom.FindImplInIdiom(c.ImplID)
+		list = append(list, CommentFacade{
+			Comment:  c,
+			Key:      keys[i],
+			Outdated: c.Outdated(idiom),
+			Idiom:    idiom,
+			Impl:     impl,
+		})
+	}
+	return list, nil
+}
+
+// RecentDiscussionsFacade is the Facade for the recent comments page.
+type RecentDiscussionsFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Comments    []CommentFacade
+}
+
+// Handle /recent-discussions
+func recentDiscussions(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	comments, err := recentComments(ctx, nbRecentComments)
+	if err != nil {
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve comments: %v", err)
+	}
+	data := &RecentDiscussionsFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Recent discussions",
+			Toggles:   toggles,
+		},
+		UserProfile: readUserProfile(r),
+		Comments:    comments,
+	}
+	return templates.ExecuteTemplate(w, "page-recent-discussions", data)
+}
+
+// Handle /rss-recent-discussions
+func rssRecentDiscussions(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	comments, err := recentComments(ctx, nbRecentComments)
+	if err != nil {
+		return err
+	}
+	path := "/rss-recent-discussions"
+	feedTitle := "Programming Idioms recent discussions"
+	feedDescription := "Recent comments on all idioms and implementations"
+
+	itemsAsStrings := make([]string, len(comments))
+	for i, c := range comments {
+		title := "Comment on [" + c.Idiom.Title + "]"
+		if c.Impl != nil {
+			title += " in " + PrintNiceLang(c.Impl.LanguageName)
+		}
+		itemLink := fmt.Sprintf("%s/discussion/%d", env.Host, c.IdiomID)
+		if c.ImplID != 0 {
+			itemLink += fmt.Sprintf("/impl/%d", c.ImplID)
+		}
+		itemLink += fmt.Sprintf("#comment-%d", c.Key.IntID())
+		item := &RssItem{
+			Link:        itemLink,
+			Title:       title,
+			Description: markup2HTML(c.Text) + "<br/>By: " + html.EscapeString(c.Author) + ".",
+			PubDate:     c.CreationDate.Format(rssPubDatelayout),
+			GUID: GUID{
+				Value:       itemLink,
+				IsPermaLink: true,
+			},
+		}
+		buff, err := xml.MarshalIndent(item, "  ", "    ")
+		if err != nil {
+			return err
+		}
+		itemsAsStrings[i] = string(buff)
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml")
+	data := &RssFacade{
+		FeedTitle:       feedTitle,
+		SiteLink:        env.Host,
+		FeedDescription: feedDescription,
+		Items:           itemsAsStrings,
+		FeedURL:         env.Host + path,
+	}
+	return rssTemplate.ExecuteTemplate(w, "rss", data)
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/user"
+)
+
+//
+// Drafts of idiom and impl edits.
+// The edit forms have a [Save draft] button, posting to /draft-save
+// instead of /idiom-save or /impl-save.
+// A draft belongs to the signed-in account, or else to the random token
+// of the draft-owner cookie.
+// Publishing a draft replays the edit form through idiomSave or implSave,
+// with the version the draft was based on.
+//
+
+func newDraftKey(ctx context.Context, id string) *datastore.Key {
+	return datastore.NewKey(ctx, "Draft", id, 0, nil)
+}
+
+func draftURL(id string) string {
+	return hostPrefix() + "/draft/" + id
+}
+
+// draftOwnerCookie holds the secret token of an anonymous draft owner.
+const draftOwnerCookie = "draft-owner"
+
+var rxDraftOwnerToken = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// draftOwner is the signed-in account, or else the token of the draft-owner cookie.
+// Never the nickname nor a request parameter: anyone could claim them.
+func draftOwner(r *http.Request) (owner string, authenticated bool) {
+	if u := user.Current(r.Context()); u != nil {
+		return u.String(), true
+	}
+	if cookie, err := r.Cookie(draftOwnerCookie); err == nil && rxDraftOwnerToken.MatchString(cookie.Value) {
+		return cookie.Value, false
+	}
+	return "", false
+}
+
+// newDraftOwner sets a new random token in the draft-owner cookie, and returns it.
+func newDraftOwner(w http.ResponseWriter) (string, error) {
+	token, err := NewDraftID()
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     draftOwnerCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().AddDate(1, 0, 0),
+		HttpOnly: true,
+	})
+	return token, nil
+}
+
+// canManageDraft tells if the requester may edit, publish or delete d.
+// Anyone having the URL may preview it.
+func canManageDraft(r *http.Request, d *Draft) bool {
+	if IsAdmin(r) {
+		return true
+	}
+	owner, authenticated := draftOwner(r)
+	return owner != "" && d.Owner == owner && d.Authenticated == authenticated
+}
+
+func loadDraft(ctx context.Context, id string) (*Draft, error) {
+	if id == "" {
+		return nil, PiErrorf(http.StatusBadRequest, "Missing draft id")
+	}
+	var d Draft
+	err := datastore.Get(ctx, newDraftKey(ctx, id), &d)
+	if err == datastore.ErrNoSuchEntity {
+		return nil, PiErrorf(http.StatusNotFound, "Draft not found. It may have been published or deleted.")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// lookForDraft returns the draft given in the "draft" URL parameter
+// of the edit pages, or nil.
+func lookForDraft(r *http.Request, idiomID int) (*Draft, error) {
+	id := r.FormValue("draft")
+	if id == "" {
+		return nil, nil
+	}
+	d, err := loadDraft(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageDraft(r, d) {
+		return nil, PiErrorf(http.StatusForbidden, "This draft is not yours.")
+	}
+	if d.IdiomID != idiomID {
+		return nil, PiErrorf(http.StatusBadRequest, "Draft %s is not about idiom %d", id, idiomID)
+	}
+	return d, nil
+}
+
+// Handle /draft-save
+// The form is the same as for /idiom-save (existing idiom),
+// or /impl-save (new or existing impl), plus an optional draft_id.
+func draftSave(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+	ctx := r.Context()
+	nickname := Truncate(r.FormValue("user_nickname"), 30)
+	if nickname == "" {
+		return PiErrorf(http.StatusBadRequest, "Username is mandatory. No anonymous draft.")
+	}
+	setNicknameCookie(w, nickname)
+	owner, authenticated := draftOwner(r)
+	if owner == "" {
+		var err error
+		if owner, err = newDraftOwner(w); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	var d *Draft
+	if id := r.FormValue("draft_id"); id != "" {
+		var err error
+		if d, err = loadDraft(ctx, id); err != nil {
+			return err
+		}
+		if !canManageDraft(r, d) {
+			return PiErrorf(http.StatusForbidden, "This draft is not yours.")
+		}
+	} else {
+		id, err := NewDraftID()
+		if err != nil {
+			return err
+		}
+		d = &Draft{
+			ID:            id,
+			Owner:         owner,
+			Authenticated: authenticated,
+			CreationDate:  now,
+		}
+	}
+
+	idiomIDStr := r.FormValue("idiom_id")
+	_, idiom, err := dao.getIdiom(ctx, String2Int(idiomIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+	d.IdiomID = idiom.Id
+	d.Nickname = nickname
+	d.UpdateDate = now
+	d.EditSummary = Truncate(r.FormValue("edit_summary"), 120)
+	if err := readDraftFields(r, d, idiom); err != nil {
+		return err
+	}
+
+	if _, err := datastore.Put(ctx, newDraftKey(ctx, d.ID), d); err != nil {
+		log.Errorf(ctx, "saving Draft: %v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not save draft :(")
+	}
+	log.Infof(ctx, "[%s] saved %s draft %s for idiom %d impl %d", d.Nickname, d.Kind, d.ID, d.IdiomID, d.ImplID)
+
+	http.Redirect(w, r, draftURL(d.ID), http.StatusFound)
+	return nil
+}
+
+// readDraftFields reads the edit form into d, with the same truncations
+// as idiomSave and implSave.
+func readDraftFields(r *http.Request, d *Draft, idiom *Idiom) error {
+	trim := strings.TrimSpace
+	switch {
+	case r.FormValue("impl_id") != "":
+		implIDStr := r.FormValue("impl_id")
+		_, impl, found := idiom.FindImplInIdiom(String2Int(implIDStr))
+		if !found {
+			return PiErrorf(http.StatusNotFound, "Could not find implementation %q for idiom %d", implIDStr, idiom.Id)
+		}
+		d.Kind = DraftImpl
+		d.ImplID = impl.Id
+		d.LanguageName = impl.LanguageName
+		d.BaseVersion = String2Int(r.FormValue("impl_version"))
+	case r.FormValue("impl_language") != "":
+		d.Kind = DraftNewImpl
+		d.LanguageName = NormLang(r.FormValue("impl_language"))
+		if !StringSliceContains(AllLanguages(), d.LanguageName) {
+			return PiErrorf(http.StatusBadRequest, "Sorry, [%v] is currently not a supported language. Supported languages are %v.", r.FormValue("impl_language"), AllNiceLanguages())
+		}
+	case r.FormValue("idiom_version") != "":
+		d.Kind = DraftIdiom
+		d.BaseVersion = String2Int(r.FormValue("idiom_version"))
+		d.Title = trim(Truncate(r.FormValue("idiom_title"), 250))
+		d.LeadParagraph = TruncateBytes(r.FormValue("idiom_lead"), 500)
+		d.ExtraKeywords = Truncate(r.FormValue("idiom_keywords"), 250)
+		d.Tags = ParseTags(Truncate(r.FormValue("idiom_tags"), 250))
+		d.ExampleInputs, d.ExampleOutputs = exampleFormValues(r)
+		return nil
+	default:
+		return PiErrorf(http.StatusBadRequest, "Drafts are for the edition of an existing idiom, or of an implementation")
+	}
+
+	d.ImportsBlock = trim(Truncate(r.FormValue("impl_imports"), 200))
+	d.CodeBlock = TruncateBytes(NoCR(r.FormValue("impl_code")), 500)
+	d.AuthorComment = trim(TruncateBytes(r.FormValue("impl_comment"), 500))
+	d.OriginalAttributionURL = trim(Truncate(r.FormValue("impl_attribution_url"), 250))
+	d.DemoURL = trim(Truncate(r.FormValue("impl_demo_url"), 250))
+	d.DocumentationURL = trim(Truncate(r.FormValue("impl_doc_url"), 250))
+	d.MinVersion = trim(Truncate(r.FormValue("impl_min_version"), 30))
+	d.Dialect = trim(Truncate(r.FormValue("impl_dialect"), 30))
+	d.ExpectedOutput = TruncateBytes(NoCR(r.FormValue("impl_expected_output")), 500)
+	d.VerifiedExamples = r.FormValue("impl_verified_examples") != ""
+	return nil
+}
+
+// DraftPreview decorates the idiom detail page, for a draft.
+type DraftPreview struct {
+	Draft *Draft
+	// CurrentVersion of the idiom or impl, see Draft.CurrentVersion.
+	CurrentVersion int
+	Outdated       bool
+	// CanManage if the visitor may edit, publish or delete the draft.
+	CanManage bool
+}
+
+// EditURL is the edit page, filled with the draft.
+func (p *DraftPreview) EditURL() string {
+	d := p.Draft
+	switch d.Kind {
+	case DraftIdiom:
+		return fmt.Sprintf("%s/idiom-edit/%d?draft=%s", hostPrefix(), d.IdiomID, d.ID)
+	case DraftImpl:
+		return fmt.Sprintf("%s/impl-edit/%d/%d?draft=%s", hostPrefix(), d.IdiomID, d.ImplID, d.ID)
+	}
+	return fmt.Sprintf("%s/impl-create/%d/%s?draft=%s", hostPrefix(), d.IdiomID, d.LanguageName, d.ID)
+}
+
+// Handle /draft/{draftId}
+// The draft is rendered with the idiom detail template.
+func draftPreview(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	d, err := loadDraft(ctx, mux.Vars(r)["draftId"])
+	if err != nil {
+		return err
+	}
+	_, idiom, err := dao.getIdiom(ctx, d.IdiomID)
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %d", d.IdiomID)
+	}
+	preview := &DraftPreview{
+		Draft:          d,
+		CurrentVersion: d.CurrentVersion(idiom),
+		Outdated:       d.Outdated(idiom),
+		CanManage:      canManageDraft(r, d),
+	}
+	impl, err := d.Apply(idiom)
+	if err != nil {
+		return PiErrorf(http.StatusConflict, "Can't apply draft: %v", err)
+	}
+
+	var selectedImplID int
+	var selectedImplLang string
+	if impl != nil {
+		selectedImplID, selectedImplLang = impl.Id, impl.LanguageName
+		// Draft impl as very first element
+		for i := range idiom.Implementations {
+			if &idiom.Implementations[i] == impl {
+				idiom.Implementations[0], idiom.Implementations[i] = idiom.Implementations[i], idiom.Implementations[0]
+				break
+			}
+		}
+	}
+	decorateImplVariables(idiom)
+
+	myToggles := copyToggles(toggles)
+	// No actions on a draft
+	myToggles["editing"] = true
+	data := &IdiomDetailFacade{
+		PageMeta: PageMeta{
+			PageTitle:             "Draft: " + idiom.Title,
+			Toggles:               myToggles,
+			PreventIndexingRobots: true,
+		},
+		UserProfile:      readUserProfile(r),
+		Idiom:            idiom,
+		SelectedImplID:   selectedImplID,
+		SelectedImplLang: selectedImplLang,
+		DraftPreview:     preview,
+	}
+	return templates.ExecuteTemplate(w, "page-idiom-detail", data)
+}
+
+// Handle /draft-publish
+// The usual version conflict check applies, against the draft BaseVersion.
+func draftPublish(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+	ctx := r.Context()
+	d, err := loadDraft(ctx, r.FormValue("draft_id"))
+	if err != nil {
+		return err
+	}
+	if !canManageDraft(r, d) {
+		return PiErrorf(http.StatusForbidden, "This draft is not yours.")
+	}
+	_, idiom, err := dao.getIdiom(ctx, d.IdiomID)
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %d", d.IdiomID)
+	}
+	log.Infof(ctx, "[%s] publishes %s draft %s for idiom %d impl %d", d.Nickname, d.Kind, d.ID, d.IdiomID, d.ImplID)
+
+	// Replay the edit form. The draft is deleted by the save, if successful.
+	form := draftFormValues(d, idiom)
+	r.Form, r.PostForm = form, form
+	if d.Kind == DraftIdiom {
+		return idiomSave(w, r)
+	}
+	return implSave(w, r)
+}
+
+// draftFormValues is the edit form of d.
+// The fields which are not in drafts (admin fields) have their current values.
+func draftFormValues(d *Draft, idiom *Idiom) url.Values {
+	form := url.Values{}
+	form.Set("draft_id", d.ID)
+	form.Set("idiom_id", strconv.Itoa(d.IdiomID))
+	form.Set("user_nickname", d.Nickname)
+	form.Set("edit_summary", d.EditSummary)
+	setLicenseFields := func(prefix, license, source string, date time.Time) {
+		form.Set(prefix+"license", license)
+		form.Set(prefix+"attribution_source", source)
+		if !date.IsZero() {
+			form.Set(prefix+"attribution_date", date.Format("2006-01-02"))
+		}
+	}
+
+	if d.Kind == DraftIdiom {
+		form.Set("idiom_version", strconv.Itoa(d.BaseVersion))
+		form.Set("idiom_title", d.Title)
+		form.Set("idiom_lead", d.LeadParagraph)
+		form.Set("idiom_keywords", d.ExtraKeywords)
+		form.Set("idiom_tags", strings.Join(d.Tags, ", "))
+		for i := range d.ExampleInputs {
+			form.Set(fmt.Sprintf("example_input_%d", i+1), d.ExampleInputs[i])
+			form.Set(fmt.Sprintf("example_output_%d", i+1), d.ExampleOutputs[i])
+		}
+		if idiom.Protected {
+			form.Set("idiom_protected", "on")
+		}
+		setLicenseFields("idiom_", idiom.License, idiom.AttributionSource, idiom.AttributionDate)
+		form.Set("idiom_variables", strings.Join(idiom.Variables, ","))
+		for i := range idiom.RelatedURLs {
+			form.Set(fmt.Sprintf("related_url_%d", i+1), idiom.RelatedURLs[i])
+			form.Set(fmt.Sprintf("related_url_label_%d", i+1), idiom.RelatedURLLabels[i])
+		}
+		return form
+	}
+
+	if d.Kind == DraftImpl {
+		form.Set("impl_id", strconv.Itoa(d.ImplID))
+		form.Set("impl_version", strconv.Itoa(d.BaseVersion))
+		if _, impl, found := idiom.FindImplInIdiom(d.ImplID); found {
+			if impl.Protected {
+				form.Set("impl_protected", "on")
+			}
+			form.Set("impl_picture_url", impl.PictureURL)
+			setLicenseFields("impl_", impl.License, impl.AttributionSource, impl.AttributionDate)
+		}
+	} else {
+		form.Set("impl_language", d.LanguageName)
+	}
+	form.Set("impl_imports", d.ImportsBlock)
+	form.Set("impl_code", d.CodeBlock)
+	form.Set("impl_comment", d.AuthorComment)
+	form.Set("impl_attribution_url", d.OriginalAttributionURL)
+	form.Set("impl_demo_url", d.DemoURL)
+	form.Set("impl_doc_url", d.DocumentationURL)
+	form.Set("impl_min_version", d.MinVersion)
+	form.Set("impl_dialect", d.Dialect)
+	form.Set("impl_expected_output", d.ExpectedOutput)
+	if d.VerifiedExamples {
+		form.Set("impl_verified_examples", "on")
+	}
+	return form
+}
+
+// discardDraft deletes the draft of a successful save, if any.
+func discardDraft(ctx context.Context, r *http.Request) {
+	id := r.FormValue("draft_id")
+	if id == "" {
+		return
+	}
+	d, err := loadDraft(ctx, id)
+	if err != nil || !canManageDraft(r, d) {
+		return
+	}
+	err = datastore.Delete(ctx, newDraftKey(ctx, id))
+	logIf(err, log.Errorf, ctx, "deleting published draft")
+}
+
+// Handle /draft-delete
+func draftDelete(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+	ctx := r.Context()
+	d, err := loadDraft(ctx, r.FormValue("draft_id"))
+	if err != nil {
+		return err
+	}
+	if !canManageDraft(r, d) {
+		return PiErrorf(http.StatusForbidden, "This draft is not yours.")
+	}
+	if err := datastore.Delete(ctx, newDraftKey(ctx, d.ID)); err != nil {
+		return err
+	}
+	log.Infof(ctx, "Deleted draft %s of [%s]", d.ID, d.Nickname)
+	http.Redirect(w, r, hostPrefix()+"/my-drafts", http.StatusFound)
+	return nil
+}
+
+// MyDraftsFacade is the Facade for the list of drafts of the visitor.
+type MyDraftsFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Owner       string
+	Drafts      []DraftPreview
+	// Titles of the idioms, by ID.
+	Titles map[int]string
+}
+
+// Handle /my-drafts
+func myDrafts(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	owner, authenticated := draftOwner(r)
+	var drafts []Draft
+	if owner != "" {
+		_, err := datastore.NewQuery("Draft").
+			Filter("Owner =", owner).
+			Filter("Authenticated =", authenticated).
+			Order("-UpdateDate").
+			GetAll(ctx, &drafts)
+		if err != nil {
+			return err
+		}
+	}
+
+	previews := make([]DraftPreview, len(drafts))
+	titles := map[int]string{}
+	for i := range drafts {
+		d := &drafts[i]
+		previews[i] = DraftPreview{Draft: d, CurrentVersion: -1, Outdated: true, CanManage: true}
+		if _, idiom, err := dao.getIdiom(ctx, d.IdiomID); err == nil {
+			previews[i].CurrentVersion = d.CurrentVersion(idiom)
+			previews[i].Outdated = d.Outdated(idiom)
+			titles[d.IdiomID] = idiom.Title
+		}
+	}
+
+	data := &MyDraftsFacade{
+		PageMeta: PageMeta{
+			PageTitle:             "My drafts",
+			Toggles:               toggles,
+			PreventIndexingRobots: true,
+		},
+		UserProfile: readUserProfile(r),
+		Owner:       owner,
+		Drafts:      previews,
+		Titles:      titles,
+	}
+	return templates.ExecuteTemplate(w, "page-my-drafts", data)
+}
@@ -149,10 +149,11 @@ func revertIdiomVersion(w http.ResponseWriter, r *http.Request) error {
 	version := String2Int(versionStr)
 	ctx := r.Context()
 
-	_, err := dao.revert(ctx, idiomID, version)
+	idiom, err := dao.revert(ctx, idiomID, version)
 	if err != nil {
 		return err
 	}
+	propagateTitle(ctx, idiom)
 	redirUrl := hostPrefix() + "/history/" + idiomIDStr + "?reverted=" + versionStr
 	http.Redirect(w, r, redirUrl, http.StatusFound)
 	return nil
@@ -172,6 +173,7 @@ func restoreIdiomVersion(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
+	propagateTitle(ctx, idiom)
 	redirUrl := NiceIdiomURL(idiom)
 	http.Redirect(w, r, redirUrl, http.StatusFound)
 	return nil
@@ -23,14 +23,28 @@ type IdiomDetailFacade struct {
 	Idiom            *Idiom
 	SelectedImplID   int
 	SelectedImplLang string
+	// Fallbacks suggest the closest impls, for the favorite languages
+	// not implemented in this idiom.
+	Fallbacks []LanguageFallback
+	// AlsoLike are the related idioms, and the best suggested ones.
+	AlsoLike []AlsoLikeLink
+	// DraftPreview is set when the page shows a draft instead of the live idiom.
+	DraftPreview *DraftPreview
 }
 
+// maxFallbackImpls is the number of closest impls suggested for a missing language.
+const maxFallbackImpls = 2
+
 func idiomDetail(w http.ResponseWriter, r *http.Request) error {
 	vars := mux.Vars(r)
 	ctx := r.Context()
 	userProfile := readUserProfile(r)
 	favlangs := userProfile.FavoriteLanguages
 
+	if toggles["relatedSuggestions"] {
+		recordIdiomView(w, r, String2Int(vars["idiomId"]))
+	}
+
 	pushResources := func() {
 		if _, err := r.Cookie("v"); err == nil {
 			log.Infof(ctx, "Returning visitor: no resource server push needed.")
@@ -79,6 +93,10 @@ func idiomDetail(w http.ResponseWriter, r *http.Request) error {
 				http.Redirect(w, r, string(properURL), 302)
 				return nil
 			}
+			if newURL, ok := err.(movedPermanentlyError); ok {
+				http.Redirect(w, r, string(newURL), http.StatusMovedPermanently)
+				return nil
+			}
 			return err
 		}
 		pushResources()
@@ -111,11 +129,19 @@ func idiomDetail(w http.ResponseWriter, r *http.Request) error {
 
 	_, idiom, err := dao.getIdiom(ctx, idiomID)
 	if err != nil {
+		if newURL, ok := mergedIdiomURL(ctx, vars); ok {
+			http.Redirect(w, r, newURL, http.StatusMovedPermanently)
+			return nil
+		}
 		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
 	}
 
+	// The URL may contain either the English or the translated title
+	englishTitleInURL := uriNormalize(idiom.Title)
+	applyTranslations(ctx, []*Idiom{idiom}, userProfile.Locale)
+
 	idiomTitleInURL := vars["idiomTitle"]
-	if idiomTitleInURL != "" && uriNormalize(idiom.Title) != idiomTitleInURL {
+	if idiomTitleInURL != "" && uriNormalize(idiom.Title) != idiomTitleInURL && englishTitleInURL != idiomTitleInURL {
 		// Maybe the title has changed recently,
 		// or someone is attempting a practical joke forging a funny URL ?
 		properURL := NiceIdiomURL(idiom)
@@ -135,14 +161,24 @@ func idiomDetail(w http.ResponseWriter, r *http.Request) error {
 		}
 		if selectedImplLang == "" {
 			// The requested implementation was not found.
+			if newURL, ok := movedImplURL(ctx, idiom.Id, selectedImplID); ok {
+				http.Redirect(w, r, newURL, http.StatusMovedPermanently)
+				return nil
+			}
 			properURL := NiceIdiomURL(idiom)
 			http.Redirect(w, r, properURL, 302)
 			return nil
 		}
-		canonicalURL = host() + NiceImplRelativeURL(idiom, selectedImplID, selectedImplLang)
+		canonicalURL = host() + localePrefix(idiom.Deco.Locale) + NiceImplRelativeURL(idiom, selectedImplID, selectedImplLang)
 	} else {
 		// Just the idiom, no specific impl
-		canonicalURL = host() + NiceIdiomRelativeURL(idiom)
+		canonicalURL = host() + localePrefix(idiom.Deco.Locale) + NiceIdiomRelativeURL(idiom)
+	}
+
+	var fallbacks []LanguageFallback
+	if toggles["languageFallbacks"] {
+		// Before the non-favorite impls are possibly filtered out
+		fallbacks = idiom.LanguageFallbacks(favlangs, maxFallbackImpls)
 	}
 
 	includeNonFav := seeNonFavorite(r)
@@ -161,6 +197,10 @@ func idiomDetail(w http.ResponseWriter, r *http.Request) error {
 	}
 	log.Debugf(ctx, "Reorder impls end.")
 
+	applyTargetVersions(idiom, userProfile, selectedImplID)
+	applyCheckedOnly(idiom, userProfile, selectedImplID)
+	decorateImplVariables(idiom)
+
 	implLangInURL := vars["implLang"]
 	if implLangInURL != "" && strings.ToLower(selectedImplLang) != strings.ToLower(implLangInURL) {
 		// Maybe an accident,
@@ -206,6 +246,8 @@ func idiomDetail(w http.ResponseWriter, r *http.Request) error {
 		Idiom:            idiom,
 		SelectedImplID:   selectedImplID,
 		SelectedImplLang: selectedImplLang,
+		Fallbacks:        fallbacks,
+		AlsoLike:         alsoLike(ctx, idiom),
 	}
 
 	pushResources()
@@ -227,6 +269,9 @@ func generateIdiomDetailPage(ctx context.Context, w io.Writer, vars map[string]s
 
 	_, idiom, err := dao.getIdiom(ctx, idiomID)
 	if err != nil {
+		if newURL, ok := mergedIdiomURL(ctx, vars); ok {
+			return movedPermanentlyError(newURL)
+		}
 		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
 	}
 
@@ -250,6 +295,9 @@ func generateIdiomDetailPage(ctx context.Context, w io.Writer, vars map[string]s
 		}
 		if selectedImplLang == "" {
 			// The requested implementation was not found.
+			if newURL, ok := movedImplURL(ctx, idiom.Id, selectedImplID); ok {
+				return movedPermanentlyError(newURL)
+			}
 			properURL := NiceIdiomURL(idiom)
 			return needRedirectError(properURL)
 		}
@@ -275,6 +323,7 @@ func generateIdiomDetailPage(ctx context.Context, w io.Writer, vars map[string]s
 		properURL := NiceImplURL(idiom, selectedImplID, selectedImplLang)
 		return needRedirectError(properURL)
 	}
+	decorateImplVariables(idiom)
 
 	pageTitle := idiom.Title
 	if selectedImplLang != "" {
@@ -298,6 +347,7 @@ func generateIdiomDetailPage(ctx context.Context, w io.Writer, vars map[string]s
 		Idiom:            idiom,
 		SelectedImplID:   selectedImplID,
 		SelectedImplLang: selectedImplLang,
+		AlsoLike:         alsoLike(ctx, idiom),
 	}
 
 	log.Debugf(ctx, "ExecuteTemplate start...")
@@ -14,6 +14,8 @@ type IdiomEditFacade struct {
 	PageMeta    PageMeta
 	UserProfile UserProfile
 	Idiom       *Idiom
+	// Draft, if the form is filled with a draft.
+	Draft *Draft
 }
 
 func idiomEdit(w http.ResponseWriter, r *http.Request) error {
@@ -28,6 +30,19 @@ func idiomEdit(w http.ResponseWriter, r *http.Request) error {
 		return PiErrorf(http.StatusNotFound, "Idiom %q not found : %v", idiomIDStr, err)
 	}
 
+	draft, err := lookForDraft(r, idiomID)
+	if err != nil {
+		return err
+	}
+	if draft != nil {
+		if draft.Kind != DraftIdiom {
+			return PiErrorf(http.StatusBadRequest, "Draft %s is not about the idiom statement", draft.ID)
+		}
+		if _, err := draft.Apply(idiom); err != nil {
+			return PiErrorf(http.StatusConflict, "Can't apply draft: %v", err)
+		}
+	}
+
 	userProfile := readUserProfile(r)
 	myToggles := copyToggles(toggles)
 	myToggles["editing"] = true
@@ -40,7 +55,33 @@ func idiomEdit(w http.ResponseWriter, r *http.Request) error {
 		},
 		UserProfile: userProfile,
 		Idiom:       idiom,
+		Draft:       draft,
 	}
 
 	return templates.ExecuteTemplate(w, "page-idiom-edit", data)
 }
+
+// exampleSlot is one example in the idiom edit forms.
+type exampleSlot struct {
+	N      int
+	Input  string
+	Output string
+}
+
+// exampleSlots returns maxExamples form slots, filled with the existing examples of idiom.
+func exampleSlots(idiom *Idiom) []exampleSlot {
+	slots := make([]exampleSlot, maxExamples)
+	for i := range slots {
+		slots[i].N = i + 1
+		if idiom == nil {
+			continue
+		}
+		if i < len(idiom.ExampleInputs) {
+			slots[i].Input = idiom.ExampleInputs[i]
+		}
+		if i < len(idiom.ExampleOutputs) {
+			slots[i].Output = idiom.ExampleOutputs[i]
+		}
+	}
+	return slots
+}
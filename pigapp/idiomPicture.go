@@ -14,16 +14,21 @@ import (
 // Admin only.
 // Admin types URL, preferably to dedicated GCS bucket programming-idioms-pictures.
 //
+// Now the pictures may also be uploaded, see imageUpload.
+// Non-admin uploads wait for moderation.
+//
 
 // IdiomAddPictureFacade is the Facade for the Add Idiom Picture page.
 type IdiomAddPictureFacade struct {
 	PageMeta    PageMeta
 	UserProfile UserProfile
 	Idiom       *Idiom
+	// Impl is set when the picture is for an impl.
+	Impl *Impl
 }
 
 func idiomAddPicture(w http.ResponseWriter, r *http.Request) error {
-	if !IsAdmin(r) {
+	if !IsAdmin(r) && !toggles["pictureEditing"] {
 		return fmt.Errorf("For now, only the Admin may add an idiom picture.")
 	}
 
@@ -41,6 +46,15 @@ func idiomAddPicture(w http.ResponseWriter, r *http.Request) error {
 		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
 	}
 
+	var impl *Impl
+	if implIDStr := r.FormValue("implId"); implIDStr != "" {
+		var found bool
+		_, impl, found = idiom.FindImplInIdiom(String2Int(implIDStr))
+		if !found {
+			return PiErrorf(http.StatusNotFound, "Could not find impl %q in idiom %q", implIDStr, idiomIDStr)
+		}
+	}
+
 	myToggles := copyToggles(toggles)
 	myToggles["editing"] = true
 
@@ -51,6 +65,7 @@ func idiomAddPicture(w http.ResponseWriter, r *http.Request) error {
 		},
 		UserProfile: readUserProfile(r),
 		Idiom:       idiom,
+		Impl:        impl,
 	}
 
 	return templates.ExecuteTemplate(w, "page-idiom-add-picture", data)
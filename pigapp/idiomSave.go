@@ -44,6 +44,7 @@ func newIdiomSave(w http.ResponseWriter, r *http.Request, username string, title
 	ctx := r.Context()
 	lead := r.FormValue("idiom_lead")
 	keywords := r.FormValue("idiom_keywords")
+	tags := ParseTags(Truncate(r.FormValue("idiom_tags"), 250))
 	picture := r.FormValue("idiom_picture") /* TODO upload file ?! */
 	language := NormLang(r.FormValue("impl_language"))
 	imports := r.FormValue("impl_imports")
@@ -66,7 +67,10 @@ func newIdiomSave(w http.ResponseWriter, r *http.Request, username string, title
 	log.Infof(ctx, "[%v] is creating new idiom [%v]", username, title)
 
 	if !StringSliceContains(AllLanguages(), language) {
-		return PiErrorf(http.StatusBadRequest, "Sorry, [%v] is currently not a supported language. Supported languages are %v.", r.FormValue("impl_language"), AllNiceLangs)
+		return PiErrorf(http.StatusBadRequest, "Sorry, [%v] is currently not a supported language. Supported languages are %v.", r.FormValue("impl_language"), AllNiceLanguages())
+	}
+	if err := checkSnippetSyntax(language, imports, code); err != nil {
+		return err
 	}
 
 	// TODO put that in a transaction!
@@ -92,6 +96,7 @@ func newIdiomSave(w http.ResponseWriter, r *http.Request, username string, title
 			OriginalAttributionURL: attributionURL,
 			DemoURL:                demoURL,
 			DocumentationURL:       docURL,
+			Checked:                IsAdmin(r),
 		},
 	}
 	idiom := &Idiom{
@@ -99,6 +104,7 @@ func newIdiomSave(w http.ResponseWriter, r *http.Request, username string, title
 		Title:            title,
 		LeadParagraph:    lead,
 		ExtraKeywords:    keywords,
+		Tags:             tags,
 		Picture:          picture, /* TODO upload file ?! */
 		Author:           username,
 		LastEditor:       username,
@@ -106,6 +112,11 @@ func newIdiomSave(w http.ResponseWriter, r *http.Request, username string, title
 		EditSummary:      editSummary,
 		Rating:           0,
 		Implementations:  implementations,
+		Checked:          IsAdmin(r),
+	}
+	idiom.SetExamples(exampleFormValues(r))
+	if err := idiom.CheckExampleVariables(); err != nil {
+		return PiErrorf(http.StatusBadRequest, "%v", err)
 	}
 	/*
 		Authenticated user name not needed here, as of 2015.
@@ -123,6 +134,8 @@ func newIdiomSave(w http.ResponseWriter, r *http.Request, username string, title
 	if err != nil {
 		return err
 	}
+	enqueueReview(ctx, r, idiom, nil)
+	enqueueReview(ctx, r, idiom, &idiom.Implementations[0])
 
 	htmlCacheEvict(ctx, "/about-block-all-idioms")
 
@@ -154,56 +167,61 @@ func existingIdiomSave(w http.ResponseWriter, r *http.Request, username string,
 	if idiom.Protected && !isAdmin {
 		return PiErrorf(http.StatusUnauthorized, "Can't edit protected idiom %q", existingIDStr)
 	}
+	if r.FormValue("idiom_version") != strconv.Itoa(idiom.Version) {
+		log.Infof(ctx, "Idiom has been concurrently modified (editing version %v, current version is %v)", r.FormValue("idiom_version"), idiom.Version)
+		submitted := &Idiom{
+			Title:         title,
+			LeadParagraph: r.FormValue("idiom_lead"),
+			ExtraKeywords: r.FormValue("idiom_keywords"),
+			Tags:          ParseTags(Truncate(r.FormValue("idiom_tags"), 250)),
+		}
+		submitted.SetExamples(exampleFormValues(r))
+		if isAdmin {
+			if err := readIdiomAdminFields(r, submitted); err != nil {
+				return err
+			}
+		}
+		merges := mergeIdiomEdit(ctx, r, idiom, submitted, isAdmin)
+		if merges.HasConflict() {
+			return showMergeConflicts(w, r, idiom, nil, merges)
+		}
+		// The save below reads the merged values
+		for name, value := range merges.Merged() {
+			r.Form.Set(name, value)
+		}
+		title = r.FormValue("idiom_title")
+	}
+
 	if isAdmin {
 		wasProtected := idiom.Protected
-		idiom.Protected = r.FormValue("idiom_protected") != ""
-
+		if err := readIdiomAdminFields(r, idiom); err != nil {
+			return err
+		}
 		if wasProtected && !idiom.Protected {
 			log.Infof(ctx, "[%v] unprotects idiom %v", username, existingIDStr)
 		}
 		if !wasProtected && idiom.Protected {
 			log.Infof(ctx, "[%v] protects idiom %v", username, existingIDStr)
 		}
-
-		if vars := strings.Replace(r.FormValue("idiom_variables"), " ", "", -1); vars == "" {
-			idiom.Variables = nil
-		} else {
-			idiom.Variables = strings.Split(vars, ",")
-		}
-
-		newRelatedURLs := []string{
-			strings.TrimSpace(r.FormValue("related_url_1")),
-			strings.TrimSpace(r.FormValue("related_url_2")),
-			strings.TrimSpace(r.FormValue("related_url_3")),
-		}
-		newRelatedURLLabels := []string{
-			strings.TrimSpace(r.FormValue("related_url_label_1")),
-			strings.TrimSpace(r.FormValue("related_url_label_2")),
-			strings.TrimSpace(r.FormValue("related_url_label_3")),
-		}
-		idiom.RelatedURLs = nil
-		idiom.RelatedURLLabels = nil
-		for i := range newRelatedURLs {
-			url, label := newRelatedURLs[i], newRelatedURLLabels[i]
-			if url != "" {
-				idiom.RelatedURLs = append(idiom.RelatedURLs, url)
-				if label == "" {
-					label = "See also"
-				}
-				idiom.RelatedURLLabels = append(idiom.RelatedURLLabels, label)
-			}
-		}
-	}
-
-	if r.FormValue("idiom_version") != strconv.Itoa(idiom.Version) {
-		return PiErrorf(http.StatusConflict, "Idiom has been concurrently modified (editing version %v, current version is %v)", r.FormValue("idiom_version"), idiom.Version)
 	}
 
 	idiom.LastEditor = username
 	idiom.LastEditedImplID = 0
+	idiom.Checked = isAdmin
+	titleChanged := idiom.Title != title
 	idiom.Title = title
 	idiom.LeadParagraph = r.FormValue("idiom_lead")
 	idiom.ExtraKeywords = r.FormValue("idiom_keywords")
+	idiom.Tags = ParseTags(Truncate(r.FormValue("idiom_tags"), 250))
+	previousExamples := Idiom{ExampleInputs: idiom.ExampleInputs, ExampleOutputs: idiom.ExampleOutputs}
+	idiom.SetExamples(exampleFormValues(r))
+	if err := idiom.CheckExampleVariables(); err != nil {
+		return PiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	if !SameExamples(idiom, &previousExamples) {
+		// Impls verified against the old examples are not verified anymore
+		idiom.ResetVerifiedExamples()
+	}
 	idiom.EditSummary = r.FormValue("edit_summary")
 	/* idiomPicture.go
 	idiom.Picture, err = processUploadFile(r, "idiom_picture")
@@ -219,7 +237,60 @@ func existingIdiomSave(w http.ResponseWriter, r *http.Request, username string,
 	if err != nil {
 		return err
 	}
+	if titleChanged {
+		propagateTitle(ctx, idiom)
+	}
+	enqueueReview(ctx, r, idiom, nil)
+	discardDraft(ctx, r)
 
 	http.Redirect(w, r, NiceIdiomURL(idiom), http.StatusFound)
 	return nil
 }
+
+// maxRelatedURLs is the number of related URL slots in the idiom form of the admins.
+const maxRelatedURLs = 3
+
+// readIdiomAdminFields reads the fields of the idiom form that only an admin may set.
+func readIdiomAdminFields(r *http.Request, idiom *Idiom) error {
+	idiom.Protected = r.FormValue("idiom_protected") != ""
+
+	// Only Admin may import a statement under another license
+	var err error
+	idiom.License, idiom.AttributionSource, idiom.AttributionDate, err = readLicenseFields(r, "idiom_")
+	if err != nil {
+		return err
+	}
+
+	if vars := strings.Replace(r.FormValue("idiom_variables"), " ", "", -1); vars == "" {
+		idiom.Variables = nil
+	} else {
+		idiom.Variables = strings.Split(vars, ",")
+	}
+
+	idiom.RelatedURLs = nil
+	idiom.RelatedURLLabels = nil
+	for i := 1; i <= maxRelatedURLs; i++ {
+		url := strings.TrimSpace(r.FormValue(fmt.Sprintf("related_url_%d", i)))
+		label := strings.TrimSpace(r.FormValue(fmt.Sprintf("related_url_label_%d", i)))
+		if url != "" {
+			idiom.RelatedURLs = append(idiom.RelatedURLs, url)
+			if label == "" {
+				label = "See also"
+			}
+			idiom.RelatedURLLabels = append(idiom.RelatedURLLabels, label)
+		}
+	}
+	return nil
+}
+
+// maxExamples is the number of example slots in the idiom forms.
+const maxExamples = 3
+
+// exampleFormValues reads the example inputs and outputs of the idiom forms.
+func exampleFormValues(r *http.Request) (inputs, outputs []string) {
+	for i := 1; i <= maxExamples; i++ {
+		inputs = append(inputs, TruncateBytes(r.FormValue(fmt.Sprintf("example_input_%d", i)), 500))
+		outputs = append(outputs, TruncateBytes(r.FormValue(fmt.Sprintf("example_output_%d", i)), 500))
+	}
+	return inputs, outputs
+}
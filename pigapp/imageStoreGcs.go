@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/file"
+)
+
+// GcsBlobStore is a BlobStore in a Google Cloud Storage bucket,
+// accessed through the JSON API with the credentials of the app.
+type GcsBlobStore struct {
+	// Bucket is the name of the bucket. Empty means the default bucket of the app.
+	Bucket string
+	// Prefix is prepended to the blob names, e.g. "images/".
+	Prefix string
+}
+
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// maxBlobBytes is more than the size of any encoded variant.
+const maxBlobBytes = 8 << 20
+
+var gcsClient = &http.Client{Timeout: 20 * time.Second}
+
+func (s GcsBlobStore) bucket(ctx context.Context) (string, error) {
+	if s.Bucket != "" {
+		return s.Bucket, nil
+	}
+	bucket, err := file.DefaultBucketName(ctx)
+	if err != nil {
+		return "", err
+	}
+	if bucket == "" {
+		return "", fmt.Errorf("no default bucket for this app")
+	}
+	return bucket, nil
+}
+
+func (s GcsBlobStore) do(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	token, _, err := appengine.AccessToken(ctx, gcsScope)
+	if err != nil {
+		return nil, err
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return gcsClient.Do(req)
+}
+
+func (s GcsBlobStore) objectURL(ctx context.Context, name string) (string, error) {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "https://storage.googleapis.com/storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(s.Prefix+name), nil
+}
+
+// Put writes the blob, overwriting any previous one with the same name.
+func (s GcsBlobStore) Put(ctx context.Context, name string, data []byte) error {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	u := "https://storage.googleapis.com/upload/storage/v1/b/" + url.PathEscape(bucket) +
+		"/o?uploadType=media&name=" + url.QueryEscape(s.Prefix+name)
+	resp, err := s.do(ctx, "POST", u, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("writing blob %s: %s", name, resp.Status)
+	}
+	return nil
+}
+
+// Get reads the blob, or returns ErrBlobNotFound.
+func (s GcsBlobStore) Get(ctx context.Context, name string) ([]byte, error) {
+	u, err := s.objectURL(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, "GET", u+"?alt=media", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrBlobNotFound
+	default:
+		return nil, fmt.Errorf("reading blob %s: %s", name, resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxBlobBytes))
+}
+
+// Delete removes the blob. Deleting an unknown blob is not an error.
+func (s GcsBlobStore) Delete(ctx context.Context, name string) error {
+	u, err := s.objectURL(ctx, name)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, "DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("deleting blob %s: %s", name, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/user"
+)
+
+//
+// Upload of idiom and impl pictures.
+// The admin uploads are applied right away, the others wait for moderation.
+// The resized pictures are served by /image/{name}, once approved.
+//
+
+// blobStore keeps the uploaded pictures, in the Cloud Storage bucket
+// PI_IMAGE_BUCKET (default: the default bucket of the app).
+// For local development only, PI_IMAGE_DIR sets a local directory instead.
+var blobStore = newBlobStore()
+
+func newBlobStore() BlobStore {
+	if dir := os.Getenv("PI_IMAGE_DIR"); dir != "" {
+		return LocalBlobStore{Dir: dir}
+	}
+	return GcsBlobStore{
+		Bucket: os.Getenv("PI_IMAGE_BUCKET"),
+		Prefix: "images/",
+	}
+}
+
+func newImageUploadKey(ctx context.Context, keyName string) *datastore.Key {
+	return datastore.NewKey(ctx, "ImageUpload", keyName, 0, nil)
+}
+
+// imageUploadsOfHash returns the uploads of the same picture, for any idiom or impl.
+func imageUploadsOfHash(ctx context.Context, hash string) ([]ImageUpload, error) {
+	var uploads []ImageUpload
+	_, err := datastore.NewQuery("ImageUpload").
+		Filter("Hash =", hash).
+		Limit(100).
+		GetAll(ctx, &uploads)
+	return uploads, err
+}
+
+// deletePendingBlobs deletes the pending blobs of upload, unless
+// another pending upload of the same picture still needs them.
+func deletePendingBlobs(ctx context.Context, upload *ImageUpload) {
+	uploads, err := imageUploadsOfHash(ctx, upload.Hash)
+	if err != nil {
+		log.Errorf(ctx, "listing uploads of picture %s: %v", upload.Hash, err)
+		return
+	}
+	for _, other := range uploads {
+		if other.Status == ImageUploadPending && other.KeyName() != upload.KeyName() {
+			return
+		}
+	}
+	for _, name := range []string{upload.ThumbName, upload.FullName} {
+		if err := blobStore.Delete(ctx, PendingBlobName(name)); err != nil {
+			log.Errorf(ctx, "deleting pending picture %s: %v", name, err)
+		}
+	}
+}
+
+func imageURL(name string) string {
+	return hostPrefix() + "/image/" + name
+}
+
+// Handle /image-upload
+// Multipart form with idiom_id, optional impl_id, and the file picture.
+func imageUpload(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	// The body size is already limited by maxRequestBytes.
+	if err := r.ParseMultipartForm(MaxImageUploadBytes + 64<<10); err != nil {
+		return PiErrorf(http.StatusBadRequest, "Could not read the upload: %v", err)
+	}
+	username := Truncate(r.FormValue("user_nickname"), 30)
+	isAdmin := IsAdmin(r)
+	if username == "" && !isAdmin {
+		return PiErrorf(http.StatusBadRequest, "Username is mandatory. No anonymous upload.")
+	}
+	setNicknameCookie(w, username)
+
+	idiomIDStr := r.FormValue("idiom_id")
+	key, idiom, err := dao.getIdiom(ctx, String2Int(idiomIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+	implID := 0
+	if implIDStr := r.FormValue("impl_id"); implIDStr != "" {
+		implID = String2Int(implIDStr)
+		if _, _, found := idiom.FindImplInIdiom(implID); !found {
+			return PiErrorf(http.StatusNotFound, "Could not find impl %q in idiom %q", implIDStr, idiomIDStr)
+		}
+	}
+	if idiom.Protected && !isAdmin {
+		return PiErrorf(http.StatusUnauthorized, "Can't edit protected idiom %q", idiomIDStr)
+	}
+
+	file, header, err := r.FormFile("picture")
+	if err != nil {
+		return PiErrorf(http.StatusBadRequest, "Missing picture file")
+	}
+	defer file.Close()
+	if header.Size > MaxImageUploadBytes {
+		return PiErrorf(http.StatusRequestEntityTooLarge, "Picture is too large (max %d kB)", MaxImageUploadBytes>>10)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(file, MaxImageUploadBytes+1))
+	if err != nil {
+		return err
+	}
+	processed, err := ProcessImage(data)
+	if err != nil {
+		return PiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	var existing ImageUpload
+	err = datastore.Get(ctx, newImageUploadKey(ctx, ImageUploadKeyName(processed.Hash, idiom.Id, implID)), &existing)
+	if err == nil && existing.Status != ImageUploadRejected {
+		return PiErrorf(http.StatusConflict, "This picture was already uploaded here")
+	}
+	for variant, blob := range processed.Blobs {
+		name := processed.BlobName(variant)
+		if !isAdmin {
+			// Not served until approved
+			name = PendingBlobName(name)
+		}
+		if err := blobStore.Put(ctx, name, blob); err != nil {
+			return err
+		}
+	}
+
+	upload := ImageUpload{
+		Hash:        processed.Hash,
+		IdiomID:     idiom.Id,
+		ImplID:      implID,
+		Uploader:    username,
+		UploadDate:  time.Now(),
+		ContentType: processed.ContentType,
+		Width:       processed.Width,
+		Height:      processed.Height,
+		ThumbName:   processed.BlobName("thumb"),
+		FullName:    processed.BlobName("full"),
+		Status:      ImageUploadPending,
+	}
+	if isAdmin {
+		if err := applyImageUpload(ctx, key, idiom, &upload, username); err != nil {
+			return err
+		}
+		upload.Status = ImageUploadApproved
+		upload.Decider = "admin"
+		upload.DecisionDate = upload.UploadDate
+	}
+	if _, err := datastore.Put(ctx, newImageUploadKey(ctx, upload.KeyName()), &upload); err != nil {
+		return err
+	}
+	log.Infof(ctx, "[%s] uploaded picture %s for idiom %d impl %d: %s", username, upload.Hash, upload.IdiomID, upload.ImplID, upload.Status)
+
+	http.Redirect(w, r, NiceIdiomURL(idiom), http.StatusFound)
+	return nil
+}
+
+// applyImageUpload sets the picture URL of the idiom or impl, in a new version.
+func applyImageUpload(ctx context.Context, key *datastore.Key, idiom *Idiom, upload *ImageUpload, editor string) error {
+	url := imageURL(upload.FullName)
+	if upload.ImplID == 0 {
+		idiom.ImageURL = url
+		idiom.LastEditedImplID = 0
+		idiom.EditSummary = fmt.Sprintf("Uploaded picture by user [%s]", upload.Uploader)
+	} else {
+		_, impl, found := idiom.FindImplInIdiom(upload.ImplID)
+		if !found {
+			return PiErrorf(http.StatusNotFound, "Could not find impl %d in idiom %d", upload.ImplID, idiom.Id)
+		}
+		impl.PictureURL = url
+		idiom.LastEditedImplID = impl.Id
+		idiom.EditSummary = fmt.Sprintf("[%s] Uploaded picture by user [%s]", PrintNiceLang(impl.LanguageName), upload.Uploader)
+	}
+	idiom.LastEditor = editor
+	return dao.saveExistingIdiom(ctx, key, idiom)
+}
+
+// publishImageUpload moves the pending blobs of upload to their public names.
+func publishImageUpload(ctx context.Context, upload *ImageUpload) error {
+	for _, name := range []string{upload.ThumbName, upload.FullName} {
+		data, err := blobStore.Get(ctx, PendingBlobName(name))
+		if err != nil {
+			return fmt.Errorf("reading pending picture %s: %v", name, err)
+		}
+		if err := blobStore.Put(ctx, name, data); err != nil {
+			return err
+		}
+	}
+	deletePendingBlobs(ctx, upload)
+	return nil
+}
+
+// Handle /image/{name}
+// Only the approved pictures are public. Their names contain the hash
+// of the picture, so they can be cached forever.
+// The admins may also see the pending pictures, for moderation.
+func serveImage(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	name := mux.Vars(r)["name"]
+	hash, ok := ImageHashOfBlobName(name)
+	if !ok {
+		return PiErrorf(http.StatusNotFound, "No picture %q", name)
+	}
+	uploads, err := imageUploadsOfHash(ctx, hash)
+	if err != nil {
+		return err
+	}
+	// The same picture may be approved for an idiom, and pending for another.
+	blobName, cacheControl := "", ""
+	for _, upload := range uploads {
+		if name != upload.ThumbName && name != upload.FullName {
+			continue
+		}
+		switch {
+		case upload.Status == ImageUploadApproved:
+			blobName, cacheControl = name, "public, max-age=31536000, immutable"
+		case upload.Status == ImageUploadPending && IsAdmin(r) && blobName == "":
+			blobName, cacheControl = PendingBlobName(name), "private, no-store"
+		}
+	}
+	if blobName == "" {
+		return PiErrorf(http.StatusNotFound, "No picture %q", name)
+	}
+	data, err := blobStore.Get(ctx, blobName)
+	if err == ErrBlobNotFound {
+		return PiErrorf(http.StatusNotFound, "No picture %q", name)
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", mime.TypeByExtension(filepath.Ext(name)))
+	w.Header().Set("Cache-Control", cacheControl)
+	_, err = w.Write(data)
+	return err
+}
+
+// AdminImageUploadsFacade is the Facade for the pictures moderation page.
+type AdminImageUploadsFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Pending     []ImageUpload
+}
+
+// Handle /admin-image-uploads
+func adminImageUploads(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	var pending []ImageUpload
+	_, err := datastore.NewQuery("ImageUpload").
+		Filter("Status =", ImageUploadPending).
+		Order("UploadDate").
+		Limit(100).
+		GetAll(ctx, &pending)
+	if err != nil {
+		return err
+	}
+
+	data := &AdminImageUploadsFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Pictures moderation",
+			ExtraCss:  []string{hostPrefix() + themeDirectory() + "/css/admin.css"},
+			Toggles:   toggles,
+		},
+		UserProfile: readUserProfile(r),
+		Pending:     pending,
+	}
+	return templates.ExecuteTemplate(w, "page-admin-image-uploads", data)
+}
+
+// Handle /admin-image-upload-decide
+// decision is "approve" or "reject".
+func adminImageUploadDecide(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+	ctx := r.Context()
+	keyName := r.FormValue("upload")
+	key := newImageUploadKey(ctx, keyName)
+	var upload ImageUpload
+	err := datastore.Get(ctx, key, &upload)
+	if err == datastore.ErrNoSuchEntity {
+		return PiErrorf(http.StatusNotFound, "Picture upload %q no longer exists", keyName)
+	}
+	if err != nil {
+		log.Errorf(ctx, "retrieving ImageUpload: %v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve picture :(")
+	}
+	if upload.Status != ImageUploadPending {
+		return PiErrorf(http.StatusConflict, "Picture already %s by %s", upload.Status, upload.Decider)
+	}
+
+	decider := "admin"
+	if u := user.Current(ctx); u != nil {
+		decider = u.String()
+	}
+	switch r.FormValue("decision") {
+	case "approve":
+		idiomKey, idiom, err := dao.getIdiom(ctx, upload.IdiomID)
+		if err != nil {
+			return PiErrorf(http.StatusNotFound, "Could not find idiom %d", upload.IdiomID)
+		}
+		if err := publishImageUpload(ctx, &upload); err != nil {
+			return err
+		}
+		if err := applyImageUpload(ctx, idiomKey, idiom, &upload, upload.Uploader); err != nil {
+			return err
+		}
+		upload.Status = ImageUploadApproved
+	case "reject":
+		deletePendingBlobs(ctx, &upload)
+		upload.Status = ImageUploadRejected
+	default:
+		return PiErrorf(http.StatusBadRequest, "Unknown decision %q", r.FormValue("decision"))
+	}
+
+	upload.Decider = decider
+	upload.DecisionDate = time.Now()
+	if _, err = datastore.Put(ctx, key, &upload); err != nil {
+		log.Errorf(ctx, "saving ImageUpload: %v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not save decision :(")
+	}
+	http.Redirect(w, r, hostPrefix()+"/admin-image-uploads", http.StatusFound)
+	return nil
+}
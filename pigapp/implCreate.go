@@ -15,6 +15,8 @@ type ImplCreateFacade struct {
 	UserProfile            UserProfile
 	Idiom                  *Idiom
 	LanguageSingleSelector LanguageSingleSelector
+	// Draft, if the form is filled with a draft.
+	Draft *Draft
 }
 
 func implCreate(w http.ResponseWriter, r *http.Request) error {
@@ -33,6 +35,17 @@ func implCreate(w http.ResponseWriter, r *http.Request) error {
 		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
 	}
 
+	draft, err := lookForDraft(r, idiomID)
+	if err != nil {
+		return err
+	}
+	if draft != nil {
+		if draft.Kind != DraftNewImpl {
+			return PiErrorf(http.StatusBadRequest, "Draft %s is not a new implementation", draft.ID)
+		}
+		preSelectedLanguage = draft.LanguageName
+	}
+
 	// This alters the idiom content in the Facade only
 	implFavoriteLanguagesFirstWithOrder(idiom, userProfile.FavoriteLanguages, "", userProfile.SeeNonFavorite)
 
@@ -51,6 +64,7 @@ func implCreate(w http.ResponseWriter, r *http.Request) error {
 			FieldName: "impl_language",
 			Selected:  preSelectedLanguage,
 		},
+		Draft: draft,
 	}
 
 	return templates.ExecuteTemplate(w, "page-impl-create", data)
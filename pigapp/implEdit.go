@@ -15,6 +15,8 @@ type ImplEditFacade struct {
 	UserProfile UserProfile
 	Idiom       *Idiom
 	Impl        *Impl
+	// Draft, if the form is filled with a draft.
+	Draft *Draft
 }
 
 func implEdit(w http.ResponseWriter, r *http.Request) error {
@@ -33,6 +35,19 @@ func implEdit(w http.ResponseWriter, r *http.Request) error {
 		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
 	}
 
+	draft, err := lookForDraft(r, idiomID)
+	if err != nil {
+		return err
+	}
+	if draft != nil {
+		if draft.Kind != DraftImpl || draft.ImplID != implID {
+			return PiErrorf(http.StatusBadRequest, "Draft %s is not about implementation %q", draft.ID, implIDStr)
+		}
+		if _, err := draft.Apply(idiom); err != nil {
+			return PiErrorf(http.StatusConflict, "Can't apply draft: %v", err)
+		}
+	}
+
 	_, impl, exists := idiom.FindImplInIdiom(implID)
 	if !exists {
 		return PiErrorf(http.StatusNotFound, "Could not find implementation %q in idiom %q", implIDStr, idiomIDStr)
@@ -56,6 +71,7 @@ func implEdit(w http.ResponseWriter, r *http.Request) error {
 		UserProfile: readUserProfile(r),
 		Idiom:       idiom,
 		Impl:        &implCopy,
+		Draft:       draft,
 	}
 
 	return templates.ExecuteTemplate(w, "page-impl-edit", data)
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/user"
+)
+
+//
+// An admin may move an impl to a more appropriate idiom, or copy it.
+// A moved impl keeps its ID: its former URL redirects to its new idiom.
+//
+
+// Handle /admin-impl-move-ajax
+func adminImplMoveAjax(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	idiomIDStr, targetIDStr := r.FormValue("idiomId"), r.FormValue("targetId")
+	implID := String2Int(r.FormValue("implId"))
+	mode := r.FormValue("mode")
+	if mode == "" {
+		mode = "move"
+	}
+	if mode != "move" && mode != "copy" {
+		return PiErrorf(http.StatusBadRequest, "Unknown mode %q", mode)
+	}
+
+	sourceKey, source, err := dao.getIdiom(ctx, String2Int(idiomIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+	targetKey, target, err := dao.getIdiom(ctx, String2Int(targetIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", targetIDStr)
+	}
+	if target.Protected {
+		return PiErrorf(http.StatusBadRequest, "Idiom %d is protected", target.Id)
+	}
+
+	editor := "admin"
+	if u := user.Current(ctx); u != nil {
+		editor = u.String()
+	}
+
+	var impl *Impl
+	done := "moved"
+	if mode == "move" {
+		impl, err = moveImpl(ctx, sourceKey, source, targetKey, target, implID, editor)
+	} else {
+		impl, err = copyImpl(ctx, source, targetKey, target, implID, editor)
+		done = "copied"
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, Response{
+		"message": fmt.Sprintf("Impl %d %s to idiom %d", implID, done, target.Id),
+		"url":     NiceImplURL(target, impl.Id, impl.LanguageName),
+	})
+	return nil
+}
+
+func moveImpl(ctx context.Context, sourceKey *datastore.Key, source *Idiom, targetKey *datastore.Key, target *Idiom, implID int, editor string) (*Impl, error) {
+	_, impl, found := source.FindImplInIdiom(implID)
+	if !found {
+		return nil, PiErrorf(http.StatusNotFound, "Could not find impl %d in idiom %d", implID, source.Id)
+	}
+	oldURL := NiceImplRelativeURL(source, implID, impl.LanguageName)
+
+	// Both idioms in a single cross-group transaction, so that the impl
+	// is never lost nor duplicated when one of the saves fails.
+	var moved *Impl
+	var newSource, newTarget Idiom
+	err := datastore.RunInTransaction(ctx, func(ctx context.Context) error {
+		newSource, newTarget = Idiom{}, Idiom{}
+		if err := datastore.Get(ctx, sourceKey, &newSource); err != nil {
+			return err
+		}
+		if err := datastore.Get(ctx, targetKey, &newTarget); err != nil {
+			return err
+		}
+		var err error
+		moved, err = newSource.MoveImpl(implID, &newTarget)
+		if err != nil {
+			return PiErrorf(http.StatusBadRequest, "%v", err)
+		}
+		newSource.LastEditor = editor
+		newTarget.LastEditor = editor
+		if err := dao.GaeDatastoreAccessor.saveExistingIdiom(ctx, sourceKey, &newSource); err != nil {
+			return err
+		}
+		return dao.GaeDatastoreAccessor.saveExistingIdiom(ctx, targetKey, &newTarget)
+	}, &datastore.TransactionOptions{XG: true})
+	if err != nil {
+		return nil, err
+	}
+	// The caches, only once committed. The old source is needed to evict the old paths.
+	logIf(dao.uncacheIdiom(ctx, source), log.Errorf, ctx, "uncaching source idiom")
+	logIf(dao.uncacheIdiom(ctx, &newTarget), log.Errorf, ctx, "uncaching target idiom")
+
+	if err := unindexImpl(ctx, source.Id, implID); err != nil {
+		log.Errorf(ctx, "Unindexing impl %d from idiom %d: %v", implID, source.Id, err)
+		// But keep going
+	}
+	if err := daoVotes.moveImplVotes(ctx, implID, target.Id); err != nil {
+		log.Errorf(ctx, "Moving votes of impl %d to idiom %d: %v", implID, target.Id, err)
+	}
+	htmlCacheEvict(ctx, oldURL)
+	log.Infof(ctx, "[%s] moved impl %d from idiom %d to idiom %d", editor, implID, source.Id, target.Id)
+	return moved, nil
+}
+
+func copyImpl(ctx context.Context, source *Idiom, targetKey *datastore.Key, target *Idiom, implID int, editor string) (*Impl, error) {
+	newImplID, err := dao.nextImplID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	copied, err := source.CopyImpl(implID, target, newImplID)
+	if err != nil {
+		return nil, PiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	target.LastEditor = editor
+	if err := dao.saveExistingIdiom(ctx, targetKey, target); err != nil {
+		return nil, err
+	}
+	log.Infof(ctx, "[%s] copied impl %d from idiom %d to idiom %d, as impl %d", editor, implID, source.Id, target.Id, newImplID)
+	return copied, nil
+}
+
+// movedImplURL is the URL of the impl implID, when it has moved out of idiom idiomID.
+func movedImplURL(ctx context.Context, idiomID, implID int) (string, bool) {
+	_, idiom, err := dao.getIdiomByImplID(ctx, implID)
+	if err != nil || idiom.Id == idiomID {
+		return "", false
+	}
+	_, impl, _ := idiom.FindImplInIdiom(implID)
+	return NiceImplURL(idiom, impl.Id, impl.LanguageName), true
+}
@@ -48,6 +48,9 @@ func newImplSave(w http.ResponseWriter, r *http.Request, username string, idiomI
 	attributionURL := r.FormValue("impl_attribution_url")
 	demoURL := r.FormValue("impl_demo_url")
 	docURL := r.FormValue("impl_doc_url")
+	minVersion := r.FormValue("impl_min_version")
+	dialect := r.FormValue("impl_dialect")
+	expectedOutput := TruncateBytes(NoCR(r.FormValue("impl_expected_output")), 500)
 	editSummary := fmt.Sprintf("New %s implementation by user [%s]", PrintNiceLang(language), username)
 
 	trim := strings.TrimSpace
@@ -57,11 +60,16 @@ func newImplSave(w http.ResponseWriter, r *http.Request, username string, idiomI
 	attributionURL = trim(Truncate(attributionURL, 250))
 	demoURL = trim(Truncate(demoURL, 250))
 	docURL = trim(Truncate(docURL, 250))
+	minVersion = trim(Truncate(minVersion, 30))
+	dialect = trim(Truncate(dialect, 30))
 
 	log.Infof(ctx, "[%s] is creating new %s impl for idiom %v", username, PrintNiceLang(language), idiomIDStr)
 
 	if !StringSliceContains(AllLanguages(), language) {
-		return PiErrorf(http.StatusBadRequest, "Sorry, [%v] is currently not a supported language. Supported languages are %v.", r.FormValue("impl_language"), AllNiceLangs)
+		return PiErrorf(http.StatusBadRequest, "Sorry, [%v] is currently not a supported language. Supported languages are %v.", r.FormValue("impl_language"), AllNiceLanguages())
+	}
+	if err := checkSnippetSyntax(language, imports, code); err != nil {
+		return err
 	}
 
 	idiomID := String2Int(idiomIDStr)
@@ -100,13 +108,23 @@ func newImplSave(w http.ResponseWriter, r *http.Request, username string, idiomI
 		OriginalAttributionURL: attributionURL,
 		DemoURL:                demoURL,
 		DocumentationURL:       docURL,
+		MinVersion:             minVersion,
+		Dialect:                dialect,
+		ExpectedOutput:         expectedOutput,
 		Version:                1,
 		VersionDate:            now,
+		Checked:                IsAdmin(r),
+		VerifiedExamples:       idiom.HasExamples() && r.FormValue("impl_verified_examples") != "",
 	}
 
 	if IsAdmin(r) {
 		// 2016-10: only Admin may set an impl picture
 		newImpl.PictureURL = r.FormValue("impl_picture_url")
+		// Only Admin may import a snippet under another license
+		newImpl.License, newImpl.AttributionSource, newImpl.AttributionDate, err = readLicenseFields(r, "impl_")
+		if err != nil {
+			return err
+		}
 	}
 
 	idiom.Implementations = append(idiom.Implementations, newImpl)
@@ -117,6 +135,9 @@ func newImplSave(w http.ResponseWriter, r *http.Request, username string, idiomI
 	if err != nil {
 		return err
 	}
+	enqueueReview(ctx, r, idiom, &idiom.Implementations[len(idiom.Implementations)-1])
+	warnVariablesLint(ctx, username, idiom, &idiom.Implementations[len(idiom.Implementations)-1])
+	discardDraft(ctx, r)
 
 	http.Redirect(w, r, NiceImplURL(idiom, implID, language), http.StatusFound)
 	return nil
@@ -137,6 +158,9 @@ func existingImplSave(w http.ResponseWriter, r *http.Request, username string, i
 	attributionURL := r.FormValue("impl_attribution_url")
 	demoURL := r.FormValue("impl_demo_url")
 	docURL := r.FormValue("impl_doc_url")
+	minVersion := r.FormValue("impl_min_version")
+	dialect := r.FormValue("impl_dialect")
+	expectedOutput := TruncateBytes(NoCR(r.FormValue("impl_expected_output")), 500)
 
 	trim := strings.TrimSpace
 	imports = trim(Truncate(imports, 200))
@@ -145,6 +169,8 @@ func existingImplSave(w http.ResponseWriter, r *http.Request, username string, i
 	attributionURL = trim(Truncate(attributionURL, 250))
 	demoURL = trim(Truncate(demoURL, 250))
 	docURL = trim(Truncate(docURL, 250))
+	minVersion = trim(Truncate(minVersion, 30))
+	dialect = trim(Truncate(dialect, 30))
 
 	log.Infof(ctx, "[%s] is updating impl %s of idiom %s", username, existingImplIDStr, idiomIDStr)
 
@@ -165,6 +191,10 @@ func existingImplSave(w http.ResponseWriter, r *http.Request, username string, i
 
 	_, impl, _ := idiom.FindImplInIdiom(implID)
 
+	if err := checkSnippetSyntax(impl.LanguageName, imports, code); err != nil {
+		return err
+	}
+
 	isAdmin := IsAdmin(r)
 	if idiom.Protected && !isAdmin {
 		return PiErrorf(http.StatusUnauthorized, "Can't edit protected idiom %q", idiomIDStr)
@@ -172,20 +202,46 @@ func existingImplSave(w http.ResponseWriter, r *http.Request, username string, i
 	if impl.Protected && !isAdmin {
 		return PiErrorf(http.StatusUnauthorized, "Can't edit protected impl %q", existingImplIDStr)
 	}
-	if isAdmin {
-		wasProtected := impl.Protected
-		impl.Protected = r.FormValue("impl_protected") != ""
-
-		if wasProtected && !impl.Protected {
-			log.Infof(ctx, "[%v] unprotects impl %v of idiom %v", username, existingImplIDStr, idiomIDStr)
+	if r.FormValue("impl_version") != strconv.Itoa(impl.Version) {
+		log.Infof(ctx, "Impl has been concurrently modified (editing version %v, current version is %v)", r.FormValue("impl_version"), impl.Version)
+		mine := &Impl{
+			ImportsBlock:           imports,
+			CodeBlock:              code,
+			AuthorComment:          comment,
+			OriginalAttributionURL: attributionURL,
+			DemoURL:                demoURL,
+			DocumentationURL:       docURL,
+			MinVersion:             minVersion,
+			Dialect:                dialect,
+			ExpectedOutput:         expectedOutput,
+			VerifiedExamples:       r.FormValue("impl_verified_examples") != "",
 		}
-		if !wasProtected && impl.Protected {
-			log.Infof(ctx, "[%v] protects impl %v of idiom %v", username, existingImplIDStr, idiomIDStr)
+		if isAdmin {
+			if err := readImplAdminFields(r, mine); err != nil {
+				return err
+			}
+		}
+		merges := mergeImplEdit(ctx, r, idiom, impl, mine, isAdmin)
+		if merges.HasConflict() {
+			return showMergeConflicts(w, r, idiom, impl, merges)
+		}
+		merged := merges.Merged()
+		// The checkboxes and the admin fields below are read from the form
+		for name, value := range merged {
+			r.Form.Set(name, value)
+		}
+		imports = merged["impl_imports"]
+		code = merged["impl_code"]
+		comment = merged["impl_comment"]
+		attributionURL = merged["impl_attribution_url"]
+		demoURL = merged["impl_demo_url"]
+		docURL = merged["impl_doc_url"]
+		minVersion = merged["impl_min_version"]
+		dialect = merged["impl_dialect"]
+		expectedOutput = merged["impl_expected_output"]
+		if err := checkSnippetSyntax(impl.LanguageName, imports, code); err != nil {
+			return err
 		}
-	}
-
-	if r.FormValue("impl_version") != strconv.Itoa(impl.Version) {
-		return PiErrorf(http.StatusConflict, "Implementation has been concurrently modified (editing version %v, current version is %v)", r.FormValue("impl_version"), impl.Version)
 	}
 
 	if err := validateURLFormatOrEmpty(attributionURL); err != nil {
@@ -205,19 +261,49 @@ func existingImplSave(w http.ResponseWriter, r *http.Request, username string, i
 	impl.OriginalAttributionURL = attributionURL
 	impl.DemoURL = demoURL
 	impl.DocumentationURL = docURL
+	impl.MinVersion = minVersion
+	impl.Dialect = dialect
+	impl.ExpectedOutput = expectedOutput
+	// The last execution result is obsolete
+	impl.RunStatus = ""
+	impl.RunDate = time.Time{}
 	impl.Version = impl.Version + 1
 	impl.VersionDate = time.Now()
+	impl.Checked = isAdmin
+	impl.VerifiedExamples = idiom.HasExamples() && r.FormValue("impl_verified_examples") != ""
 
 	if isAdmin {
-		// 2016-10: only Admin may set an impl picture
-		impl.PictureURL = r.FormValue("impl_picture_url")
+		wasProtected := impl.Protected
+		if err := readImplAdminFields(r, impl); err != nil {
+			return err
+		}
+		if wasProtected && !impl.Protected {
+			log.Infof(ctx, "[%v] unprotects impl %v of idiom %v", username, existingImplIDStr, idiomIDStr)
+		}
+		if !wasProtected && impl.Protected {
+			log.Infof(ctx, "[%v] protects impl %v of idiom %v", username, existingImplIDStr, idiomIDStr)
+		}
 	}
 
 	err = dao.saveExistingIdiom(ctx, key, idiom)
 	if err != nil {
 		return err
 	}
+	enqueueReview(ctx, r, idiom, impl)
+	warnVariablesLint(ctx, username, idiom, impl)
+	discardDraft(ctx, r)
 
 	http.Redirect(w, r, NiceImplURL(idiom, implID, impl.LanguageName), http.StatusFound)
 	return nil
 }
+
+// readImplAdminFields reads the fields of the impl edit form that only an admin may set.
+func readImplAdminFields(r *http.Request, impl *Impl) error {
+	impl.Protected = r.FormValue("impl_protected") != ""
+	// 2016-10: only Admin may set an impl picture
+	impl.PictureURL = r.FormValue("impl_picture_url")
+	// Only Admin may import a snippet under another license
+	var err error
+	impl.License, impl.AttributionSource, impl.AttributionDate, err = readLicenseFields(r, "impl_")
+	return err
+}
@@ -5,8 +5,11 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
+	. "github.com/Deleplace/programming-idioms/pig"
+
 	"github.com/gorilla/mux"
 
 	"context"
@@ -59,32 +62,60 @@ func initRoutes() {
 		handle("/random-idiom", randomIdiom)
 		handle("/search", searchRedirect)
 		handle("/search/{q}", search)
+		handle("/search-code", searchCode)
 		handle("/list-by-language/{langs}", listByLanguage)
+		handle("/tags", tagIndex)
+		handle("/tag/{name}", tagPage)
 		handle("/missing-fields/{lang}", missingList)
+		handle("/broken-links/{lang}", brokenLinks)
+		handle("/playground-go/{idiomId}/{implId}", playgroundGo)
+		handle("/variables-lint", variablesLint)
+		handle("/set-locale/{locale}", setLocale)
+		handle("/needs-translation/{locale}", needsTranslation)
+		handle("/translation-history/{idiomId}/{locale}", translationHistory)
 		handle("/idiom-picture", idiomPicture)
+		handle("/image/{name}", serveImage)
 		handle("/rss-recently-created", rssRecentlyCreated)
 		handle("/rss-recently-updated", rssRecentlyUpdated)
 		handle("/rss-recent-changes", rssRecentChanges)
+		handle("/rss-recent-discussions", rssRecentDiscussions)
+		handle("/discussion/{idiomId}", discussion)
+		handle("/discussion/{idiomId}/impl/{implId}", discussion)
+		handle("/recent-discussions", recentDiscussions)
+		handle("/draft/{draftId}", draftPreview)
+		handle("/my-drafts", myDrafts)
+		handle("/guid/idiom/{idiomId}", guidRedirect)
+		handle("/guid/idiom/{idiomId}/version/{version}", guidRedirect)
 		handle("/my/{nickname}/{langs}", bookmarkableUserURL)
 		handle("/my/{langs}", bookmarkableUserURL)
+		handle("/my-versions", myVersions)
 		handle("/cheatsheet/{lang}", cheatsheet)
 		handle("/cheatsheet/{lang1}/{lang2}", cheatsheetDouble)
 		handleAjax("/typeahead-languages", typeaheadLanguages)
 		handleAjax("/supported-languages", supportedLanguages)
 		handleAjax("/ajax-other-implementations", ajaxOtherImplementations)
 		handleAjax("/ajax-impl-flag/{idiomId}/{implId}", ajaxImplFlag)
+		handleAjax("/ajax-validate-syntax", ajaxValidateSyntax)
+		handleAjax("/ajax-markup-preview", ajaxMarkupPreview)
 		if toggles["writable"] {
 			// When not in "read-only" mode
 			handle("/idiom-save", idiomSave)
 			handle("/idiom-edit/{idiomId}", idiomEdit)
 			handle("/idiom-add-picture/{idiomId}", idiomAddPicture)
 			handle("/idiom-save-picture", idiomSavePicture)
+			handle("/image-upload", imageUpload)
+			handle("/discussion-post", discussionPost)
+			handle("/draft-save", draftSave)
+			handle("/draft-publish", draftPublish)
+			handle("/draft-delete", draftDelete)
 			handle("/impl-edit/{idiomId}/{implId}", implEdit)
 			//handle("/fake-idiom-save", fakeIdiomSave)
 			handle("/idiom-create", idiomCreate)
 			handle("/impl-create/{idiomId}", implCreate)
 			handle("/impl-create/{idiomId}/{lang}", implCreate)
 			handle("/impl-save", implSave)
+			handle("/translate/{idiomId}/{locale}", translationEdit)
+			handle("/translation-save", translationSave)
 			// Ajax
 			handleAjax("/ajax-idiom-vote", ajaxIdiomVote)
 			handleAjax("/ajax-impl-vote", ajaxImplVote)
@@ -104,9 +135,31 @@ func initRoutes() {
 			handle("/admin-data-import", adminImport)
 			handle("/admin-resave-entities", adminResaveEntities)
 			handle("/admin-flagged", adminListFlaggedContent)
+			handle("/admin-languages", adminLanguages)
+			handle("/admin-language-save", adminLanguageSave)
+			handle("/admin-reviews", adminReviews)
+			handle("/admin-review-decide", adminReviewDecide)
+			handle("/admin-broken-snippets", adminBrokenSnippets)
+			handle("/admin-invalid-snippets", adminInvalidSnippets)
+			handle("/admin-related-suggestions", adminRelatedSuggestions)
+			handle("/admin-related-suggestion-decide", adminRelatedSuggestionDecide)
+			handle("/admin-idiom-relations/{idiomId}", adminIdiomRelations)
+			handle("/admin-relation-save", adminRelationSave)
+			handle("/admin-relation-remove", adminRelationRemove)
+			handle("/admin-relation-move", adminRelationMove)
+			handle("/admin-relations-check", adminRelationsCheck)
+			handle("/admin-image-uploads", adminImageUploads)
+			handle("/admin-image-upload-decide", adminImageUploadDecide)
 			handleAjax("/admin-repair-history-versions", adminRepairHistoryVersions)
 			handleAjax("/admin-data-import-ajax", adminImportAjax)
 			handleAjax("/admin-reindex-ajax", adminReindexAjax)
+			handleAjax("/admin-run-snippets-ajax", adminRunSnippetsAjax)
+			handleAjax("/admin-check-links-ajax", adminCheckLinksAjax)
+			handleAjax("/admin-comment-moderate-ajax", adminCommentModerateAjax)
+			handleAjax("/admin-compute-related-ajax", adminComputeRelatedAjax)
+			handleAjax("/admin-relations-repair-ajax", adminRelationsRepairAjax)
+			handleAjax("/admin-idiom-merge-ajax", adminIdiomMergeAjax)
+			handleAjax("/admin-impl-move-ajax", adminImplMoveAjax)
 			handleAjax("/admin-refresh-toggles-ajax", ajaxRefreshToggles)
 			handleAjax("/admin-set-toggle-ajax", ajaxSetToggle)
 			handleAjax("/admin-create-relation-ajax", ajaxCreateRelation)
@@ -115,20 +168,29 @@ func initRoutes() {
 			handleAjax("/admin-send-message-for-user", sendMessageForUserAjax)
 			handleAjax("/admin-flag-resolve", ajaxAdminFlagResolve)
 			handleAjax("/admin-memcache-flush", ajaxAdminMemcacheFlush)
+			handleAjax("/admin-language-delete", ajaxAdminLanguageDelete)
 		}
 		handleAjax("/api/idiom/{idiomId}", jsonIdiom)
+		handleAjax("/api/idiom/{idiomId}/diff/{v1}/{v2}", jsonVersionDiff)
+		handleAjax("/api/idiom/{idiomId}/impl/{implId}/diff/{v1}/{v2}", jsonVersionDiff)
 		handleAjax("/api/idioms/all", jsonAllIdioms)
 		handleAjax("/api/search/{q}", jsonSearch)
+		handleAjax("/api/search-code", jsonSearchCode)
+		handleAjax("/api/tags", jsonTags)
+		handleAjax("/api/tag/{name}", jsonTag)
+		handleAjax("/api/attribution", jsonAttribution)
 		r.PathPrefix("/using/").HandlerFunc(using)
 
+		handle("/attribution", attributionNotice)
 		handle("/auth", handleAuth)
 		handle("/_ah/login_required", handleAuth)
 	}
-	http.Handle("/", r)
+	http.Handle("/", withLocalePrefix(r))
 }
 
 // Request will fail if path parameters are missing
 var neededPathVariables = map[string][]string{
+	"/playground-go/{idiomId}/{implId}":                 {"idiomId", "implId"},
 	"/idiom/{idiomId}":                                  {"idiomId"},
 	"/idiom/{idiomId}/impl/{implId}":                    {"idiomId"},
 	"/idiom/{idiomId}/{idiomTitle}":                     {"idiomId"},
@@ -141,55 +203,150 @@ var neededPathVariables = map[string][]string{
 	"/impl-create/{idiomId}":                            {"idiomId"},
 	"/impl-create/{idiomId}/{lang}":                     {"idiomId"},
 	"/cheatsheet/{lang}":                                {"lang"},
+	"/tag/{name}":                                       {"name"},
+	"/image/{name}":                                     {"name"},
+	"/discussion/{idiomId}":                             {"idiomId"},
+	"/discussion/{idiomId}/impl/{implId}":               {"idiomId", "implId"},
+	"/draft/{draftId}":                                  {"draftId"},
+	"/api/tag/{name}":                                   {"name"},
+	"/admin-idiom-relations/{idiomId}":                  {"idiomId"},
+	"/guid/idiom/{idiomId}":                             {"idiomId"},
+	"/guid/idiom/{idiomId}/version/{version}":           {"idiomId"},
 }
 
 // Request will fail if it doesn't provide the required GET or POST parameters
 var neededParameters = map[string][]string{
-	"/typeahead-languages":         { /*todo*/ },
-	"/idiom-save":                  {"idiom_title"},
-	"/idiom-save-picture":          { /*todo*/ },
-	"/impl-save":                   {"idiom_id", "impl_code"},
-	"/revert":                      {"idiomId", "version"},
-	"/ajax-idiom-vote":             {"idiomId", "choice"},
-	"/ajax-impl-vote":              {"implId", "choice"},
-	"/ajax-demo-site-suggest":      { /*todo*/ },
-	"/ajax-dismiss-user-message":   {"key"},
-	"/admin-data-export":           { /*todo*/ },
-	"/admin-data-import":           { /*todo*/ },
-	"/admin-data-import-ajax":      { /*todo*/ },
-	"/admin-set-toggle-ajax":       {"toggle", "value"},
-	"/admin-create-relation-ajax":  {"idiomAId", "idiomBId"},
-	"/admin-idiom-delete":          {"idiomId"},
-	"/admin-impl-delete":           {"idiomId", "implId"},
-	"/admin-send-message-for-user": {"username", "message"},
-	"/admin-flag-resolve":          {"flagkey"},
-	"/api/idiom":                   {"idiomId"},
+	"/typeahead-languages":             { /*todo*/ },
+	"/idiom-save":                      {"idiom_title"},
+	"/idiom-save-picture":              { /*todo*/ },
+	"/impl-save":                       {"idiom_id", "impl_code"},
+	"/translation-save":                {"idiom_id", "locale", "translation_version"},
+	"/revert":                          {"idiomId", "version"},
+	"/ajax-idiom-vote":                 {"idiomId", "choice"},
+	"/ajax-impl-vote":                  {"implId", "choice"},
+	"/ajax-demo-site-suggest":          { /*todo*/ },
+	"/ajax-dismiss-user-message":       {"key"},
+	"/admin-data-export":               { /*todo*/ },
+	"/admin-data-import":               { /*todo*/ },
+	"/admin-data-import-ajax":          { /*todo*/ },
+	"/admin-set-toggle-ajax":           {"toggle", "value"},
+	"/admin-create-relation-ajax":      {"idiomAId", "idiomBId"},
+	"/admin-idiom-delete":              {"idiomId"},
+	"/admin-impl-delete":               {"idiomId", "implId"},
+	"/admin-send-message-for-user":     {"username", "message"},
+	"/admin-flag-resolve":              {"flagkey"},
+	"/admin-language-save":             {"lang_name"},
+	"/admin-language-delete":           {"name"},
+	"/admin-review-decide":             {"reviewkey", "decision"},
+	"/admin-related-suggestion-decide": {"suggestionkey", "decision"},
+	"/admin-image-upload-decide":       {"upload", "decision"},
+	"/image-upload":                    {"idiom_id"},
+	"/discussion-post":                 {"idiom_id", "comment_text"},
+	"/admin-comment-moderate-ajax":     {"key", "action"},
+	"/draft-save":                      {"idiom_id"},
+	"/draft-publish":                   {"draft_id"},
+	"/draft-delete":                    {"draft_id"},
+	"/admin-relation-save":             {"idiomId", "otherId", "type"},
+	"/admin-relation-remove":           {"idiomId", "otherId"},
+	"/admin-relation-move":             {"idiomId", "otherId", "delta"},
+	"/admin-idiom-merge-ajax":          {"sourceId", "targetId"},
+	"/admin-impl-move-ajax":            {"idiomId", "implId", "targetId"},
+	"/api/idiom":                       {"idiomId"},
 }
 
 // Request will fail if corresponding toggle is off
 var neededToggles = map[string][]string{
-	"/home":                         {"online"},
-	"/search":                       {"searchable"},
-	"/search/{q}":                   {"searchable"},
-	"/idiom-save":                   {"writable"},
-	"/idiom-edit/{idiomId}":         {"writable", "writable", "idiomEditing"},
-	"/idiom-add-picture/{idiomId}":  {"writable", "idiomEditing"},
-	"/idiom-save-picture":           {"writable", "idiomEditing"},
-	"/impl-edit/{idiomId}/{implId}": {"writable", "implEditing"},
-	"/idiom-create":                 {"writable"},
-	"/impl-create/{idiomId}":        {"writable", "implAddition"},
-	"/impl-create/{idiomId}/{lang}": {"writable", "implAddition"},
-	"/impl-save":                    {"writable"},
-	"/ajax-idiom-vote":              {"writable"},
-	"/ajax-impl-vote":               {"writable"},
-	"/admin":                        {"administrable"},
-	"/admin-data-export":            {"administrable"},
-	"/admin-data-import":            {"administrable"},
-	"/admin-data-import-ajax":       {"administrable"},
-	"/admin-set-toggle-ajax":        {"administrable"},
-	"/admin-create-relation-ajax":   {"administrable"},
-	"/admin-idiom-delete":           {"administrable"},
-	"/admin-impl-delete":            {"administrable"},
+	"/home":                                   {"online"},
+	"/search":                                 {"searchable"},
+	"/search/{q}":                             {"searchable"},
+	"/search-code":                            {"searchable"},
+	"/api/search-code":                        {"searchable"},
+	"/idiom-save":                             {"writable"},
+	"/idiom-edit/{idiomId}":                   {"writable", "writable", "idiomEditing"},
+	"/idiom-add-picture/{idiomId}":            {"writable", "idiomEditing"},
+	"/idiom-save-picture":                     {"writable", "idiomEditing"},
+	"/image-upload":                           {"writable", "pictureEditing"},
+	"/discussion/{idiomId}":                   {"discussions"},
+	"/discussion/{idiomId}/impl/{implId}":     {"discussions"},
+	"/discussion-post":                        {"writable", "discussions"},
+	"/recent-discussions":                     {"discussions"},
+	"/rss-recent-discussions":                 {"discussions"},
+	"/admin-comment-moderate-ajax":            {"administrable", "discussions"},
+	"/draft/{draftId}":                        {"drafts"},
+	"/my-drafts":                              {"drafts"},
+	"/draft-save":                             {"writable", "drafts"},
+	"/draft-publish":                          {"writable", "drafts"},
+	"/draft-delete":                           {"writable", "drafts"},
+	"/impl-edit/{idiomId}/{implId}":           {"writable", "implEditing"},
+	"/idiom-create":                           {"writable"},
+	"/impl-create/{idiomId}":                  {"writable", "implAddition"},
+	"/impl-create/{idiomId}/{lang}":           {"writable", "implAddition"},
+	"/impl-save":                              {"writable"},
+	"/ajax-idiom-vote":                        {"writable"},
+	"/ajax-impl-vote":                         {"writable"},
+	"/admin":                                  {"administrable"},
+	"/admin-data-export":                      {"administrable"},
+	"/admin-data-import":                      {"administrable"},
+	"/admin-data-import-ajax":                 {"administrable"},
+	"/admin-set-toggle-ajax":                  {"administrable"},
+	"/admin-create-relation-ajax":             {"administrable"},
+	"/admin-idiom-delete":                     {"administrable"},
+	"/admin-impl-delete":                      {"administrable"},
+	"/admin-languages":                        {"administrable"},
+	"/admin-language-save":                    {"administrable"},
+	"/admin-language-delete":                  {"administrable"},
+	"/admin-reviews":                          {"administrable"},
+	"/admin-review-decide":                    {"administrable"},
+	"/admin-broken-snippets":                  {"administrable", "snippetExecution"},
+	"/admin-run-snippets-ajax":                {"administrable", "snippetExecution"},
+	"/admin-check-links-ajax":                 {"administrable", "linkChecker"},
+	"/broken-links/{lang}":                    {"linkChecker"},
+	"/playground-go/{idiomId}/{implId}":       {"playgroundLinks"},
+	"/admin-invalid-snippets":                 {"administrable", "syntaxValidation"},
+	"/admin-related-suggestions":              {"administrable", "relatedSuggestions"},
+	"/admin-related-suggestion-decide":        {"administrable", "relatedSuggestions"},
+	"/admin-compute-related-ajax":             {"administrable", "relatedSuggestions"},
+	"/admin-idiom-relations/{idiomId}":        {"administrable"},
+	"/admin-relation-save":                    {"administrable"},
+	"/admin-relation-remove":                  {"administrable"},
+	"/admin-relation-move":                    {"administrable"},
+	"/admin-relations-check":                  {"administrable"},
+	"/admin-relations-repair-ajax":            {"administrable"},
+	"/admin-idiom-merge-ajax":                 {"administrable"},
+	"/admin-impl-move-ajax":                   {"administrable"},
+	"/admin-image-uploads":                    {"administrable"},
+	"/admin-image-upload-decide":              {"administrable"},
+	"/variables-lint":                         {"variablesLint"},
+	"/set-locale/{locale}":                    {"translations"},
+	"/needs-translation/{locale}":             {"translations"},
+	"/translation-history/{idiomId}/{locale}": {"translations"},
+	"/translate/{idiomId}/{locale}":           {"writable", "translations"},
+	"/translation-save":                       {"writable", "translations"},
+}
+
+// Request bodies larger than these are refused before any form parsing.
+var maxRequestBytes = map[string]int64{
+	"/image-upload": MaxImageUploadBytes + 64<<10,
+}
+
+// limitRequestBody enforces maxRequestBytes. It must run before the first
+// read of a form value, which parses the whole body.
+func limitRequestBody(w http.ResponseWriter, r *http.Request, path string) error {
+	max, ok := maxRequestBytes[path]
+	if !ok {
+		return nil
+	}
+	if r.ContentLength > max {
+		return PiErrorf(http.StatusRequestEntityTooLarge, "Request is too large (max %d kB)", max>>10)
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, max)
+	return nil
+}
+
+// isAdminPath tells if path is an admin route. Those are protected by app.yaml,
+// and checked again by handle and handleAjax.
+func isAdminPath(path string) bool {
+	return strings.HasPrefix(path, "/admin")
 }
 
 type standardHandler func(w http.ResponseWriter, r *http.Request)
@@ -200,9 +357,20 @@ type betterHandler func(w http.ResponseWriter, r *http.Request) error
 // - mandatory path variables check
 // - mandatory parameters check
 // - toggles check
+// - admin check, for the admin routes
+// - request size check
 func handle(path string, h betterHandler) {
+	adminOnly := isAdminPath(path)
 	r.HandleFunc(path,
 		func(w http.ResponseWriter, r *http.Request) {
+			if adminOnly && !IsAdmin(r) {
+				errorPage(w, r, PiErrorf(http.StatusForbidden, "Admin only"))
+				return
+			}
+			if err := limitRequestBody(w, r, path); err != nil {
+				errorPage(w, r, err)
+				return
+			}
 			if isSpam(w, r) {
 				return
 			}
@@ -218,6 +386,7 @@ func handle(path string, h betterHandler) {
 				_ = refreshToggles(ctx)
 				// If it fails... well, ignore for now and continue with non-fresh toggles.
 			}
+			refreshLanguagesIfStale(r.Context())
 
 			if err := muxVarsMissing(w, r, neededPathVariables[path]...); err != nil {
 				errorPage(w, r, err)
@@ -239,8 +408,17 @@ func handle(path string, h betterHandler) {
 }
 
 func handleAjax(path string, h betterHandler) {
+	adminOnly := isAdminPath(path)
 	r.HandleFunc(path,
 		func(w http.ResponseWriter, r *http.Request) {
+			if adminOnly && !IsAdmin(r) {
+				errorJSON(w, r, PiErrorf(http.StatusForbidden, "Admin only"))
+				return
+			}
+			if err := limitRequestBody(w, r, path); err != nil {
+				errorJSON(w, r, err)
+				return
+			}
 			if isSpam(w, r) {
 				return
 			}
@@ -256,6 +434,7 @@ func handleAjax(path string, h betterHandler) {
 				_ = refreshToggles(ctx)
 				// If it fails... well, ignore for now and continue with non-fresh toggles.
 			}
+			refreshLanguagesIfStale(r.Context())
 
 			if err := muxVarsMissing(w, r, neededPathVariables[path]...); err != nil {
 				errorJSON(w, r, err)
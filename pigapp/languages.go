@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"context"
+
+	"google.golang.org/appengine/log"
+)
+
+//
+// This file is about the data-driven list of supported languages.
+// Languages are persisted as Language entities, and managed by admins.
+//
+
+// languagesStampKey is the cache stamp bumped each time a language is saved
+// or deleted, so that all the instances reload the languages.
+const languagesStampKey = "languagesStamp"
+
+// languagesStamp is the cache stamp of the languages loaded in this instance.
+// Accessed atomically.
+var languagesStamp uint64
+
+// refreshLanguagesIfStale reloads the languages, if they were modified
+// since this instance loaded them.
+func refreshLanguagesIfStale(ctx context.Context) {
+	stamp, err := cacheStamp(ctx, languagesStampKey)
+	if err != nil {
+		log.Warningf(ctx, "Reading %s: %v", languagesStampKey, err)
+		// Not 0: don't reload at each request while memcache is unavailable
+		stamp = 1
+	}
+	if stamp == atomic.LoadUint64(&languagesStamp) {
+		return
+	}
+	if err := refreshLanguages(ctx); err != nil {
+		// Well, ignore for now and continue with non-fresh languages.
+		return
+	}
+	atomic.StoreUint64(&languagesStamp, stamp)
+}
+
+func refreshLanguages(ctx context.Context) error {
+	langs, err := dao.getLanguages(ctx)
+	if err == languageNotFound {
+		// Nothing in Memcache, nothing in Datastore!
+		// Then, persist the default (hard-coded) languages.
+		langs = DefaultLanguages()
+		log.Infof(ctx, "Saving default Languages to Datastore...")
+		err = dao.saveLanguages(ctx, langs)
+		if err != nil {
+			return err
+		}
+		log.Infof(ctx, "Default Languages saved to Datastore.")
+	}
+	if err != nil {
+		log.Errorf(ctx, "Error while loading Languages from datastore: %v", err)
+		return err
+	}
+	SetLanguages(langs)
+	log.Infof(ctx, "Updated Languages from memcached or datastore\n")
+	return nil
+}
+
+// AdminLanguagesFacade is the Facade for the Languages admin page.
+type AdminLanguagesFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Languages   []*Language
+	// Edited is the Language in the form. Empty for a creation.
+	Edited *Language
+}
+
+func adminLanguages(w http.ResponseWriter, r *http.Request) error {
+	edited := &Language{}
+	if name := r.FormValue("name"); name != "" {
+		edited = FindLanguage(name)
+		if edited == nil {
+			return PiErrorf(http.StatusNotFound, "Language %q not found", name)
+		}
+	}
+
+	data := &AdminLanguagesFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Languages",
+			ExtraCss:  []string{hostPrefix() + themeDirectory() + "/css/admin.css"},
+			ExtraJs:   []string{hostPrefix() + themeDirectory() + "/js/programming-idioms-admin.js"},
+			Toggles:   toggles,
+		},
+		UserProfile: readUserProfile(r),
+		Languages:   Languages(),
+		Edited:      edited,
+	}
+	return templates.ExecuteTemplate(w, "page-admin-languages", data)
+}
+
+var regexpLanguageName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_+#-]*$`)
+
+func adminLanguageSave(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+
+	name := strings.TrimSpace(r.FormValue("lang_name"))
+	if !regexpLanguageName.MatchString(name) {
+		return PiErrorf(http.StatusBadRequest, "Invalid language name %q", name)
+	}
+	existing := FindLanguage(name)
+	if existing == nil || existing.Name != name {
+		if !toggles["languageCreation"] {
+			return PiErrorf(http.StatusForbidden, "Language creation is disabled (toggle languageCreation).")
+		}
+		if existing != nil {
+			return PiErrorf(http.StatusConflict, "%q is already an alias of language %q", name, existing.Name)
+		}
+	}
+
+	lang := &Language{
+		Name:                  name,
+		NiceName:              strings.TrimSpace(r.FormValue("lang_nice_name")),
+		Aliases:               splitCommaList(r.FormValue("lang_aliases")),
+		Extensions:            splitCommaList(r.FormValue("lang_extensions")),
+		Keywords:              splitCommaList(r.FormValue("lang_keywords")),
+		HighlighterClass:      strings.TrimSpace(r.FormValue("lang_highlighter_class")),
+		HighlighterScript:     strings.TrimSpace(r.FormValue("lang_highlighter_script")),
+		DocURLTemplate:        strings.TrimSpace(r.FormValue("lang_doc_url_template")),
+		PlaygroundURLTemplate: strings.TrimSpace(r.FormValue("lang_playground_url_template")),
+		Mainstream:            r.FormValue("lang_mainstream") != "",
+		Similar:               splitCommaList(r.FormValue("lang_similar")),
+	}
+	for _, alias := range lang.Aliases {
+		if other := FindLanguage(alias); other != nil && other.Name != name {
+			return PiErrorf(http.StatusConflict, "Alias %q already designates language %q", alias, other.Name)
+		}
+	}
+	for i, similar := range lang.Similar {
+		other := FindLanguage(similar)
+		if other == nil || other.Name == name {
+			return PiErrorf(http.StatusBadRequest, "Invalid closest language %q", similar)
+		}
+		lang.Similar[i] = other.Name
+	}
+
+	log.Infof(ctx, "Saving language %q", name)
+	err := dao.saveLanguage(ctx, lang)
+	if err != nil {
+		return err
+	}
+	// Language lists are displayed in most pages: flush the HTML cache
+	err = dao.deleteCache(ctx)
+	if err != nil {
+		return err
+	}
+	// Don't re-query the datastore: it may not be consistent yet.
+	langs := filterOutLanguage(Languages(), name)
+	SetLanguages(append(langs, lang))
+	http.Redirect(w, r, hostPrefix()+"/admin-languages", http.StatusFound)
+	return nil
+}
+
+func ajaxAdminLanguageDelete(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	name := r.FormValue("name")
+	lang := FindLanguage(name)
+	if lang == nil || lang.Name != name {
+		return PiErrorf(http.StatusNotFound, "Language %q not found", name)
+	}
+
+	log.Infof(ctx, "Deleting language %q", name)
+	err := dao.deleteLanguage(ctx, name)
+	if err != nil {
+		return err
+	}
+	err = dao.deleteCache(ctx)
+	if err != nil {
+		return err
+	}
+	SetLanguages(filterOutLanguage(Languages(), name))
+	fmt.Fprint(w, Response{"success": true, "message": "Language " + name + " deleted"})
+	return nil
+}
+
+// filterOutLanguage returns a new slice of the langs not named name.
+func filterOutLanguage(langs []*Language, name string) []*Language {
+	kept := make([]*Language, 0, len(langs))
+	for _, lg := range langs {
+		if lg.Name != name {
+			kept = append(kept, lg)
+		}
+	}
+	return kept
+}
+
+// splitCommaList parses "a, b,c" into [a b c].
+func splitCommaList(s string) []string {
+	chunks := strings.Split(s, ",")
+	chunks = MapStrings(chunks, strings.TrimSpace)
+	return RemoveEmptyStrings(chunks)
+}
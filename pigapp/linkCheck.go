@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/delay"
+	"google.golang.org/appengine/log"
+)
+
+//
+// Background check of the external URLs of the idioms and impls.
+// The last LinkStatus of each URL is stored, with the SHA1 of the URL as key name.
+//
+
+var linkChecker = NewLinkChecker(NewPublicLinkClient(15*time.Second), 2*time.Second)
+
+// A URL checked recently is not checked again by the batch job.
+const linkRecheckPeriod = 7 * 24 * time.Hour
+
+// Number of idioms being processed by each single delayed task
+const checkLinksBatchSize = 10
+
+func newLinkStatusKey(ctx context.Context, url string) *datastore.Key {
+	return datastore.NewKey(ctx, "LinkStatus", Sha1hash(url), 0, nil)
+}
+
+// Handle /admin-check-links-ajax
+func adminCheckLinksAjax(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	lang := r.FormValue("lang")
+	if lang != "" {
+		lang = NormLang(lang)
+	}
+	err := checkLinksDelayer.Call(ctx, "", lang)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, Response{"message": "Link checking launched in delayed tasks"})
+	return nil
+}
+
+var checkLinksDelayer *delay.Function
+
+func init() {
+	checkLinksDelayer = delay.Func("check-links", func(ctx context.Context, cursorStr string, lang string) error {
+		q := datastore.NewQuery("Idiom")
+		if cursorStr != "" {
+			cursor, err := datastore.DecodeCursor(cursorStr)
+			if err != nil {
+				return err
+			}
+			q = q.Start(cursor)
+		}
+		iterator := q.Run(ctx)
+
+		var urls []string
+		done := false
+		for i := 0; i < checkLinksBatchSize; i++ {
+			var idiom Idiom
+			_, err := iterator.Next(&idiom)
+			if err == datastore.Done {
+				done = true
+				break
+			} else if err != nil {
+				return err
+			}
+			for _, ref := range CollectLinks(&idiom) {
+				if lang == "" || ref.LanguageName == lang {
+					urls = append(urls, ref.URL)
+				}
+			}
+		}
+
+		if err := checkLinks(ctx, urls); err != nil {
+			return err
+		}
+		if done {
+			log.Infof(ctx, "Link checking completed.")
+			return nil
+		}
+		cursor, err := iterator.Cursor()
+		if err != nil {
+			return err
+		}
+		return checkLinksDelayer.Call(ctx, cursor.String(), lang)
+	})
+}
+
+// checkLinks checks and saves the status of the urls not checked recently.
+func checkLinks(ctx context.Context, urls []string) error {
+	previous, err := loadLinkStatuses(ctx, urls)
+	if err != nil {
+		return err
+	}
+	var todo []string
+	for _, url := range urls {
+		if status, ok := previous[url]; ok && time.Since(status.CheckDate) < linkRecheckPeriod {
+			continue
+		}
+		todo = append(todo, url)
+	}
+	if len(todo) == 0 {
+		return nil
+	}
+
+	results := linkChecker.CheckAll(ctx, todo, 4)
+	keys := make([]*datastore.Key, 0, len(results))
+	statuses := make([]*LinkStatus, 0, len(results))
+	for url, status := range results {
+		status := status
+		if status.Broken {
+			log.Infof(ctx, "Broken link %s: %d %s", url, status.StatusCode, status.Error)
+		}
+		keys = append(keys, newLinkStatusKey(ctx, url))
+		statuses = append(statuses, &status)
+	}
+	_, err = datastore.PutMulti(ctx, keys, statuses)
+	return err
+}
+
+// loadLinkStatuses returns the stored statuses of the urls, by URL.
+// The urls never checked are absent.
+func loadLinkStatuses(ctx context.Context, urls []string) (map[string]LinkStatus, error) {
+	byURL := make(map[string]LinkStatus, len(urls))
+	for i := 0; i < len(urls); i += 500 {
+		j := i + 500
+		if j > len(urls) {
+			j = len(urls)
+		}
+		keys := make([]*datastore.Key, j-i)
+		for k, url := range urls[i:j] {
+			keys[k] = newLinkStatusKey(ctx, url)
+		}
+		statuses := make([]LinkStatus, len(keys))
+		err := datastore.GetMulti(ctx, keys, statuses)
+		if multi, ok := err.(appengine.MultiError); ok {
+			for k, e := range multi {
+				if e == nil {
+					byURL[statuses[k].URL] = statuses[k]
+				} else if e != datastore.ErrNoSuchEntity {
+					return nil, e
+				}
+			}
+		} else if err != nil {
+			return nil, err
+		} else {
+			for _, status := range statuses {
+				byURL[status.URL] = status
+			}
+		}
+	}
+	return byURL, nil
+}
+
+// BrokenLinksFacade is the Facade for the broken links of a language.
+type BrokenLinksFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Lang        string
+	Links       []BrokenLink
+}
+
+// BrokenLink is a link whose last check failed.
+type BrokenLink struct {
+	Idiom  *Idiom
+	Ref    LinkRef
+	Status LinkStatus
+}
+
+// Handle /broken-links/{lang}
+// This screen shows, for a given language, the impl links found broken
+// by the last check.
+func brokenLinks(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	lang := NormLang(mux.Vars(r)["lang"])
+
+	_, idioms, err := dao.getAllIdioms(ctx, 0, "Id")
+	if err != nil {
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve idioms: %v", err)
+	}
+	var links []BrokenLink
+	var urls []string
+	for _, idiom := range idioms {
+		for _, ref := range CollectLinks(idiom) {
+			if ref.LanguageName == lang {
+				links = append(links, BrokenLink{Idiom: idiom, Ref: ref})
+				urls = append(urls, ref.URL)
+			}
+		}
+	}
+	statuses, err := loadLinkStatuses(ctx, urls)
+	if err != nil {
+		return err
+	}
+	broken := links[:0]
+	for _, link := range links {
+		if status, ok := statuses[link.Ref.URL]; ok && status.Broken {
+			// Never show the redirect chains, they could reveal internal hosts
+			status.Redirects, status.FinalURL = nil, ""
+			link.Status = status
+			broken = append(broken, link)
+		}
+	}
+
+	data := &BrokenLinksFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Broken links in the " + PrintNiceLang(lang) + " implementations",
+			Toggles:   toggles,
+		},
+		UserProfile: readUserProfile(r),
+		Lang:        lang,
+		Links:       broken,
+	}
+	return templates.ExecuteTemplate(w, "page-broken-links", data)
+}
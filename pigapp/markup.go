@@ -1,27 +1,37 @@
 package main
 
 import (
+	"fmt"
 	"html/template"
+	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 )
 
-// Markup in idioms comments is extremely lightweight: it only
-// knows one syntax _x for identifiers.
+// Markup in idioms lead paragraphs and impl comments is a small,
+// safe subset of Markdown:
+//
+//   _x          identifier emphasis (the historical syntax)
+//   `code`      inline code
+//   *text*      emphasis
+//   **text**    strong emphasis
+//   [text](url) links, only for the schemes in markupURLSchemes
+//   - item      bullet lists (also "* item")
+//   ```         fenced code blocks
+//
+// Everything else is escaped: raw HTML is never interpreted.
 
-// It may be interpreted client-side (in a Preview),
-// or server-side in HTML pages and RSS feed.
+// It is interpreted server-side in HTML pages, RSS feeds and cheatsheets.
+// The Previews ask the server (/ajax-markup-preview), so that the
+// preview and the final page always agree.
 
 func markup2HTML(paragraph string) string {
-	return emphasize(paragraph)
+	return renderMarkup(paragraph, emphasize)
 }
 
 func markup2CSS(paragraph string) template.HTML {
-	untrusted := paragraph
-	sanitized := template.HTMLEscapeString(untrusted)
-	emphasized := emphasizeCSS(sanitized)
-	linebroken := linebreak(emphasized)
-	return template.HTML(linebroken)
+	return template.HTML(renderMarkup(paragraph, emphasizeCSS))
 }
 
 // emphasize the "underscored" identifiers
@@ -53,3 +63,135 @@ func linebreak(sentence string) string {
 	sentence = strings.Replace(sentence, "\n", "<br/>", -1)
 	return sentence
 }
+
+// markupURLSchemes are the only schemes allowed in links.
+// Site-relative URLs like /idiom/12 are allowed as well.
+var markupURLSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+}
+
+// safeMarkupURL tells if rawurl may be the target of a link.
+func safeMarkupURL(rawurl string) bool {
+	if strings.Contains(rawurl, "\\") {
+		// Browsers read /\evil.com as //evil.com
+		return false
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		// Not protocol-relative
+		return u.Host == "" && strings.HasPrefix(rawurl, "/") && !strings.HasPrefix(rawurl, "//")
+	}
+	return markupURLSchemes[strings.ToLower(u.Scheme)] && u.Host != ""
+}
+
+var (
+	rxMarkupFence  = regexp.MustCompile("^\\s*```")
+	rxMarkupBullet = regexp.MustCompile(`^\s*[-*]\s+(.*)$`)
+	rxMarkupCode   = regexp.MustCompile("^`([^`\n]+)`")
+	rxMarkupLink   = regexp.MustCompile(`^\[([^\]\n]+)\]\(([^)\s]+)\)`)
+	rxMarkupStrong = regexp.MustCompile(`^\*\*([^*\s](?:[^*\n]*[^*\s])?)\*\*`)
+	rxMarkupEm     = regexp.MustCompile(`^\*([^*\s](?:[^*\n]*[^*\s])?)\*`)
+)
+
+// renderMarkup converts the markup to safe HTML.
+// emphasizer renders the _x identifiers of already escaped text.
+func renderMarkup(text string, emphasizer func(string) string) string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	text = strings.Replace(text, "\n\r", "\n", -1)
+	lines := strings.Split(text, "\n")
+
+	var blocks []string
+	var paragraph []string
+	flushParagraph := func() {
+		if paragraph != nil {
+			blocks = append(blocks, linebreak(renderInline(strings.Join(paragraph, "\n"), emphasizer)))
+			paragraph = nil
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case rxMarkupFence.MatchString(line):
+			flushParagraph()
+			var code []string
+			for i++; i < len(lines) && !rxMarkupFence.MatchString(lines[i]); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, "<pre class=\"markup-code\"><code>"+template.HTMLEscapeString(strings.Join(code, "\n"))+"</code></pre>")
+		case rxMarkupBullet.MatchString(line):
+			flushParagraph()
+			var items strings.Builder
+			items.WriteString("<ul>")
+			for ; i < len(lines) && rxMarkupBullet.MatchString(lines[i]); i++ {
+				item := rxMarkupBullet.FindStringSubmatch(lines[i])[1]
+				items.WriteString("<li>" + renderInline(item, emphasizer) + "</li>")
+			}
+			i--
+			items.WriteString("</ul>")
+			blocks = append(blocks, items.String())
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flushParagraph()
+	return strings.Join(blocks, "")
+}
+
+// renderInline converts the inline markup of text to safe HTML.
+func renderInline(text string, emphasizer func(string) string) string {
+	var buf strings.Builder
+	plain := 0
+	flushPlain := func(end int) {
+		if end > plain {
+			buf.WriteString(emphasizer(template.HTMLEscapeString(text[plain:end])))
+		}
+	}
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		var html string
+		var n int
+		switch text[i] {
+		case '`':
+			if m := rxMarkupCode.FindStringSubmatch(rest); m != nil {
+				html, n = "<code>"+template.HTMLEscapeString(m[1])+"</code>", len(m[0])
+			}
+		case '[':
+			if m := rxMarkupLink.FindStringSubmatch(rest); m != nil {
+				label := renderInline(m[1], emphasizer)
+				if safeMarkupURL(m[2]) {
+					html = fmt.Sprintf("<a href=\"%s\" rel=\"nofollow\">%s</a>", template.HTMLEscapeString(m[2]), label)
+				} else {
+					html = label
+				}
+				n = len(m[0])
+			}
+		case '*':
+			if m := rxMarkupStrong.FindStringSubmatch(rest); m != nil {
+				html, n = "<strong>"+renderInline(m[1], emphasizer)+"</strong>", len(m[0])
+			} else if m := rxMarkupEm.FindStringSubmatch(rest); m != nil {
+				html, n = "<em>"+renderInline(m[1], emphasizer)+"</em>", len(m[0])
+			}
+		}
+		if n == 0 {
+			i++
+			continue
+		}
+		flushPlain(i)
+		buf.WriteString(html)
+		i += n
+		plain = i
+	}
+	flushPlain(len(text))
+	return buf.String()
+}
+
+// Handle /ajax-markup-preview
+func ajaxMarkupPreview(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, markup2CSS(r.FormValue("text")))
+	return nil
+}
@@ -30,3 +30,64 @@ func TestEmphasize(t *testing.T) {
 		}
 	}
 }
+
+var markupTests = []struct {
+	text     string
+	expected string
+}{
+	{"", ""},
+	{"Plain text", "Plain text"},
+	{"Variables _a, _b", `Variables <span class="variable">a</span>, <span class="variable">b</span>`},
+	{"snake_case", "snake_case"},
+	{"a\nb", "a<br/>b"},
+	{"a\r\nb", "a<br/>b"},
+
+	// Escaping
+	{"<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+	{"a & b", "a &amp; b"},
+
+	// Inline code
+	{"Call `len(_s)`", "Call <code>len(_s)</code>"},
+	{"`<b>`", "<code>&lt;b&gt;</code>"},
+	{"`unclosed", "`unclosed"},
+
+	// Emphasis
+	{"*very* **important**", "<em>very</em> <strong>important</strong>"},
+	{"**_x** is", `<strong><span class="variable">x</span></strong> is`},
+	{"a * b * c", "a * b * c"},
+
+	// Links
+	{"[doc](https://golang.org/pkg/)", `<a href="https://golang.org/pkg/" rel="nofollow">doc</a>`},
+	{"[idiom](/idiom/12)", `<a href="/idiom/12" rel="nofollow">idiom</a>`},
+	{"[x](javascript:alert(1))", "x)"},
+	{"[x](javascript:alert)", "x"},
+	{"[x](//evil.example.com)", "x"},
+	{"[x](/\\evil.example.com)", "x"},
+	{"[x](/\\/evil.example.com)", "x"},
+	{"[x](https://a.com\\@evil.example.com)", "x"},
+	{"[x](/idiom/1\\x)", "x"},
+	{"[x](https://a.com/?q=\"><script>)", `<a href="https://a.com/?q=&#34;&gt;&lt;script&gt;" rel="nofollow">x</a>`},
+
+	// Lists
+	{"Either:\n- _a\n* b\nDone", `Either:<ul><li><span class="variable">a</span></li><li>b</li></ul>Done`},
+
+	// Fenced code
+	{"Like:\n```\nif a < b {\n  _x++\n}\n```\nok", "Like:<pre class=\"markup-code\"><code>if a &lt; b {\n  _x++\n}</code></pre>ok"},
+	{"```go\nunterminated", "<pre class=\"markup-code\"><code>unterminated</code></pre>"},
+}
+
+func TestMarkup2CSS(t *testing.T) {
+	for i, tt := range markupTests {
+		if processed := string(markup2CSS(tt.text)); processed != tt.expected {
+			t.Errorf("%d. markup2CSS(%q) => %q, want %q", i, tt.text, processed, tt.expected)
+		}
+	}
+}
+
+func TestMarkup2HTML(t *testing.T) {
+	text := "Sort _items with `sort.Slice`"
+	expected := "Sort <b><i>items</i></b> with <code>sort.Slice</code>"
+	if processed := markup2HTML(text); processed != expected {
+		t.Errorf("markup2HTML(%q) => %q, want %q", text, processed, expected)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/user"
+)
+
+//
+// Merge of duplicate idioms. The source idiom is deleted, and replaced
+// by an IdiomTombstone: its URLs permanently redirect to the target idiom.
+//
+
+func newTombstoneKey(ctx context.Context, idiomID int) *datastore.Key {
+	return datastore.NewKey(ctx, "IdiomTombstone", "", int64(idiomID), nil)
+}
+
+// Handle /admin-idiom-merge-ajax
+func adminIdiomMergeAjax(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	sourceIDStr, targetIDStr := r.FormValue("sourceId"), r.FormValue("targetId")
+	_, source, err := dao.getIdiom(ctx, String2Int(sourceIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", sourceIDStr)
+	}
+	targetKey, target, err := dao.getIdiom(ctx, String2Int(targetIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", targetIDStr)
+	}
+
+	merger := "admin"
+	if u := user.Current(ctx); u != nil {
+		merger = u.String()
+	}
+	if err := mergeIdioms(ctx, source, targetKey, target, merger); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, Response{
+		"message": fmt.Sprintf("Idiom %d merged into idiom %d", source.Id, target.Id),
+		"url":     NiceIdiomURL(target),
+	})
+	return nil
+}
+
+func mergeIdioms(ctx context.Context, source *Idiom, targetKey *datastore.Key, target *Idiom, merger string) error {
+	var related []*Idiom
+	relatedKeys := map[int]*datastore.Key{}
+	for _, id := range source.RelatedIdiomIds {
+		if id == target.Id {
+			related = append(related, target)
+			continue
+		}
+		key, idiom, err := dao.getIdiom(ctx, id)
+		if err != nil {
+			// A dangling relation, never mind
+			log.Warningf(ctx, "Idiom %d related to idiom %d not found: %v", id, source.Id, err)
+			continue
+		}
+		related = append(related, idiom)
+		relatedKeys[id] = key
+	}
+
+	if err := target.MergeDuplicate(source, related); err != nil {
+		return PiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	target.LastEditor = merger
+	target.LastEditedImplID = 0
+	if err := dao.saveExistingIdiom(ctx, targetKey, target); err != nil {
+		return err
+	}
+	for _, idiom := range related {
+		if idiom.Id == target.Id {
+			continue
+		}
+		if err := dao.saveExistingIdiom(ctx, relatedKeys[idiom.Id], idiom); err != nil {
+			log.Errorf(ctx, "Saving idiom %d related to merged idiom %d: %v", idiom.Id, source.Id, err)
+		}
+	}
+
+	// The last version of the source, in its history
+	now := time.Now()
+	var historyItem IdiomHistory
+	historyItem.Idiom = *source
+	historyItem.Version = source.Version + 1
+	historyItem.VersionDate = now
+	historyItem.LastEditor = merger
+	historyItem.LastEditedImplID = 0
+	historyItem.EditSummary = fmt.Sprintf("Merged into idiom #%d [%v]", target.Id, target.Title)
+	historyItem.ComputeIdiomOrImplLastEditor()
+	if _, err := datastore.Put(ctx, newHistoryKey(ctx), &historyItem); err != nil {
+		return err
+	}
+
+	tombstone := IdiomTombstone{
+		IdiomID:    source.Id,
+		Title:      source.Title,
+		MergedInto: target.Id,
+		MergeDate:  now,
+		Merger:     merger,
+	}
+	if _, err := datastore.Put(ctx, newTombstoneKey(ctx, source.Id), &tombstone); err != nil {
+		return err
+	}
+
+	// The idioms previously merged into source now redirect to target
+	var older []IdiomTombstone
+	olderKeys, err := datastore.NewQuery("IdiomTombstone").Filter("MergedInto =", source.Id).GetAll(ctx, &older)
+	if err != nil {
+		return err
+	}
+	for i := range older {
+		older[i].MergedInto = target.Id
+	}
+	if _, err := datastore.PutMulti(ctx, olderKeys, older); err != nil {
+		return err
+	}
+
+	if err := dao.deleteIdiom(ctx, source.Id, historyItem.EditSummary); err != nil {
+		return err
+	}
+	htmlCacheEvict(ctx, "/about-block-all-idioms")
+	log.Infof(ctx, "[%s] merged idiom %d into idiom %d", merger, source.Id, target.Id)
+	return nil
+}
+
+// mergedIdiom returns the idiom which replaced the deleted idiom idiomID,
+// or an error if idiomID was not merged.
+func mergedIdiom(ctx context.Context, idiomID int) (*Idiom, error) {
+	var tombstone IdiomTombstone
+	if err := datastore.Get(ctx, newTombstoneKey(ctx, idiomID), &tombstone); err != nil {
+		return nil, err
+	}
+	_, target, err := dao.getIdiom(ctx, tombstone.MergedInto)
+	return target, err
+}
+
+// mergedIdiomURL is the URL of the idiom which replaced the deleted idiom
+// of the request path, with the same impl if any.
+func mergedIdiomURL(ctx context.Context, vars map[string]string) (string, bool) {
+	target, err := mergedIdiom(ctx, String2Int(vars["idiomId"]))
+	if err != nil {
+		return "", false
+	}
+	if implID := String2Int(vars["implId"]); implID > 0 {
+		if _, impl, found := target.FindImplInIdiom(implID); found {
+			return NiceImplURL(target, impl.Id, impl.LanguageName), true
+		}
+	}
+	return NiceIdiomURL(target), true
+}
+
+// movedPermanentlyError is returned when the page has a new URL.
+type movedPermanentlyError string
+
+func (err movedPermanentlyError) Error() string {
+	return string(err)
+}
+
+// Handle /guid/idiom/{idiomId} and /guid/idiom/{idiomId}/version/{version}
+// The GUIDs of the RSS items.
+func guidRedirect(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	idiomIDStr := vars["idiomId"]
+	_, idiom, err := dao.getIdiom(ctx, String2Int(idiomIDStr))
+	if err != nil {
+		if url, ok := mergedIdiomURL(ctx, vars); ok {
+			http.Redirect(w, r, url, http.StatusMovedPermanently)
+			return nil
+		}
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+	http.Redirect(w, r, NiceIdiomURL(idiom), http.StatusMovedPermanently)
+	return nil
+}
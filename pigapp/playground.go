@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+)
+
+var playgroundClient = &http.Client{Timeout: 10 * time.Second}
+
+// playgroundLink is the "Open in playground" link of impl, or nil.
+func playgroundLink(idiom *Idiom, impl Impl) *PlaygroundLink {
+	if !toggles["playgroundLinks"] || impl.DemoURL != "" {
+		return nil
+	}
+	goShareURL := hostPrefix() + fmt.Sprintf("/playground-go/%d/%d", idiom.Id, impl.Id)
+	link, ok := PlaygroundFor(&impl, goShareURL)
+	if !ok {
+		return nil
+	}
+	return &link
+}
+
+// Handle /playground-go/{idiomId}/{implId}
+// Shares the Go impl in the Go Playground, and redirects to it.
+// Each impl version is shared only once, the snippet ID is saved.
+func playgroundGo(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	idiomIDStr, implIDStr := vars["idiomId"], vars["implId"]
+	_, idiom, err := dao.getIdiom(ctx, String2Int(idiomIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+	_, impl, found := idiom.FindImplInIdiom(String2Int(implIDStr))
+	if !found {
+		return PiErrorf(http.StatusNotFound, "Could not find impl %q in idiom %q", implIDStr, idiomIDStr)
+	}
+
+	key := newGoPlaygroundShareKey(ctx, idiom.Id, impl.Id, impl.Version)
+	var share GoPlaygroundShare
+	err = datastore.Get(ctx, key, &share)
+	if err == nil {
+		http.Redirect(w, r, GoPlaygroundSnippetURL+share.SnippetID, http.StatusFound)
+		return nil
+	}
+	if err != datastore.ErrNoSuchEntity {
+		log.Warningf(ctx, "Reading playground share of impl %d: %v", impl.Id, err)
+	}
+
+	snippetID, err := shareInGoPlayground(ctx, impl)
+	if err != nil {
+		return err
+	}
+	share = GoPlaygroundShare{
+		IdiomID:     idiom.Id,
+		ImplID:      impl.Id,
+		ImplVersion: impl.Version,
+		SnippetID:   snippetID,
+		ShareDate:   time.Now(),
+	}
+	if _, err := datastore.Put(ctx, key, &share); err != nil {
+		log.Errorf(ctx, "Saving playground share of impl %d: %v", impl.Id, err)
+	}
+	http.Redirect(w, r, GoPlaygroundSnippetURL+snippetID, http.StatusFound)
+	return nil
+}
+
+func newGoPlaygroundShareKey(ctx context.Context, idiomID, implID, implVersion int) *datastore.Key {
+	return datastore.NewKey(ctx, "GoPlaygroundShare", fmt.Sprintf("%d-%d-%d", idiomID, implID, implVersion), 0, nil)
+}
+
+// shareInGoPlayground sends the share request of impl, and returns the snippet ID.
+func shareInGoPlayground(ctx context.Context, impl *Impl) (string, error) {
+	payload, err := GoPlaygroundPayload(impl)
+	if err != nil {
+		return "", PiErrorf(http.StatusBadRequest, "%v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, GoPlaygroundShareURL, strings.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	resp, err := playgroundClient.Do(req)
+	if err != nil {
+		log.Errorf(ctx, "Sharing impl %d in the Go Playground: %v", impl.Id, err)
+		return "", PiErrorf(http.StatusBadGateway, "Could not reach the Go Playground")
+	}
+	defer resp.Body.Close()
+	// The response is just the snippet ID
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+	snippetID := strings.TrimSpace(string(body))
+	if err != nil || resp.StatusCode != http.StatusOK || !ValidGoPlaygroundSnippetID(snippetID) {
+		log.Errorf(ctx, "Sharing impl %d in the Go Playground: %s %v", impl.Id, resp.Status, err)
+		return "", PiErrorf(http.StatusBadGateway, "The Go Playground could not share the snippet")
+	}
+	return snippetID, nil
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/delay"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/memcache"
+	"google.golang.org/appengine/user"
+)
+
+//
+// Related idioms suggestions: a batch job computes the candidate relations
+// (see SuggestRelations), and stores them as RelationSuggestion entities.
+// An admin accepts or rejects each of them.
+// The idiom page shows the related idioms, plus the pending suggestions
+// having a high score.
+//
+
+// IdiomCooccurrence counts the user sessions in which 2 idioms were viewed.
+// The count is an estimate, see cooccurrenceSampling.
+// The Datastore key name is made of the 2 idiom IDs.
+type IdiomCooccurrence struct {
+	IdiomIDs []int
+	Count    int
+}
+
+// pairKeyName is "a_b", a being the lowest ID.
+func pairKeyName(pair IdiomPair) string {
+	return fmt.Sprintf("%d_%d", pair[0], pair[1])
+}
+
+// viewSessionCookie identifies the browser session. The idioms viewed in
+// the session are kept server-side in Memcache, so they can't be forged.
+const viewSessionCookie = "view-session"
+
+// viewSessionDuration is how long the viewed idioms of a session are remembered.
+const viewSessionDuration = 4 * time.Hour
+
+// maxSessionIdioms is the number of viewed idioms remembered in the session.
+// It must stay below 25, the max number of entity groups in a transaction.
+const maxSessionIdioms = 10
+
+// cooccurrenceSampling saves the tasks and the writes: only 1 idiom view
+// out of cooccurrenceSampling is counted, with the weight cooccurrenceSampling.
+const cooccurrenceSampling = 4
+
+var rxViewSessionID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+func newViewSessionID() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// recordIdiomView adds idiomID to the idioms of the session, and
+// counts the co-occurrences with the idioms previously viewed.
+func recordIdiomView(w http.ResponseWriter, r *http.Request, idiomID int) {
+	ctx := r.Context()
+	if _, _, err := dao.getIdiom(ctx, idiomID); err != nil {
+		// Not an idiom, not a view
+		return
+	}
+	var sessionID string
+	if cookie, err := r.Cookie(viewSessionCookie); err == nil && rxViewSessionID.MatchString(cookie.Value) {
+		sessionID = cookie.Value
+	} else {
+		if sessionID = newViewSessionID(); sessionID == "" {
+			return
+		}
+		// No expiration: a session cookie
+		http.SetCookie(w, &http.Cookie{
+			Name:     viewSessionCookie,
+			Value:    sessionID,
+			Path:     "/",
+			HttpOnly: true,
+		})
+	}
+
+	cacheKey := "idiom-views-" + sessionID
+	var viewed []int
+	if item, err := memcache.Get(ctx, cacheKey); err == nil {
+		for _, chunk := range strings.Split(string(item.Value), "_") {
+			if id, err := strconv.Atoi(chunk); err == nil {
+				viewed = append(viewed, id)
+			}
+		}
+	}
+	for _, id := range viewed {
+		if id == idiomID {
+			// Already counted in this session
+			return
+		}
+	}
+
+	if len(viewed) > 0 && rand.Intn(cooccurrenceSampling) == 0 {
+		if err := cooccurrenceDelayer.Call(ctx, idiomID, viewed); err != nil {
+			log.Errorf(ctx, "counting co-occurrences of idiom %d: %v", idiomID, err)
+		}
+	}
+
+	viewed = append(viewed, idiomID)
+	if len(viewed) > maxSessionIdioms {
+		viewed = viewed[len(viewed)-maxSessionIdioms:]
+	}
+	chunks := make([]string, len(viewed))
+	for i, id := range viewed {
+		chunks[i] = strconv.Itoa(id)
+	}
+	err := memcache.Set(ctx, &memcache.Item{
+		Key:        cacheKey,
+		Value:      []byte(strings.Join(chunks, "_")),
+		Expiration: viewSessionDuration,
+	})
+	if err != nil {
+		log.Warningf(ctx, "saving viewed idioms of the session: %v", err)
+	}
+}
+
+var cooccurrenceDelayer = delay.Func("idiom-cooccurrence", func(ctx context.Context, idiomID int, others []int) error {
+	keys := make([]*datastore.Key, len(others))
+	for i, other := range others {
+		pair := NewIdiomPair(idiomID, other)
+		keys[i] = datastore.NewKey(ctx, "IdiomCooccurrence", pairKeyName(pair), 0, nil)
+	}
+	// In a transaction, so that the concurrent increments are not lost
+	return datastore.RunInTransaction(ctx, func(ctx context.Context) error {
+		counts := make([]IdiomCooccurrence, len(others))
+		err := datastore.GetMulti(ctx, keys, counts)
+		if merr, ok := err.(appengine.MultiError); ok {
+			for _, e := range merr {
+				if e != nil && e != datastore.ErrNoSuchEntity {
+					return e
+				}
+			}
+		} else if err != nil {
+			return err
+		}
+		for i, other := range others {
+			pair := NewIdiomPair(idiomID, other)
+			counts[i].IdiomIDs = []int{pair[0], pair[1]}
+			counts[i].Count += cooccurrenceSampling
+		}
+		_, err = datastore.PutMulti(ctx, keys, counts)
+		return err
+	}, &datastore.TransactionOptions{XG: true})
+})
+
+// loadCooccurrences returns the counts of all the IdiomCooccurrence entities.
+func loadCooccurrences(ctx context.Context) (map[IdiomPair]int, error) {
+	var all []IdiomCooccurrence
+	_, err := datastore.NewQuery("IdiomCooccurrence").GetAll(ctx, &all)
+	if err != nil {
+		return nil, err
+	}
+	cooc := make(map[IdiomPair]int, len(all))
+	for _, c := range all {
+		if len(c.IdiomIDs) == 2 {
+			cooc[NewIdiomPair(c.IdiomIDs[0], c.IdiomIDs[1])] = c.Count
+		}
+	}
+	return cooc, nil
+}
+
+// Handle /admin-compute-related-ajax
+func adminComputeRelatedAjax(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	err := computeRelatedDelayer.Call(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, Response{"message": "Related idioms computation launched in a delayed task"})
+	return nil
+}
+
+// maxSuggestionsPerIdiom is the number of candidates computed for each idiom.
+const maxSuggestionsPerIdiom = 5
+
+var computeRelatedDelayer *delay.Function
+
+func init() {
+	computeRelatedDelayer = delay.Func("compute-related", func(ctx context.Context) error {
+		_, idioms, err := dao.getAllIdioms(ctx, 0, "Id")
+		if err != nil {
+			return err
+		}
+		titles := make(map[int]string, len(idioms))
+		for _, idiom := range idioms {
+			titles[idiom.Id] = idiom.Title
+		}
+		cooc, err := loadCooccurrences(ctx)
+		if err != nil {
+			return err
+		}
+		candidates := SuggestRelations(idioms, cooc, maxSuggestionsPerIdiom)
+
+		// The decisions already taken must be kept.
+		var existing []RelationSuggestion
+		existingKeys, err := datastore.NewQuery("RelationSuggestion").GetAll(ctx, &existing)
+		if err != nil {
+			return err
+		}
+		decided := map[string]bool{}
+		stale := map[string]*datastore.Key{}
+		for i, s := range existing {
+			name := existingKeys[i].StringID()
+			if s.Status == RelationSuggestionPending {
+				stale[name] = existingKeys[i]
+			} else {
+				decided[name] = true
+			}
+		}
+
+		now := time.Now()
+		var keys []*datastore.Key
+		var suggestions []*RelationSuggestion
+		for _, c := range candidates {
+			name := pairKeyName(c.Pair)
+			if decided[name] {
+				continue
+			}
+			delete(stale, name)
+			keys = append(keys, datastore.NewKey(ctx, "RelationSuggestion", name, 0, nil))
+			suggestions = append(suggestions, &RelationSuggestion{
+				IdiomIDs:     []int{c.Pair[0], c.Pair[1]},
+				Titles:       []string{titles[c.Pair[0]], titles[c.Pair[1]]},
+				Signals:      c.Signals,
+				Score:        c.Score,
+				Status:       RelationSuggestionPending,
+				ComputedDate: now,
+			})
+		}
+		// Datastore batch operations are limited to 500 entities
+		for len(keys) > 0 {
+			n := len(keys)
+			if n > 500 {
+				n = 500
+			}
+			if _, err := datastore.PutMulti(ctx, keys[:n], suggestions[:n]); err != nil {
+				return err
+			}
+			keys, suggestions = keys[n:], suggestions[n:]
+		}
+
+		staleKeys := make([]*datastore.Key, 0, len(stale))
+		for _, key := range stale {
+			staleKeys = append(staleKeys, key)
+		}
+		for len(staleKeys) > 0 {
+			n := len(staleKeys)
+			if n > 500 {
+				n = 500
+			}
+			if err := datastore.DeleteMulti(ctx, staleKeys[:n]); err != nil {
+				return err
+			}
+			staleKeys = staleKeys[n:]
+		}
+		log.Infof(ctx, "Computed %d related idioms suggestions, removed %d stale ones.", len(candidates), len(stale))
+		return nil
+	})
+}
+
+// AdminRelatedSuggestionsFacade is the Facade for the Related Suggestions page.
+type AdminRelatedSuggestionsFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Pending     []RelationSuggestionFacade
+}
+
+// RelationSuggestionFacade is the Facade for 1 suggested relation.
+type RelationSuggestionFacade struct {
+	RelationSuggestion
+	Key *datastore.Key
+}
+
+// Handle /admin-related-suggestions
+func adminRelatedSuggestions(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	var pending []RelationSuggestion
+	keys, err := datastore.NewQuery("RelationSuggestion").
+		Filter("Status =", RelationSuggestionPending).
+		Order("-Score").
+		Limit(200).
+		GetAll(ctx, &pending)
+	if err != nil {
+		return err
+	}
+	list := make([]RelationSuggestionFacade, len(pending))
+	for i, s := range pending {
+		list[i] = RelationSuggestionFacade{
+			RelationSuggestion: s,
+			Key:                keys[i],
+		}
+	}
+
+	data := &AdminRelatedSuggestionsFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Related idioms suggestions",
+			ExtraCss:  []string{hostPrefix() + themeDirectory() + "/css/admin.css"},
+			Toggles:   toggles,
+		},
+		UserProfile: readUserProfile(r),
+		Pending:     list,
+	}
+	return templates.ExecuteTemplate(w, "page-admin-related-suggestions", data)
+}
+
+// Handle /admin-related-suggestion-decide
+// decision is "accept" or "reject".
+func adminRelatedSuggestionDecide(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+	ctx := r.Context()
+	keyStr := r.FormValue("suggestionkey")
+	key, err := datastore.DecodeKey(keyStr)
+	if err != nil {
+		return PiErrorf(http.StatusBadRequest, "Could not decode key %q", keyStr)
+	}
+	var suggestion RelationSuggestion
+	err = datastore.Get(ctx, key, &suggestion)
+	if err == datastore.ErrNoSuchEntity {
+		return PiErrorf(http.StatusNotFound, "Suggestion %q no longer exists", keyStr)
+	}
+	if err != nil {
+		log.Errorf(ctx, "retrieving RelationSuggestion: %v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve suggestion :(")
+	}
+	if suggestion.Status != RelationSuggestionPending {
+		return PiErrorf(http.StatusConflict, "Suggestion already %s by %s", suggestion.Status, suggestion.Decider)
+	}
+	if len(suggestion.IdiomIDs) != 2 {
+		return PiErrorf(http.StatusInternalServerError, "Malformed suggestion %q", keyStr)
+	}
+
+	switch r.FormValue("decision") {
+	case "accept":
+		keyA, idiomA, err := dao.getIdiom(ctx, suggestion.IdiomIDs[0])
+		if err != nil {
+			return PiErrorf(http.StatusNotFound, "%v", err)
+		}
+		keyB, idiomB, err := dao.getIdiom(ctx, suggestion.IdiomIDs[1])
+		if err != nil {
+			return PiErrorf(http.StatusNotFound, "%v", err)
+		}
+		idiomA.AddRelation(idiomB)
+		if err := dao.saveExistingIdiom(ctx, keyA, idiomA); err != nil {
+			return err
+		}
+		if err := dao.saveExistingIdiom(ctx, keyB, idiomB); err != nil {
+			return err
+		}
+		suggestion.Status = RelationSuggestionAccepted
+	case "reject":
+		suggestion.Status = RelationSuggestionRejected
+	default:
+		return PiErrorf(http.StatusBadRequest, "Unknown decision %q", r.FormValue("decision"))
+	}
+
+	suggestion.Decider = "admin"
+	if u := user.Current(ctx); u != nil {
+		suggestion.Decider = u.String()
+	}
+	suggestion.DecisionDate = time.Now()
+	if _, err = datastore.Put(ctx, key, &suggestion); err != nil {
+		log.Errorf(ctx, "saving RelationSuggestion: %v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not save decision :(")
+	}
+	http.Redirect(w, r, hostPrefix()+"/admin-related-suggestions", http.StatusFound)
+	return nil
+}
+
+// AlsoLikeLink is an idiom shown in the "You may also like" section.
+type AlsoLikeLink struct {
+	Id    int
+	Title string
+	Type  RelationType
+	// Suggested is true for a high-confidence suggestion not yet
+	// accepted by an admin.
+	Suggested bool
+}
+
+// maxAlsoLike is the number of links in the "You may also like" section.
+const maxAlsoLike = 5
+
+// alsoLike returns the related idioms of idiom, then its best suggestions.
+func alsoLike(ctx context.Context, idiom *Idiom) []AlsoLikeLink {
+	var links []AlsoLikeLink
+	for _, related := range idiom.RelatedIdioms() {
+		links = append(links, AlsoLikeLink{Id: related.Id, Title: related.Title, Type: related.Type})
+	}
+	if !toggles["relatedSuggestions"] || len(links) >= maxAlsoLike {
+		return links
+	}
+
+	var suggestions []RelationSuggestion
+	_, err := datastore.NewQuery("RelationSuggestion").
+		Filter("IdiomIDs =", idiom.Id).
+		Filter("Status =", RelationSuggestionPending).
+		Filter("Score >=", HighConfidenceScore).
+		Order("-Score").
+		Limit(maxAlsoLike).
+		GetAll(ctx, &suggestions)
+	if err != nil {
+		// Not essential to the page
+		log.Errorf(ctx, "retrieving suggestions for idiom %d: %v", idiom.Id, err)
+		return links
+	}
+	for _, s := range suggestions {
+		if len(links) >= maxAlsoLike {
+			break
+		}
+		id, title := s.Other(idiom.Id)
+		if id == 0 || containsInt(idiom.RelatedIdiomIds, id) {
+			continue
+		}
+		links = append(links, AlsoLikeLink{Id: id, Title: title, Type: RelationSeeAlso, Suggested: true})
+	}
+	return links
+}
+
+func containsInt(a []int, x int) bool {
+	for _, y := range a {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
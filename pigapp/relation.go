@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+)
+
+//
+// Management of the relations between idioms: typing, removal, ordering,
+// propagation of title changes and deletions, and integrity check.
+//
+
+// propagateTitle updates the denormalized title of idiom in all its related idioms.
+func propagateTitle(ctx context.Context, idiom *Idiom) {
+	for _, relatedID := range idiom.RelatedIdiomIds {
+		_, _, err := dao.stealthRenameRelated(ctx, relatedID, idiom.Id, idiom.Title)
+		if err != nil {
+			log.Errorf(ctx, "propagating title of idiom %d to idiom %d: %v", idiom.Id, relatedID, err)
+		}
+	}
+}
+
+// propagateDeletion removes the deleted idiom from all its related idioms.
+func propagateDeletion(ctx context.Context, idiom *Idiom) {
+	for _, relatedID := range idiom.RelatedIdiomIds {
+		_, _, err := dao.stealthRemoveRelated(ctx, relatedID, idiom.Id)
+		if err != nil {
+			log.Errorf(ctx, "removing deleted idiom %d from idiom %d: %v", idiom.Id, relatedID, err)
+		}
+	}
+}
+
+// IdiomRelationsFacade is the Facade for the relations management page of an idiom.
+type IdiomRelationsFacade struct {
+	PageMeta      PageMeta
+	UserProfile   UserProfile
+	Idiom         *Idiom
+	Related       []RelatedIdiom
+	RelationTypes []RelationType
+}
+
+// Handle /admin-idiom-relations/{idiomId}
+func adminIdiomRelations(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	idiomIDStr := mux.Vars(r)["idiomId"]
+	_, idiom, err := dao.getIdiom(ctx, String2Int(idiomIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+
+	data := &IdiomRelationsFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Relations of idiom " + idiomIDStr,
+			ExtraCss:  []string{hostPrefix() + themeDirectory() + "/css/admin.css"},
+			Toggles:   toggles,
+		},
+		UserProfile:   readUserProfile(r),
+		Idiom:         idiom,
+		Related:       idiom.RelatedIdioms(),
+		RelationTypes: RelationTypes,
+	}
+	return templates.ExecuteTemplate(w, "page-admin-idiom-relations", data)
+}
+
+// loadRelationPair retrieves the idioms of form values idiomId and otherId.
+func loadRelationPair(ctx context.Context, r *http.Request) (idiomKey, otherKey *datastore.Key, idiom, other *Idiom, err error) {
+	idiomIDStr, otherIDStr := r.FormValue("idiomId"), r.FormValue("otherId")
+	idiomKey, idiom, err = dao.getIdiom(ctx, String2Int(idiomIDStr))
+	if err != nil {
+		return nil, nil, nil, nil, PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+	otherKey, other, err = dao.getIdiom(ctx, String2Int(otherIDStr))
+	if err != nil {
+		return nil, nil, nil, nil, PiErrorf(http.StatusNotFound, "Could not find idiom %q", otherIDStr)
+	}
+	return idiomKey, otherKey, idiom, other, nil
+}
+
+func redirectToRelations(w http.ResponseWriter, r *http.Request, idiomID int) {
+	http.Redirect(w, r, fmt.Sprintf("%s/admin-idiom-relations/%d", hostPrefix(), idiomID), http.StatusFound)
+}
+
+// Handle /admin-relation-save
+// Creates the relation, or changes its type.
+func adminRelationSave(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+	ctx := r.Context()
+	relationType, ok := ParseRelationType(r.FormValue("type"))
+	if !ok {
+		return PiErrorf(http.StatusBadRequest, "Unknown relation type %q", r.FormValue("type"))
+	}
+	idiomKey, otherKey, idiom, other, err := loadRelationPair(ctx, r)
+	if err != nil {
+		return err
+	}
+	if err := idiom.AddTypedRelation(other, relationType); err != nil {
+		return PiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	if err := dao.saveExistingIdiom(ctx, idiomKey, idiom); err != nil {
+		return err
+	}
+	if err := dao.saveExistingIdiom(ctx, otherKey, other); err != nil {
+		return err
+	}
+	redirectToRelations(w, r, idiom.Id)
+	return nil
+}
+
+// Handle /admin-relation-remove
+func adminRelationRemove(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+	ctx := r.Context()
+	idiomKey, otherKey, idiom, other, err := loadRelationPair(ctx, r)
+	if err != nil {
+		return err
+	}
+	idiom.RemoveRelation(other)
+	if err := dao.saveExistingIdiom(ctx, idiomKey, idiom); err != nil {
+		return err
+	}
+	if err := dao.saveExistingIdiom(ctx, otherKey, other); err != nil {
+		return err
+	}
+	redirectToRelations(w, r, idiom.Id)
+	return nil
+}
+
+// Handle /admin-relation-move
+// delta is -1 (up) or 1 (down).
+func adminRelationMove(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+	ctx := r.Context()
+	idiomIDStr := r.FormValue("idiomId")
+	key, idiom, err := dao.getIdiom(ctx, String2Int(idiomIDStr))
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", idiomIDStr)
+	}
+	delta, err := strconv.Atoi(r.FormValue("delta"))
+	if err != nil {
+		return PiErrorf(http.StatusBadRequest, "Invalid delta %q", r.FormValue("delta"))
+	}
+	if err := idiom.MoveRelation(String2Int(r.FormValue("otherId")), delta); err != nil {
+		return PiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	idiom.EditSummary = "Reordered related idioms"
+	if err := dao.saveExistingIdiom(ctx, key, idiom); err != nil {
+		return err
+	}
+	redirectToRelations(w, r, idiom.Id)
+	return nil
+}
+
+// RelationsCheckFacade is the Facade for the relations integrity report.
+type RelationsCheckFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Issues      []RelationIssue
+}
+
+// Handle /admin-relations-check
+func adminRelationsCheck(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	_, idioms, err := dao.getAllIdioms(ctx, 0, "Id")
+	if err != nil {
+		return err
+	}
+
+	data := &RelationsCheckFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Relations integrity",
+			ExtraCss:  []string{hostPrefix() + themeDirectory() + "/css/admin.css"},
+			ExtraJs:   []string{hostPrefix() + themeDirectory() + "/js/programming-idioms-admin.js"},
+			Toggles:   toggles,
+		},
+		UserProfile: readUserProfile(r),
+		Issues:      CheckRelations(idioms),
+	}
+	return templates.ExecuteTemplate(w, "page-admin-relations-check", data)
+}
+
+// Handle /admin-relations-repair-ajax
+func adminRelationsRepairAjax(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	keys, idioms, err := dao.getAllIdioms(ctx, 0, "Id")
+	if err != nil {
+		return err
+	}
+	keysByID := make(map[int]*datastore.Key, len(idioms))
+	for i, idiom := range idioms {
+		keysByID[idiom.Id] = keys[i]
+	}
+
+	modified := RepairRelations(idioms)
+	for _, idiom := range modified {
+		idiom.EditSummary = "Repaired related idioms"
+		if err := dao.saveExistingIdiom(ctx, keysByID[idiom.Id], idiom); err != nil {
+			return err
+		}
+	}
+	log.Infof(ctx, "Repaired the relations of %d idioms", len(modified))
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, Response{"message": fmt.Sprintf("Repaired the relations of %d idioms", len(modified))})
+	return nil
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+	"google.golang.org/appengine/user"
+)
+
+//
+// Review workflow: the contributions of untrusted users (non-admins)
+// enter a review queue. A reviewer approves them (this sets Checked),
+// or rejects them (this reverts the contribution).
+//
+
+// Review is a contribution waiting for, or having received, a reviewer decision.
+// It contains an Idiom ID, but not the Idiom itself.
+type Review struct {
+	IdiomID int
+
+	// IdiomVersion is the version created by the contribution.
+	IdiomVersion int
+
+	// ImplID is 0 when the contribution is about the idiom statement.
+	ImplID int
+
+	// ImplVersion is the version of the impl created by the contribution.
+	ImplVersion int
+
+	LanguageName string
+
+	// Contributor is the nickname of the author of the contribution.
+	Contributor string
+
+	EditSummary string
+
+	// Timestamp of the contribution.
+	Timestamp time.Time
+
+	// Status is ReviewPending, ReviewApproved, ReviewRejected or ReviewStale.
+	Status string
+
+	// Reviewer is the account of the admin who took the decision.
+	Reviewer string
+
+	// ReviewComment is the rationale of the decision.
+	ReviewComment string
+
+	ReviewDate time.Time
+}
+
+// Review statuses
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+	// ReviewStale means the reviewed contents were edited again
+	// before the approval: the newer contribution has its own review.
+	ReviewStale = "stale"
+)
+
+// errContributionOutdated is returned when approving contents
+// which are not the current contents anymore.
+var errContributionOutdated = fmt.Errorf("reviewed contents are not current anymore")
+
+// enqueueReview is called after a contribution has been saved.
+// Contributions of admins are trusted, they don't need any review.
+func enqueueReview(ctx context.Context, r *http.Request, idiom *Idiom, impl *Impl) {
+	if !toggles["reviewQueue"] || IsAdmin(r) {
+		return
+	}
+	review := Review{
+		IdiomID:      idiom.Id,
+		IdiomVersion: idiom.Version,
+		Contributor:  idiom.LastEditor,
+		EditSummary:  idiom.EditSummary,
+		Timestamp:    time.Now(),
+		Status:       ReviewPending,
+	}
+	if impl != nil {
+		review.ImplID = impl.Id
+		review.ImplVersion = impl.Version
+		review.LanguageName = impl.LanguageName
+		review.Contributor = impl.LastEditor
+	}
+	key, err := datastore.Put(ctx, datastore.NewIncompleteKey(ctx, "Review", nil), &review)
+	if err != nil {
+		// The contribution itself is saved: don't fail the request
+		log.Errorf(ctx, "saving Review: %v", err)
+		return
+	}
+	log.Infof(ctx, "Enqueued review %s for idiom %d v%d impl %d", key.Encode(), review.IdiomID, review.IdiomVersion, review.ImplID)
+}
+
+// AdminReviewsFacade is the Facade for the Review Queue page.
+type AdminReviewsFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Pending     []ReviewFacade
+	Stats       []ReviewerStats
+}
+
+// ReviewFacade is the Facade for 1 line of the Review Queue.
+type ReviewFacade struct {
+	Review
+	Key *datastore.Key
+	// DiffPath shows the contribution, and the review form.
+	DiffPath string
+}
+
+// ReviewerStats are the numbers of decisions taken by a reviewer.
+type ReviewerStats struct {
+	Reviewer   string
+	Approved   int
+	Rejected   int
+	LastReview time.Time
+}
+
+func adminReviews(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	var pending []Review
+	keys, err := datastore.NewQuery("Review").
+		Filter("Status =", ReviewPending).
+		Order("Timestamp").
+		Limit(100).
+		GetAll(ctx, &pending)
+	if err != nil {
+		return err
+	}
+	queue := make([]ReviewFacade, len(pending))
+	for i, review := range pending {
+		queue[i] = ReviewFacade{
+			Review:   review,
+			Key:      keys[i],
+			DiffPath: reviewDiffPath(review, keys[i]),
+		}
+	}
+
+	stats, err := reviewerStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	data := &AdminReviewsFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Review queue",
+			ExtraCss:  []string{hostPrefix() + themeDirectory() + "/css/admin.css"},
+			Toggles:   toggles,
+		},
+		UserProfile: readUserProfile(r),
+		Pending:     queue,
+		Stats:       stats,
+	}
+	return templates.ExecuteTemplate(w, "page-admin-reviews", data)
+}
+
+func reviewDiffPath(review Review, key *datastore.Key) string {
+	if review.ImplID == 0 {
+		return fmt.Sprintf("/idiom/%d/diff/%d/%d?review=%s", review.IdiomID, review.IdiomVersion-1, review.IdiomVersion, key.Encode())
+	}
+	return fmt.Sprintf("/idiom/%d/impl/%d/diff/%d/%d?review=%s", review.IdiomID, review.ImplID, review.IdiomVersion-1, review.IdiomVersion, key.Encode())
+}
+
+func reviewerStats(ctx context.Context) ([]ReviewerStats, error) {
+	byReviewer := map[string]*ReviewerStats{}
+	for _, status := range []string{ReviewApproved, ReviewRejected} {
+		var reviews []Review
+		_, err := datastore.NewQuery("Review").
+			Filter("Status =", status).
+			Limit(5000).
+			GetAll(ctx, &reviews)
+		if err != nil {
+			return nil, err
+		}
+		for _, review := range reviews {
+			stats := byReviewer[review.Reviewer]
+			if stats == nil {
+				stats = &ReviewerStats{Reviewer: review.Reviewer}
+				byReviewer[review.Reviewer] = stats
+			}
+			if status == ReviewApproved {
+				stats.Approved++
+			} else {
+				stats.Rejected++
+			}
+			if review.ReviewDate.After(stats.LastReview) {
+				stats.LastReview = review.ReviewDate
+			}
+		}
+	}
+	list := make([]ReviewerStats, 0, len(byReviewer))
+	for _, stats := range byReviewer {
+		list = append(list, *stats)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Approved+list[i].Rejected > list[j].Approved+list[j].Rejected
+	})
+	return list, nil
+}
+
+// loadReview decodes the key, and retrieves the Review.
+func loadReview(ctx context.Context, reviewKeyStr string) (*datastore.Key, *Review, error) {
+	reviewKey, err := datastore.DecodeKey(reviewKeyStr)
+	if err != nil {
+		return nil, nil, PiErrorf(http.StatusBadRequest, "Could not decode key %q", reviewKeyStr)
+	}
+	var review Review
+	err = datastore.Get(ctx, reviewKey, &review)
+	if err == datastore.ErrNoSuchEntity {
+		return nil, nil, PiErrorf(http.StatusNotFound, "Review %q no longer exists", reviewKeyStr)
+	}
+	if err != nil {
+		log.Errorf(ctx, "retrieving Review: %v", err)
+		return nil, nil, PiErrorf(http.StatusInternalServerError, "Could not retrieve Review entry :(")
+	}
+	return reviewKey, &review, nil
+}
+
+// Handle /admin-review-decide
+// decision is "approve" or "reject".
+func adminReviewDecide(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return PiErrorf(http.StatusBadRequest, "POST only")
+	}
+	ctx := r.Context()
+	reviewKey, review, err := loadReview(ctx, r.FormValue("reviewkey"))
+	if err != nil {
+		return err
+	}
+	if review.Status != ReviewPending {
+		return PiErrorf(http.StatusConflict, "Review already %s by %s", review.Status, review.Reviewer)
+	}
+
+	comment := Truncate(r.FormValue("comment"), 500)
+	reviewer := "admin"
+	if u := user.Current(ctx); u != nil {
+		reviewer = u.String()
+	}
+
+	switch r.FormValue("decision") {
+	case "approve":
+		err = approveContribution(ctx, review)
+		review.Status = ReviewApproved
+		if err == errContributionOutdated {
+			err = nil
+			review.Status = ReviewStale
+		}
+	case "reject":
+		err = rejectContribution(ctx, review, reviewer, comment)
+		review.Status = ReviewRejected
+	default:
+		return PiErrorf(http.StatusBadRequest, "Unknown decision %q", r.FormValue("decision"))
+	}
+	if err != nil {
+		return err
+	}
+
+	review.Reviewer = reviewer
+	review.ReviewComment = comment
+	review.ReviewDate = time.Now()
+	_, err = datastore.Put(ctx, reviewKey, review)
+	if err != nil {
+		log.Errorf(ctx, "saving Review: %v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not save review decision")
+	}
+	log.Infof(ctx, "[%s] %s review %s", reviewer, review.Status, reviewKey.Encode())
+
+	http.Redirect(w, r, hostPrefix()+"/admin-reviews", http.StatusFound)
+	return nil
+}
+
+// approveContribution sets Checked, if the reviewed contents are
+// still the current contents. Otherwise, it returns errContributionOutdated
+// and sets nothing: the newer contribution has its own review.
+func approveContribution(ctx context.Context, review *Review) error {
+	_, idiom, err := dao.getIdiom(ctx, review.IdiomID)
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %d", review.IdiomID)
+	}
+
+	if review.ImplID != 0 {
+		_, impl, found := idiom.FindImplInIdiom(review.ImplID)
+		if !found || impl.Version != review.ImplVersion {
+			log.Infof(ctx, "Impl %d v%d is not current anymore, not setting Checked", review.ImplID, review.ImplVersion)
+			return errContributionOutdated
+		}
+		_, _, err = dao.stealthSetChecked(ctx, idiom.Id, impl.Id, true)
+		return err
+	}
+
+	_, reviewed, err := dao.getIdiomHistory(ctx, review.IdiomID, review.IdiomVersion)
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %d v%d", review.IdiomID, review.IdiomVersion)
+	}
+	if !sameStatement(idiom, &reviewed.Idiom) {
+		log.Infof(ctx, "Idiom %d statement v%d is not current anymore, not setting Checked", review.IdiomID, review.IdiomVersion)
+		return errContributionOutdated
+	}
+	_, _, err = dao.stealthSetChecked(ctx, idiom.Id, 0, true)
+	return err
+}
+
+// rejectContribution reverts only the reviewed contents: the other
+// contributions made since are kept. The revert creates a new version.
+func rejectContribution(ctx context.Context, review *Review, reviewer string, comment string) error {
+	key, idiom, err := dao.getIdiom(ctx, review.IdiomID)
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %d", review.IdiomID)
+	}
+	why := fmt.Sprintf("Rejected by reviewer: %s", comment)
+
+	if review.ImplID == 0 && review.IdiomVersion == 1 {
+		// Rejected idiom creation
+		return dao.deleteIdiom(ctx, review.IdiomID, why)
+	}
+
+	previous := &IdiomHistory{}
+	if review.IdiomVersion > 1 {
+		_, previous, err = dao.getIdiomHistory(ctx, review.IdiomID, review.IdiomVersion-1)
+		if err != nil {
+			return PiErrorf(http.StatusNotFound, "Could not find idiom %d v%d", review.IdiomID, review.IdiomVersion-1)
+		}
+	}
+
+	if review.ImplID != 0 {
+		i, impl, found := idiom.FindImplInIdiom(review.ImplID)
+		if !found {
+			return PiErrorf(http.StatusConflict, "Impl %d doesn't exist anymore", review.ImplID)
+		}
+		if impl.Version != review.ImplVersion {
+			return PiErrorf(http.StatusConflict, "Impl %d has been modified since v%d: please review the newer contribution", review.ImplID, review.ImplVersion)
+		}
+		_, previousImpl, existed := previous.FindImplInIdiom(review.ImplID)
+		if existed {
+			restored := *previousImpl
+			restored.Version = impl.Version + 1
+			restored.VersionDate = time.Now()
+			restored.LastEditor = reviewer
+			idiom.Implementations[i] = restored
+		} else {
+			// Rejected impl creation
+			idiom.Implementations = append(idiom.Implementations[:i], idiom.Implementations[i+1:]...)
+		}
+		idiom.EditSummary = Truncate("["+PrintNiceLang(review.LanguageName)+"] "+why, 120)
+		idiom.LastEditedImplID = review.ImplID
+	} else {
+		_, reviewed, err := dao.getIdiomHistory(ctx, review.IdiomID, review.IdiomVersion)
+		if err != nil {
+			return PiErrorf(http.StatusNotFound, "Could not find idiom %d v%d", review.IdiomID, review.IdiomVersion)
+		}
+		if !sameStatement(idiom, &reviewed.Idiom) {
+			return PiErrorf(http.StatusConflict, "Idiom %d statement has been modified since v%d: please review the newer contribution", review.IdiomID, review.IdiomVersion)
+		}
+		idiom.Title = previous.Title
+		idiom.LeadParagraph = previous.LeadParagraph
+		idiom.ExtraKeywords = previous.ExtraKeywords
+		idiom.Tags = previous.Tags
+		if !SameExamples(idiom, &previous.Idiom) {
+			idiom.ExampleInputs = previous.ExampleInputs
+			idiom.ExampleOutputs = previous.ExampleOutputs
+			idiom.ResetVerifiedExamples()
+		}
+		idiom.Checked = previous.Checked
+		idiom.EditSummary = Truncate(why, 120)
+		idiom.LastEditedImplID = 0
+	}
+	idiom.LastEditor = reviewer
+	return dao.saveExistingIdiom(ctx, key, idiom)
+}
+
+// sameStatement compares the fields that a contributor may edit in a statement.
+func sameStatement(a, b *Idiom) bool {
+	return a.Title == b.Title &&
+		a.LeadParagraph == b.LeadParagraph &&
+		a.ExtraKeywords == b.ExtraKeywords &&
+		strings.Join(a.Tags, ",") == strings.Join(b.Tags, ",") &&
+		SameExamples(a, b)
+}
+
+// applyCheckedOnly removes the impls not approved by a reviewer, if the user wants so,
+// except keepImplID (e.g. the impl explicitly requested in the URL).
+func applyCheckedOnly(idiom *Idiom, userProfile UserProfile, keepImplID int) {
+	if !userProfile.CheckedOnly {
+		return
+	}
+	kept := make([]Impl, 0, len(idiom.Implementations))
+	for _, impl := range idiom.Implementations {
+		if impl.Checked || impl.Id == keepImplID {
+			kept = append(kept, impl)
+		}
+	}
+	idiom.Implementations = kept
+}
+
+// applyCheatsheetCheckedOnly removes the lines of the impls not approved by a reviewer,
+// if the user wants so.
+func applyCheatsheetCheckedOnly(lines []cheatSheetLineDoc, userProfile UserProfile) []cheatSheetLineDoc {
+	if !userProfile.CheckedOnly {
+		return lines
+	}
+	kept := make([]cheatSheetLineDoc, 0, len(lines))
+	for _, line := range lines {
+		if line.ImplChecked == "true" {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"encoding/xml"
 	"fmt"
+	"html"
 	"net/http"
 	"sort"
+	"strings"
 	"text/template"
 
 	. "github.com/Deleplace/programming-idioms/pig"
@@ -97,7 +99,8 @@ func rss(w http.ResponseWriter,
 
 	itemsAsStrings := make([]string, len(idioms))
 	for i, idiom := range idioms {
-		desc := idiom.LeadParagraph
+		// The description is HTML, the title is plain text
+		desc := markup2HTML(idiom.LeadParagraph)
 		sort.Sort(&implByVersionDateSorter{idiom.Implementations})
 		// Not interested in full list of impls, just most recent
 		if len(idiom.Implementations) > 5 {
@@ -107,18 +110,22 @@ func rss(w http.ResponseWriter,
 		if len(idiom.Implementations) > 0 {
 			impl0 := idiom.Implementations[0]
 			itemLink = env.Host + NiceImplRelativeURL(idiom, impl0.Id, impl0.LanguageName)
-			desc += listIntro + PrintNiceLang(impl0.LanguageName)
+			desc += listIntro + html.EscapeString(PrintNiceLang(impl0.LanguageName))
 			for _, impl := range idiom.Implementations[1:] {
-				desc += ", " + PrintNiceLang(impl.LanguageName)
+				desc += ", " + html.EscapeString(PrintNiceLang(impl.LanguageName))
 			}
 			desc += "."
 		}
-		desc += "<br/>Last contributor: " + idiom.FindIdiomOrImplLastEditor() + "."
-		desc += "<br/>Last edit: " + idiom.EditSummary
+		desc += "<br/>Last contributor: " + html.EscapeString(idiom.FindIdiomOrImplLastEditor()) + "."
+		desc += "<br/>Last edit: " + html.EscapeString(idiom.EditSummary)
+		snippet := ""
+		if len(idiom.Implementations) > 0 {
+			snippet = rssCodeSnippet(idiom.Implementations[0])
+		}
 		item := &RssItem{
 			Link:        itemLink,
-			Title:       markup2HTML(idiom.Title),
-			Description: markup2HTML(desc),
+			Title:       idiom.Title,
+			Description: desc + snippet,
 			PubDate:     datation(idiom),
 			GUID: GUID{
 				Value:       guidation(idiom),
@@ -142,3 +149,19 @@ func rss(w http.ResponseWriter,
 	}
 	return rssTemplate.ExecuteTemplate(w, "rss", data)
 }
+
+// rssCodeSnippet displays the code of the most recent impl. It is colored
+// with inline styles, because the feed readers don't have our stylesheet.
+func rssCodeSnippet(impl Impl) string {
+	if strings.TrimSpace(impl.CodeBlock) == "" {
+		return ""
+	}
+	code, ok := "", false
+	if toggles["serverSideSyntaxColoring"] {
+		code, ok = HighlightHTMLInline(impl.LanguageName, impl.CodeBlock)
+	}
+	if !ok {
+		code = html.EscapeString(impl.CodeBlock)
+	}
+	return "<br/><br/>" + html.EscapeString(PrintNiceLang(impl.LanguageName)) + ":<pre>" + code + "</pre>"
+}
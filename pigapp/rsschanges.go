@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/xml"
 	"fmt"
+	"html"
 	"net/http"
 )
 
@@ -26,14 +27,14 @@ func rssRecentChanges(w http.ResponseWriter, r *http.Request) error {
 		}
 		prev := change.Idiom.Version - 1 // always...?
 		itemLink := fmt.Sprintf("%s/idiom/%d/diff/%d/%d", env.Host, change.Idiom.Id, prev, change.Idiom.Version)
-		desc := "<br/>Edit: " + change.EditSummary +
-			"<br/>Contributor: " + change.IdiomOrImplLastEditor + "."
+		desc := "<br/>Edit: " + html.EscapeString(change.EditSummary) +
+			"<br/>Contributor: " + html.EscapeString(change.IdiomOrImplLastEditor) + "."
 		// TODO generate a short summary of the modified fields
 		changeDate := change.VersionDate.Format(rssPubDatelayout)
 		item := &RssItem{
 			Link:        itemLink,
-			Title:       markup2HTML(title),
-			Description: markup2HTML(desc),
+			Title:       title,
+			Description: desc,
 			PubDate:     changeDate,
 			GUID: GUID{
 				Value:       itemLink,
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 
 	. "github.com/Deleplace/programming-idioms/pig"
@@ -73,6 +74,8 @@ func findResults(r *http.Request, q string) (results []*Idiom, normalizedQ strin
 	q = strings.Replace(q, "C♯", "Csharp", -1)
 	q = strings.Replace(q, "c♯", "csharp", -1)
 
+	q, tagSlugs := extractTagFilters(q)
+
 	terms := SplitForSearching(q, true)
 
 	words, typedLangs := separateLangKeywords(terms)
@@ -85,6 +88,10 @@ func findResults(r *http.Request, q string) (results []*Idiom, normalizedQ strin
 	})
 
 	if len(words)+len(typedLangs) == 0 {
+		if len(tagSlugs) > 0 {
+			// Only tags: browse the tagged idioms
+			return findResultsByTags(r, tagSlugs)
+		}
 		// Search query is empty or illegible...
 		return nil, "", errEmptyQ
 	}
@@ -109,9 +116,18 @@ func findResults(r *http.Request, q string) (results []*Idiom, normalizedQ strin
 		return nil, "", err
 	}
 
+	if toggles["translations"] && userProfile.Locale != "" {
+		hits = mergeTranslatedHits(ctx, hits, userProfile.Locale, words, numberMaxResults)
+	}
+
 	matchingImplIDs := <-matchingPromise
 
+	if len(tagSlugs) > 0 {
+		hits = filterIdiomsByTags(hits, tagSlugs)
+	}
+
 	for _, idiom := range hits {
+		applyCheckedOnly(idiom, userProfile, 0)
 		implFavoriteLanguagesFirstWithOrder(idiom, userProfile.FavoriteLanguages, "", userProfile.SeeNonFavorite)
 		for i := range idiom.Implementations {
 			impl := &idiom.Implementations[i]
@@ -124,7 +140,58 @@ func findResults(r *http.Request, q string) (results []*Idiom, normalizedQ strin
 			}
 		}
 	}
-	return hits, strings.Join(terms, " "), nil
+	applyTranslations(ctx, hits, userProfile.Locale)
+	return hits, strings.Join(append(terms, tagFilterTerms(tagSlugs)...), " "), nil
+}
+
+// rxTagFilter matches search terms like tag:strings.formatting
+var rxTagFilter = regexp.MustCompile(`(?i)\btag:([a-z0-9.\-]+)`)
+
+// extractTagFilters removes the tag:xxx terms from q, and returns them as slugs.
+func extractTagFilters(q string) (string, []string) {
+	var slugs []string
+	for _, m := range rxTagFilter.FindAllStringSubmatch(q, -1) {
+		slugs = append(slugs, strings.ToLower(m[1]))
+	}
+	return rxTagFilter.ReplaceAllString(q, " "), slugs
+}
+
+func tagFilterTerms(slugs []string) []string {
+	terms := make([]string, len(slugs))
+	for i, slug := range slugs {
+		terms[i] = "tag:" + slug
+	}
+	return terms
+}
+
+// filterIdiomsByTags keeps only the idioms having all the tags (or sub-tags).
+func filterIdiomsByTags(idioms []*Idiom, slugs []string) []*Idiom {
+	kept := idioms[:0]
+	for _, idiom := range idioms {
+		ok := true
+		for _, slug := range slugs {
+			ok = ok && idiom.HasTag(slug)
+		}
+		if ok {
+			kept = append(kept, idiom)
+		}
+	}
+	return kept
+}
+
+func findResultsByTags(r *http.Request, tagSlugs []string) (results []*Idiom, normalizedQ string, err error) {
+	ctx := r.Context()
+	_, hits, err := dao.getIdiomsByTag(ctx, tagSlugs[0])
+	if err != nil {
+		return nil, "", err
+	}
+	hits = filterIdiomsByTags(hits, tagSlugs[1:])
+	userProfile := readUserProfile(r)
+	for _, idiom := range hits {
+		applyCheckedOnly(idiom, userProfile, 0)
+		implFavoriteLanguagesFirstWithOrder(idiom, userProfile.FavoriteLanguages, "", userProfile.SeeNonFavorite)
+	}
+	return hits, strings.Join(tagFilterTerms(tagSlugs), " "), nil
 }
 
 func matchingImplPromise(ctx context.Context, words, typedLangs []string) chan map[string]bool {
@@ -190,6 +257,7 @@ func listByLanguage(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	for _, idiom := range hits {
+		applyCheckedOnly(idiom, userProfile, 0)
 		implFavoriteLanguagesFirstWithOrder(idiom, userProfile.FavoriteLanguages, "", userProfile.SeeNonFavorite)
 	}
 
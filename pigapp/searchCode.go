@@ -0,0 +1,275 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"context"
+
+	"google.golang.org/appengine/log"
+)
+
+//
+// This file is about "search by code": the user pastes a snippet
+// in any language, and we find the idioms having the most similar
+// implementations.
+//
+
+// SearchCodeFacade is the facade for the Search by Code page.
+type SearchCodeFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Code        string
+	Hits        []*CodeSearchHit
+}
+
+// CodeSearchHit is an idiom whose BestImpl is similar to the searched code.
+type CodeSearchHit struct {
+	Idiom *Idiom
+	// Score is between 0 and 1.
+	Score    float64
+	BestImpl *Impl
+	// TargetImpls are the implementations in the user favorite languages.
+	TargetImpls []*Impl
+}
+
+const (
+	// Hits having a lower score are not shown.
+	codeSearchMinScore = 0.05
+	codeSearchMaxHits  = 20
+	// Avoid fingerprinting a whole program...
+	codeSearchMaxBytes = 2000
+)
+
+var errEmptyCode = PiErrorf(http.StatusBadRequest, "Please provide some code to search for")
+
+// codeIndexStamp is bumped each time an idiom is saved or deleted.
+const codeIndexStamp = "codeIndexStamp"
+
+// codeIndex holds the fingerprints of all impls, so that a search
+// doesn't load and fingerprint all the idioms again.
+var codeIndex struct {
+	sync.Mutex
+	stamp   uint64
+	entries []codeIndexEntry
+}
+
+type codeIndexEntry struct {
+	idiomID int
+	implID  int
+	fp      CodeFingerprint
+}
+
+// getCodeIndex returns the fingerprints of all impls, in idiom Id order.
+// They are rebuilt only when an idiom was saved since the last build.
+func getCodeIndex(ctx context.Context) ([]codeIndexEntry, error) {
+	codeIndex.Lock()
+	defer codeIndex.Unlock()
+
+	stamp, err := cacheStamp(ctx, codeIndexStamp)
+	if err != nil {
+		log.Warningf(ctx, "Reading %s: %v", codeIndexStamp, err)
+		if codeIndex.entries != nil {
+			// Better a slightly stale index than no index
+			return codeIndex.entries, nil
+		}
+	}
+	if codeIndex.entries != nil && stamp == codeIndex.stamp {
+		return codeIndex.entries, nil
+	}
+
+	// Not the cached getAllIdioms, which may be older than the stamp
+	_, idioms, err := dao.GaeDatastoreAccessor.getAllIdioms(ctx, 0, "Id")
+	if err != nil {
+		return nil, err
+	}
+	var entries []codeIndexEntry
+	for _, idiom := range idioms {
+		for _, impl := range idiom.Implementations {
+			entries = append(entries, codeIndexEntry{
+				idiomID: idiom.Id,
+				implID:  impl.Id,
+				fp:      Fingerprint(impl.ImportsBlock, impl.CodeBlock),
+			})
+		}
+	}
+	log.Infof(ctx, "Fingerprinted %d impls for the search by code", len(entries))
+	codeIndex.stamp, codeIndex.entries = stamp, entries
+	return entries, nil
+}
+
+// findCodeResults compares the fingerprint of code against the fingerprints
+// of CodeBlock+ImportsBlock of all impls. The target languages are the ones
+// whose impls are listed next to the best matching impl.
+func findCodeResults(ctx context.Context, code string, targetLangs []string) ([]*CodeSearchHit, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, errEmptyCode
+	}
+	code = TruncateBytes(code, codeSearchMaxBytes)
+	fp := Fingerprint(code)
+
+	index, err := getCodeIndex(ctx)
+	if err != nil {
+		log.Errorf(ctx, "%v", err)
+		return nil, PiErrorf(http.StatusInternalServerError, "Could not retrieve idioms.")
+	}
+
+	// The best matching impl of each idiom
+	type match struct {
+		idiomID, implID int
+		score           float64
+	}
+	var matches []*match
+	bestOfIdiom := make(map[int]*match)
+	for _, entry := range index {
+		score := fp.Similarity(entry.fp)
+		if score < codeSearchMinScore {
+			continue
+		}
+		m := bestOfIdiom[entry.idiomID]
+		if m == nil {
+			m = &match{idiomID: entry.idiomID}
+			bestOfIdiom[entry.idiomID] = m
+			matches = append(matches, m)
+		}
+		if score > m.score {
+			m.implID, m.score = entry.implID, score
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) > codeSearchMaxHits {
+		matches = matches[:codeSearchMaxHits]
+	}
+
+	hits := make([]*CodeSearchHit, 0, len(matches))
+	for _, m := range matches {
+		_, idiom, err := dao.getIdiom(ctx, m.idiomID)
+		if err != nil {
+			// e.g. deleted since the index was built
+			log.Warningf(ctx, "Code search hit idiom %d: %v", m.idiomID, err)
+			continue
+		}
+		_, best, found := idiom.FindImplInIdiom(m.implID)
+		if !found {
+			continue
+		}
+		best.Deco.Matching = true
+		hit := &CodeSearchHit{
+			Idiom:    idiom,
+			Score:    m.score,
+			BestImpl: best,
+		}
+		for _, lang := range targetLangs {
+			if lang == best.LanguageName {
+				continue
+			}
+			for i := range idiom.Implementations {
+				impl := &idiom.Implementations[i]
+				if impl.LanguageName == lang {
+					impl.Deco.SearchedLang = true
+					hit.TargetImpls = append(hit.TargetImpls, impl)
+				}
+			}
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// codeSearchTargetLangs are the explicit "lang" parameters if any,
+// or the user favorite languages.
+func codeSearchTargetLangs(r *http.Request, userProfile UserProfile) []string {
+	r.ParseForm()
+	if langs := r.Form["lang"]; len(langs) > 0 {
+		langs = MapStrings(langs, NormLang)
+		return RemoveEmptyStrings(langs)
+	}
+	return userProfile.FavoriteLanguages
+}
+
+// Handle /search-code
+// GET displays the form, POST displays the results.
+func searchCode(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	userProfile := readUserProfile(r)
+	code := r.FormValue("code")
+
+	var hits []*CodeSearchHit
+	if r.Method == "POST" {
+		var err error
+		hits, err = findCodeResults(ctx, code, codeSearchTargetLangs(r, userProfile))
+		if err != nil {
+			return err
+		}
+		applyCheckedOnlyToHits(hits, userProfile)
+	}
+
+	data := &SearchCodeFacade{
+		PageMeta: PageMeta{
+			PageTitle:             "Search by code",
+			Toggles:               toggles,
+			PreventIndexingRobots: true,
+		},
+		UserProfile: userProfile,
+		Code:        code,
+		Hits:        hits,
+	}
+	return templates.ExecuteTemplate(w, "page-search-code", data)
+}
+
+// applyCheckedOnlyToHits removes the target impls not approved by a reviewer,
+// if the user wants so. The BestImpl is kept: it is the one matching the searched code.
+func applyCheckedOnlyToHits(hits []*CodeSearchHit, userProfile UserProfile) {
+	if !userProfile.CheckedOnly {
+		return
+	}
+	for _, hit := range hits {
+		kept := make([]*Impl, 0, len(hit.TargetImpls))
+		for _, impl := range hit.TargetImpls {
+			if impl.Checked {
+				kept = append(kept, impl)
+			}
+		}
+		hit.TargetImpls = kept
+	}
+}
+
+// CodeSearchJSONHit is the JSON form of a CodeSearchHit.
+type CodeSearchJSONHit struct {
+	IdiomID     int
+	IdiomTitle  string
+	IdiomURL    string
+	Score       float64
+	BestImpl    *Impl
+	TargetImpls []*Impl
+}
+
+// Handle /api/search-code
+func jsonSearchCode(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	userProfile := readUserProfile(r)
+	hits, err := findCodeResults(ctx, r.FormValue("code"), codeSearchTargetLangs(r, userProfile))
+	if err != nil {
+		return err
+	}
+	applyCheckedOnlyToHits(hits, userProfile)
+	jsonHits := make([]CodeSearchJSONHit, len(hits))
+	for i, hit := range hits {
+		jsonHits[i] = CodeSearchJSONHit{
+			IdiomID:     hit.Idiom.Id,
+			IdiomTitle:  hit.Idiom.Title,
+			IdiomURL:    NiceIdiomURL(hit.Idiom),
+			Score:       hit.Score,
+			BestImpl:    hit.BestImpl,
+			TargetImpls: hit.TargetImpls,
+		}
+	}
+	return printJSON(w, jsonHits, true)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/delay"
+	"google.golang.org/appengine/log"
+)
+
+//
+// Automated execution of the snippets, in a sandbox container.
+// The admin launches a batch job, which records a RunStatus in each impl.
+//
+
+// snippetSandbox is configured with the environment variables PI_SNIPPET_SANDBOX_IMAGE
+// (mandatory: the image with the toolchains), PI_SNIPPET_SANDBOX_ENGINE (default "docker")
+// and PI_SNIPPET_SANDBOX_RUNTIME (default "runsc", i.e. gVisor).
+// It is nil when no image is configured: then no snippet is executed.
+func snippetSandbox() *Sandbox {
+	image := os.Getenv("PI_SNIPPET_SANDBOX_IMAGE")
+	if image == "" {
+		return nil
+	}
+	sb := NewSandbox(image)
+	if engine := os.Getenv("PI_SNIPPET_SANDBOX_ENGINE"); engine != "" {
+		sb.Engine = engine
+	}
+	if runtime, ok := os.LookupEnv("PI_SNIPPET_SANDBOX_RUNTIME"); ok {
+		sb.Runtime = runtime
+	}
+	return sb
+}
+
+// Handle /admin-run-snippets-ajax
+func adminRunSnippetsAjax(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	if snippetSandbox() == nil {
+		return PiErrorf(http.StatusServiceUnavailable, "No sandbox configured for the snippets execution (PI_SNIPPET_SANDBOX_IMAGE)")
+	}
+	err := runSnippetsDelayer.Call(ctx, "", r.FormValue("lang"), 0)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, Response{"message": "Snippets execution launched in delayed tasks"})
+	return nil
+}
+
+// runSnippetsTaskBudget is the running time of each single delayed task,
+// well within the 10 minutes deadline of a push task.
+const runSnippetsTaskBudget = 5 * time.Minute
+
+var runSnippetsDelayer *delay.Function
+
+func init() {
+	// The task resumes at the idiom of cursorStr, skipping its first skip impls.
+	runSnippetsDelayer = delay.Func("run-snippets", func(ctx context.Context, cursorStr string, lang string, skip int) error {
+		start := time.Now()
+		q := datastore.NewQuery("Idiom")
+		if cursorStr != "" {
+			cursor, err := datastore.DecodeCursor(cursorStr)
+			if err != nil {
+				return err
+			}
+			q = q.Start(cursor)
+		}
+		iterator := q.Run(ctx)
+
+		runner := NewSnippetRunner(snippetSandbox())
+		// The worst case of one snippet, plus the start of the sandbox
+		maxImplDuration := runner.Limits.BuildTimeout + runner.Limits.Timeout + 30*time.Second
+		for {
+			cursor, err := iterator.Cursor()
+			if err != nil {
+				return err
+			}
+			var idiom Idiom
+			_, err = iterator.Next(&idiom)
+			if err == datastore.Done {
+				log.Infof(ctx, "Snippets execution completed.")
+				return nil
+			} else if err != nil {
+				return err
+			}
+			for i, impl := range idiom.Implementations {
+				if i < skip || (lang != "" && impl.LanguageName != lang) {
+					continue
+				}
+				if time.Since(start)+maxImplDuration > runSnippetsTaskBudget {
+					// Not enough time left for this impl: let a new task run it.
+					return runSnippetsDelayer.Call(ctx, cursor.String(), lang, i)
+				}
+				result := runner.Run(ctx, &impl)
+				log.Infof(ctx, "Idiom %d impl %d (%s): %v in %v", idiom.Id, impl.Id, impl.LanguageName, result.Status, result.Duration)
+				_, _, err = dao.stealthSetRunStatus(ctx, idiom.Id, impl.Id, result.Status, time.Now())
+				if err != nil {
+					log.Errorf(ctx, "Saving run status of impl %d: %v", impl.Id, err)
+				}
+			}
+			skip = 0
+		}
+	})
+}
+
+// AdminBrokenSnippetsFacade is the Facade for the broken snippets report.
+type AdminBrokenSnippetsFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	ByLanguage  []BrokenSnippetsGroup
+}
+
+// BrokenSnippetsGroup lists the broken snippets of one language.
+type BrokenSnippetsGroup struct {
+	Lang string
+	// Ran is the number of impls of this language having a meaningful RunStatus.
+	Ran    int
+	Broken []BrokenSnippet
+}
+
+// BrokenSnippet is an impl whose last execution failed.
+type BrokenSnippet struct {
+	Idiom *Idiom
+	Impl  Impl
+}
+
+// Handle /admin-broken-snippets
+func adminBrokenSnippets(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	_, idioms, err := dao.getAllIdioms(ctx, 0, "Id")
+	if err != nil {
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve idioms: %v", err)
+	}
+
+	groups := map[string]*BrokenSnippetsGroup{}
+	for _, idiom := range idioms {
+		for _, impl := range idiom.Implementations {
+			switch impl.RunStatus {
+			case "", RunUnsupported, RunError:
+				continue
+			}
+			group := groups[impl.LanguageName]
+			if group == nil {
+				group = &BrokenSnippetsGroup{Lang: impl.LanguageName}
+				groups[impl.LanguageName] = group
+			}
+			group.Ran++
+			if impl.RunStatus.IsBroken() {
+				group.Broken = append(group.Broken, BrokenSnippet{Idiom: idiom, Impl: impl})
+			}
+		}
+	}
+	byLanguage := make([]BrokenSnippetsGroup, 0, len(groups))
+	for _, group := range groups {
+		byLanguage = append(byLanguage, *group)
+	}
+	sort.Slice(byLanguage, func(i, j int) bool {
+		return byLanguage[i].Lang < byLanguage[j].Lang
+	})
+
+	data := &AdminBrokenSnippetsFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Broken snippets",
+			Toggles:   toggles,
+			ExtraCss:  []string{hostPrefix() + themeDirectory() + "/css/admin.css"},
+		},
+		UserProfile: readUserProfile(r),
+		ByLanguage:  byLanguage,
+	}
+	if err := templates.ExecuteTemplate(w, "page-admin-broken-snippets", data); err != nil {
+		return PiErrorf(http.StatusInternalServerError, "%v", err)
+	}
+	return nil
+}
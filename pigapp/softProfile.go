@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	. "github.com/Deleplace/programming-idioms/pig"
 
@@ -44,11 +45,56 @@ func seeNonFavorite(r *http.Request) bool {
 	return true
 }
 
+// lookForTargetVersions reads the cookie "my-versions".
+func lookForTargetVersions(r *http.Request) map[string]string {
+	cookie, errkie := r.Cookie("my-versions")
+	if errkie != nil {
+		return nil
+	}
+	return parseTargetVersions(cookie.Value)
+}
+
+// parseTargetVersions reads a string like "Python:3.8_Go:1.17".
+func parseTargetVersions(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	versions := map[string]string{}
+	for _, chunk := range strings.Split(s, "_") {
+		parts := strings.SplitN(chunk, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if lang := NormLang(parts[0]); lang != "" && parts[1] != "" {
+			versions[lang] = parts[1]
+		}
+	}
+	return versions
+}
+
+func hideTooNew(r *http.Request) bool {
+	if cookie, errkie := r.Cookie("hide-too-new"); errkie == nil {
+		return cookie.Value == "1"
+	}
+	return false
+}
+
+func checkedOnly(r *http.Request) bool {
+	if cookie, errkie := r.Cookie("checked-only"); errkie == nil {
+		return cookie.Value == "1"
+	}
+	return false
+}
+
 func readUserProfile(r *http.Request) UserProfile {
 	u := UserProfile{
 		Nickname:          lookForNickname(r),
 		FavoriteLanguages: lookForFavoriteLanguages(r),
 		SeeNonFavorite:    seeNonFavorite(r),
+		TargetVersions:    lookForTargetVersions(r),
+		HideTooNew:        hideTooNew(r),
+		CheckedOnly:       checkedOnly(r),
+		Locale:            lookForLocale(r),
 		IsAdmin:           IsAdmin(r),
 	}
 	if u.Nickname != "" || len(u.FavoriteLanguages) > 0 {
@@ -87,6 +133,38 @@ func setLanguagesCookie(w http.ResponseWriter, langs string) http.Cookie {
 	return newCookie
 }
 
+// versions should be like "Python:3.8_Go:1.17"
+func setVersionsCookies(w http.ResponseWriter, versions string, hideTooNew bool) {
+	hide := "0"
+	if hideTooNew {
+		hide = "1"
+	}
+	for name, value := range map[string]string{
+		"my-versions":  versions,
+		"hide-too-new": hide,
+	} {
+		http.SetCookie(w, &http.Cookie{
+			Name:    name,
+			Value:   value,
+			Path:    "/",
+			Expires: time.Now().AddDate(0, 0, 100),
+		})
+	}
+}
+
+func setCheckedOnlyCookie(w http.ResponseWriter, checkedOnly bool) {
+	value := "0"
+	if checkedOnly {
+		value = "1"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:    "checked-only",
+		Value:   value,
+		Path:    "/",
+		Expires: time.Now().AddDate(0, 0, 100),
+	})
+}
+
 //
 // This URL will display homepage, and set soft profile cookies.
 // That way users may transfer preferences to another browser,
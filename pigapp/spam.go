@@ -100,5 +100,9 @@ func isSpam(w http.ResponseWriter, r *http.Request) (busted bool) {
 			}
 		}
 	}
+	if m := CommentSpamMotive(r.FormValue("comment_text")); m != "" {
+		motive = "suspicious comment : " + m + " [" + Truncate(r.FormValue("comment_text"), 30) + "]"
+		return true
+	}
 	return false
 }
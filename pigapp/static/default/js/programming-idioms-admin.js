@@ -103,6 +103,27 @@ $(function() {
 	    });
 	});
 	
+	$('#merge-idioms-form input.merge-idioms').on("click", function(){
+		var sourceId = $("#merge-idioms-form input.source").val();
+		var targetId = $("#merge-idioms-form input.target").val();
+		if( !confirm("Merge idiom " + sourceId + " into idiom " + targetId + "? Idiom " + sourceId + " will be deleted.") )
+			return;
+	    $.ajax({
+	        url: '/admin-idiom-merge-ajax',
+	        type: 'POST',
+	        success: function(response){
+	        	$.fn.pisuccess( response.message );
+	        },
+	        error: function(xhr, status, e){
+	        	$.fn.pierror( "Merge of idiom " + sourceId + " into idiom " + targetId + " failed : " + xhr.responseText );
+	        },
+	        data: {
+	        	sourceId: sourceId,
+	        	targetId: targetId
+	        }
+	    });
+	});
+
 	$('#reindex-form input.submit').on("click", function(){
 	    $.ajax({
 	        url: '/admin-reindex-ajax',
@@ -123,6 +144,66 @@ $(function() {
 	    });
 	});
 
+	$('#run-snippets-form input.submit').on("click", function(){
+		var lang = $("#run-snippets-form input.lang").val();
+	    $.ajax({
+	        url: '/admin-run-snippets-ajax',
+	        type: 'POST',
+	        success: function(response){
+	        	$.fn.pisuccess( response.message );
+	        },
+	        error: function(xhr, status, e){
+	        	$.fn.pierror( "Snippets execution failed : " + xhr.responseText);
+	        },
+	        data: {
+	        	lang: lang
+	        }
+	    });
+	});
+
+	$('#check-links-form input.submit').on("click", function(){
+		var lang = $("#check-links-form input.lang").val();
+	    $.ajax({
+	        url: '/admin-check-links-ajax',
+	        type: 'POST',
+	        success: function(response){
+	        	$.fn.pisuccess( response.message );
+	        },
+	        error: function(xhr, status, e){
+	        	$.fn.pierror( "Link checking failed : " + xhr.responseText);
+	        },
+	        data: {
+	        	lang: lang
+	        }
+	    });
+	});
+
+	$('#compute-related-form input.submit').on("click", function(){
+	    $.ajax({
+	        url: '/admin-compute-related-ajax',
+	        type: 'POST',
+	        success: function(response){
+	        	$.fn.pisuccess( response.message );
+	        },
+	        error: function(xhr, status, e){
+	        	$.fn.pierror( "Related idioms computation failed : " + xhr.responseText);
+	        }
+	    });
+	});
+
+	$('#repair-relations-form input.submit').on("click", function(){
+	    $.ajax({
+	        url: '/admin-relations-repair-ajax',
+	        type: 'POST',
+	        success: function(response){
+	        	$.fn.pisuccess( response.message );
+	        },
+	        error: function(xhr, status, e){
+	        	$.fn.pierror( "Relations repair failed : " + xhr.responseText);
+	        }
+	    });
+	});
+
 	$('#repair-history-form input.submit').on("click", function(){
 		var id = $("#repair-history-form input.idiom").val();
 	    $.ajax({
@@ -215,4 +296,26 @@ $(function() {
 	        cache: false
 	    });
 	});
+
+	$('button.language-delete').on("click", function(){
+		let btn = $(this);
+		let name = btn.data('name');
+		if( !confirm("Delete language " + name + " ?") )
+			return;
+	    $.ajax({
+	        url: '/admin-language-delete',
+	        type: 'POST',
+	        data: {
+	        	name: name
+	        },
+	        success: function(response){
+	        	$.fn.pisuccess( "Language " + name + " deleted." );
+				btn.closest("tr").remove();
+	        },
+	        error: function(xhr, status, e){
+	        	$.fn.pierror( "Language delete failed : " + xhr.responseText );
+	        },
+	        cache: false
+	    });
+	});
 });
@@ -177,6 +177,25 @@ $(function() {
 	        },
 	    });
 	 });
+
+	 $(".impl-move-action").on("click", function(){
+		var mode = $(this).attr("data-mode");
+		var targetId = window.prompt("Target idiom ID?");
+		if( !targetId )
+			return; // Clicked Cancel
+		$.ajax({
+			url: $(this).attr("data-url"),
+			type: 'POST',
+			data: {targetId: targetId, mode: mode},
+			success: function(response){
+				$.fn.pisuccess( response.message + ' <a href="' + response.url + '">See</a>' );
+			},
+			error: function(xhr, status, e){
+				$.fn.pierror( xhr.responseText );
+			},
+		});
+		return false;
+	 });
 	 
 	
 	// 
@@ -412,32 +431,16 @@ $(function() {
 		return favlangs.indexOf(lg.toLowerCase()) !== -1;
 	}
 	
+	// piLanguageNames and piLanguageNiceNames are written in the page
+	// from the supported languages, see template include-js.
 	var normLang = function(lang){
-		switch(lang.toLowerCase()){
-		case "c++":
-			return "Cpp";
-		case "c#":
-			return "Csharp";
-		case "cs":
-			return "Csharp";
-		case "golang":
-			return "go";
-		case "py":
-			return "Python";
-		case "rs":
-			return "Rust";
-		}
-		return lang;
+		var names = window.piLanguageNames || {};
+		return names[$.trim(lang).toLowerCase()] || lang;
 	}
 
 	var niceLang = function(lang){
-		switch(lang){
-		case "Cpp":
-			return "C++";
-		case "Csharp":
-			return "C#";
-		}
-		return lang;
+		var niceNames = window.piLanguageNiceNames || {};
+		return niceNames[lang] || lang;
 	}
 	
 	$('.show-languages-pool').on('click', function(){
@@ -608,6 +611,8 @@ $(function() {
 				function(response) {
 					if( response.suggestion )
 						form.find("input[name=impl_demo_url]").attr("placeholder", response.suggestion)
+					if( response.docSuggestion )
+						form.find("input[name=impl_doc_url]").attr("placeholder", response.docSuggestion)
 				});
 	});
 
@@ -676,6 +681,22 @@ $(function() {
 		if( /\bmain\b/.test(code) ) {
 			warn("Are you sure about <span class=\"variable\">main</span>? We usually don't want a whole program.");
 		}
+
+		let form = $(this).closest("form");
+		let lang = $(this).attr("data-lang") || form.find("input[name=impl_language]").val();
+		if( lang && code ) {
+			$.post('/ajax-validate-syntax', {
+					lang: lang,
+					imports: form.find("textarea[name=impl_imports]").val(),
+					code: code
+				},
+				function(response) {
+					if( !response.valid ) {
+						let level = response.blocking ? "Syntax error" : "Possible syntax error";
+						warn(level + ": " + $("<span>").text(response.message).html());
+					}
+				});
+		}
 	});
 	
 	// Being able to insert <tab> characters in code
@@ -771,6 +792,17 @@ $(function() {
 	// in modal window.
 	//
 
+	// The Previews ask the server to render the markup, so that
+	// they always agree with the final pages.
+	// The client-side emphasize is only a fallback.
+	function renderMarkup(raw, callback){
+		$.post('/ajax-markup-preview', { text: raw }, function(html){
+			callback(html);
+		}).fail(function(){
+			callback(emphasize($("<div>").text(raw).html()));
+		});
+	}
+
 	// This client-side formatting should be rarely used : only in Previews.
 	function emphasize(raw){
 		// Emphasize the "underscored" identifier
@@ -794,9 +826,9 @@ $(function() {
 				m.find(".piimports pre").text( imports ).hide();
 			m.find(".picode pre").text( $(".form-impl-creation textarea.impl-code").val() );
 			var comment = $(".form-impl-creation textarea[name=impl_comment]").val();
-			var escapedComment = $("<div>").text(comment).html();
-			var refinedComment = emphasize(escapedComment);
-			m.find(".picode pre").attr("data-content", refinedComment);
+			renderMarkup(comment, function(refinedComment){
+				m.find(".picode pre").attr("data-content", refinedComment);
+			});
 			var extDocURL = $(".form-impl-creation input[name=impl_doc_url]").val();
 			if( extDocURL )
 				m.find("a.impl-doc").attr("href", extDocURL).show();
@@ -835,9 +867,9 @@ $(function() {
 				m.find(".piimports pre").text( imports ).hide();
 			m.find(".picode pre").text( $(".form-impl textarea.impl-code").val() );
 			var comment = $(".form-impl textarea[name=impl_comment]").val();
-			var escapedComment = $("<div>").text(comment).html();
-			var refinedComment = emphasize(escapedComment);
-			m.find(".picode pre").attr("data-content", refinedComment);
+			renderMarkup(comment, function(refinedComment){
+				m.find(".picode pre").attr("data-content", refinedComment);
+			});
 			var extDocURL = $(".form-impl input[name=impl_doc_url]").val();
 			if( extDocURL )
 				m.find("a.impl-doc").attr("href", extDocURL).show();
@@ -876,9 +908,9 @@ $(function() {
 			var title = $(".form-idiom-creation input[name=idiom_title]").val();
 			m.find(".idiom-title").html(title);
 			var lead = $(".form-idiom-creation textarea[name=idiom_lead]").val();
-			var escapedLead = $("<div>").text(lead).html();
-			var refinedLead = emphasize(escapedLead);
-			m.find(".idiom-lead-paragraph").html(refinedLead);
+			renderMarkup(lead, function(refinedLead){
+				m.find(".idiom-lead-paragraph").html(refinedLead);
+			});
 
 			var lang = $(".form-idiom-creation input[name=impl_language]").val();
 			m.find(".lang-tab span.label").html(lang);
@@ -889,9 +921,9 @@ $(function() {
 				m.find(".piimports pre").text( imports ).hide();
 			m.find(".picode pre").text( $(".form-idiom-creation textarea.impl-code").val() );
 			var comment = $(".form-idiom-creation textarea[name=impl_comment]").val();
-			var escapedComment = $("<div>").text(comment).html();
-			var refinedComment = emphasize(escapedComment);
-			m.find(".picode pre").attr("data-content", refinedComment);
+			renderMarkup(comment, function(refinedComment){
+				m.find(".picode pre").attr("data-content", refinedComment);
+			});
 			var extDocURL = $(".form-idiom-creation input[name=impl_doc_url]").val();
 			if( extDocURL )
 				m.find("a.impl-doc").attr("href", extDocURL).show();
@@ -1,52 +1,121 @@
 package main
 
 import (
+	"fmt"
+	"hash/fnv"
+	"html/template"
 	"strings"
+	"sync"
 
 	. "github.com/Deleplace/programming-idioms/pig"
 )
 
-// PrettyAdaptor contains a CSS class and a syntax coloring JS script, for some language.
-type PrettyAdaptor struct {
-	CssClass    string
-	JsExtension string
-}
-
-// Pretty maps some languages to a syntax coloring JS script.
-var Pretty = map[string]PrettyAdaptor{
-	"python":  {"py", ""},
-	"ruby":    {"rb", ""},
-	"csharp":  {"cs", ""},
-	"go":      {"go", "lang-go.js"},
-	"basic":   {"basic", "lang-basic.js"},
-	"dart":    {"dart", "lang-dart.js"},
-	"erlang":  {"erlang", "lang-erlang.js"},
-	"lisp":    {"lisp", "lang-lisp.js"},
-	"lua":     {"lua", "lang-lua.js"},
-	"matlab":  {"matlab", "lang-matlab.js"},
-	"pascal":  {"pascal", "lang-pascal.js"},
-	"r":       {"r", "lang-r.js"},
-	"scala":   {"scala", "lang-scala.js"},
-	"scheme":  {"scm", "lang-lisp.js"},
-	"haskell": {"hs", "lang-hs.js"},
-	"clojure": {"clj", "lang-clj.js"},
-}
+// The syntax coloring CSS class and JS script of each language
+// are properties of the Language entities: see Language.HighlighterClass.
 
 func prettifyCSSClass(lang string) string {
-	lg := strings.TrimSpace(strings.ToLower(lang))
-	suff := lg
-	var p PrettyAdaptor
-	var ok bool
+	lg := FindLanguage(lang)
 	// See https://github.com/google/code-prettify/blob/master/README.md
-	if p, ok = Pretty[strings.ToLower(NormLang(lang))]; !ok {
-		return "lang-" + suff
+	if lg == nil || lg.HighlighterClass == "" {
+		return "lang-" + strings.TrimSpace(strings.ToLower(lang))
 	}
-	return "lang-" + p.CssClass
+	return "lang-" + lg.HighlighterClass
 }
 
 // Just returns "" for no extension
 // Not used anymore, see prettify-extra-languages.min.js
 func prettifyExtension(lang string) string {
-	p := Pretty[strings.ToLower(NormLang(lang))]
-	return p.JsExtension
+	lg := FindLanguage(lang)
+	if lg == nil {
+		return ""
+	}
+	return lg.HighlighterScript
+}
+
+//
+// Server-side syntax coloring: for the languages having a Lexer,
+// the HTML is already colored when it leaves the server, which benefits
+// the cheatsheets, the RSS feeds, the JSON API, and clients without JS.
+// The other languages are still colored client-side by prettify.
+//
+
+// highlightCacheMaxEntries bounds the memory used by highlightCache.
+const highlightCacheMaxEntries = 5000
+
+type highlightCacheEntry struct {
+	codeHash uint64
+	html     string
+}
+
+// highlightCache holds the colored HTML of each impl version.
+// An impl version is immutable, however the hash of the code is
+// checked anyway, for unsaved impls (e.g. previews).
+var highlightCache = struct {
+	sync.Mutex
+	m map[string]highlightCacheEntry
+}{m: map[string]highlightCacheEntry{}}
+
+// highlightedHTML returns the colored, escaped code, or "" when lang has no Lexer.
+// cacheKey may be empty, for no caching.
+// The variables, if any, are emphasized.
+func highlightedHTML(cacheKey, lang, code string, variables []string) string {
+	if !toggles["serverSideSyntaxColoring"] {
+		return ""
+	}
+	h := fnv.New64a()
+	h.Write([]byte(lang + "\n" + strings.Join(variables, ",") + "\n" + code))
+	codeHash := h.Sum64()
+	if cacheKey != "" {
+		highlightCache.Lock()
+		entry, hit := highlightCache.m[cacheKey]
+		highlightCache.Unlock()
+		if hit && entry.codeHash == codeHash {
+			return entry.html
+		}
+	}
+
+	colored, ok := HighlightHTMLWithVariables(lang, code, variables)
+	if !ok {
+		return ""
+	}
+	if cacheKey != "" {
+		highlightCache.Lock()
+		if len(highlightCache.m) >= highlightCacheMaxEntries {
+			// Crude but simple eviction
+			highlightCache.m = map[string]highlightCacheEntry{}
+		}
+		highlightCache.m[cacheKey] = highlightCacheEntry{codeHash: codeHash, html: colored}
+		highlightCache.Unlock()
+	}
+	return colored
+}
+
+// implHighlightedHTML is cached per impl version.
+func implHighlightedHTML(impl *Impl) string {
+	cacheKey := ""
+	if impl.Id != 0 {
+		cacheKey = fmt.Sprintf("impl_%d_v%d", impl.Id, impl.Version)
+	}
+	return highlightedHTML(cacheKey, impl.LanguageName, impl.CodeBlock, impl.Deco.Variables)
+}
+
+// highlightImpl is a template func. Empty result means
+// "let prettify color it client-side".
+func highlightImpl(impl Impl) template.HTML {
+	return template.HTML(implHighlightedHTML(&impl))
+}
+
+// highlightCheatsheetLine is a template func, cached per impl.
+// Empty result means "display the plain code".
+func highlightCheatsheetLine(doc cheatSheetLineDoc) template.HTML {
+	cacheKey := "cheatsheet_" + string(doc.ImplID)
+	return template.HTML(highlightedHTML(cacheKey, string(doc.Lang), string(doc.ImplCodeBlock), nil))
+}
+
+// applyServerSideHighlighting fills the CodeBlockHTML of the impls, for the JSON API.
+func applyServerSideHighlighting(idiom *Idiom) {
+	for i := range idiom.Implementations {
+		impl := &idiom.Implementations[i]
+		impl.CodeBlockHTML = implHighlightedHTML(impl)
+	}
 }
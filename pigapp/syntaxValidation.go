@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+)
+
+//
+// Syntax validation of the snippets, with the validators registered in pig.
+// Failures are warnings on the edit forms, or blocking errors
+// when the toggle syntaxValidationBlocking is on.
+//
+
+// checkSnippetSyntax returns an error only if the snippet must be rejected.
+func checkSnippetSyntax(lang, imports, code string) error {
+	if !toggles["syntaxValidation"] || !toggles["syntaxValidationBlocking"] {
+		return nil
+	}
+	if err := ValidateSyntax(lang, imports, code); err != nil {
+		return PiErrorf(http.StatusBadRequest, "Invalid %s syntax: %v", PrintNiceLang(lang), err)
+	}
+	return nil
+}
+
+// Handle /ajax-validate-syntax
+func ajaxValidateSyntax(w http.ResponseWriter, r *http.Request) error {
+	lang := NormLang(r.FormValue("lang"))
+	response := Response{
+		"valid":    true,
+		"blocking": toggles["syntaxValidationBlocking"],
+	}
+	if toggles["syntaxValidation"] {
+		if err := ValidateSyntax(lang, r.FormValue("imports"), r.FormValue("code")); err != nil {
+			response["valid"] = false
+			response["message"] = err.Error()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, response)
+	return nil
+}
+
+// AdminInvalidSnippetsFacade is the Facade for the invalid syntax report.
+type AdminInvalidSnippetsFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	ByLanguage  []InvalidSnippetsGroup
+}
+
+// InvalidSnippetsGroup lists the snippets of one language failing validation.
+type InvalidSnippetsGroup struct {
+	Lang string
+	// Checked is the number of impls of this language that were validated.
+	Checked int
+	Invalid []InvalidSnippet
+}
+
+// InvalidSnippet is an impl failing validation.
+type InvalidSnippet struct {
+	Idiom *Idiom
+	Impl  Impl
+	Error string
+}
+
+// Handle /admin-invalid-snippets
+func adminInvalidSnippets(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	_, idioms, err := dao.getAllIdioms(ctx, 0, "Id")
+	if err != nil {
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve idioms: %v", err)
+	}
+
+	groups := map[string]*InvalidSnippetsGroup{}
+	for _, idiom := range idioms {
+		for _, impl := range idiom.Implementations {
+			if !HasSyntaxValidator(impl.LanguageName) {
+				continue
+			}
+			group := groups[impl.LanguageName]
+			if group == nil {
+				group = &InvalidSnippetsGroup{Lang: impl.LanguageName}
+				groups[impl.LanguageName] = group
+			}
+			group.Checked++
+			if err := ValidateImplSyntax(&impl); err != nil {
+				group.Invalid = append(group.Invalid, InvalidSnippet{Idiom: idiom, Impl: impl, Error: err.Error()})
+			}
+		}
+	}
+	byLanguage := make([]InvalidSnippetsGroup, 0, len(groups))
+	for _, group := range groups {
+		byLanguage = append(byLanguage, *group)
+	}
+	sort.Slice(byLanguage, func(i, j int) bool {
+		return byLanguage[i].Lang < byLanguage[j].Lang
+	})
+
+	data := &AdminInvalidSnippetsFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Invalid snippets",
+			Toggles:   toggles,
+			ExtraCss:  []string{hostPrefix() + themeDirectory() + "/css/admin.css"},
+		},
+		UserProfile: readUserProfile(r),
+		ByLanguage:  byLanguage,
+	}
+	if err := templates.ExecuteTemplate(w, "page-admin-invalid-snippets", data); err != nil {
+		return PiErrorf(http.StatusInternalServerError, "%v", err)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+	"github.com/gorilla/mux"
+	"google.golang.org/appengine/log"
+)
+
+//
+// Tags are hierarchical categories of idioms, e.g. "Strings > Formatting".
+// Each tag has a browse page /tag/{slug}, which includes its subtags.
+//
+
+// TagFacade is the Facade for the tag browse pages.
+type TagFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	// Tag is the display name, e.g. "Strings > Formatting". Empty for the root /tags page.
+	Tag  string
+	Slug string
+	// Breadcrumb are the ancestors of Tag, excluding Tag itself.
+	Breadcrumb []TagCount
+	SubTags    []TagCount
+	Idioms     []*Idiom
+	Coverage   []TagCoverage
+}
+
+// TagCount is a tag, and the number of idioms having this tag or one of its subtags.
+type TagCount struct {
+	Tag   string
+	Slug  string
+	Count int
+}
+
+// TagCoverage is the number of idioms of a tag implemented in a language.
+type TagCoverage struct {
+	Lang    string
+	Count   int
+	Percent int
+}
+
+// TagInfo is the JSON form of a tag.
+type TagInfo struct {
+	Tag      string
+	Slug     string
+	SubTags  []TagCount
+	Coverage []TagCoverage
+	Idioms   []*Idiom
+}
+
+// Handle /tags
+func tagIndex(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	_, idioms, err := dao.getAllIdioms(ctx, 0, "Id")
+	if err != nil {
+		log.Errorf(ctx, "%v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve idioms.")
+	}
+	data := TagFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Idioms by tag",
+			Toggles:   toggles,
+		},
+		UserProfile: readUserProfile(r),
+		SubTags:     tagCounts(idioms, ""),
+	}
+	if err := templates.ExecuteTemplate(w, "page-tag", data); err != nil {
+		return PiErrorf(http.StatusInternalServerError, "%v", err)
+	}
+	return nil
+}
+
+// Handle /tag/{name}
+func tagPage(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	slug := TagSlug(mux.Vars(r)["name"])
+	info, err := loadTagInfo(ctx, slug)
+	if err != nil {
+		return err
+	}
+	userProfile := readUserProfile(r)
+	for _, idiom := range info.Idioms {
+		applyCheckedOnly(idiom, userProfile, 0)
+		implFavoriteLanguagesFirstWithOrder(idiom, userProfile.FavoriteLanguages, "", userProfile.SeeNonFavorite)
+	}
+
+	var breadcrumb []TagCount
+	ancestors := TagAncestors(info.Tag)
+	for _, ancestor := range ancestors[:len(ancestors)-1] {
+		breadcrumb = append(breadcrumb, TagCount{Tag: tagLeaf(ancestor), Slug: TagSlug(ancestor)})
+	}
+
+	data := TagFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Idioms tagged " + info.Tag,
+			Toggles:   toggles,
+		},
+		UserProfile: userProfile,
+		Tag:         info.Tag,
+		Slug:        info.Slug,
+		Breadcrumb:  breadcrumb,
+		SubTags:     info.SubTags,
+		Idioms:      info.Idioms,
+		Coverage:    info.Coverage,
+	}
+	if err := templates.ExecuteTemplate(w, "page-tag", data); err != nil {
+		return PiErrorf(http.StatusInternalServerError, "%v", err)
+	}
+	return nil
+}
+
+// Handle /api/tags
+func jsonTags(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	_, idioms, err := dao.getAllIdioms(ctx, 0, "Id")
+	if err != nil {
+		log.Errorf(ctx, "%v", err)
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve idioms.")
+	}
+	return printJSON(w, allTagCounts(idioms), true)
+}
+
+// Handle /api/tag/{name}
+func jsonTag(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	slug := TagSlug(mux.Vars(r)["name"])
+	info, err := loadTagInfo(ctx, slug)
+	if err != nil {
+		return err
+	}
+	userProfile := apiUserProfile(r)
+	for _, idiom := range info.Idioms {
+		applyCheckedOnly(idiom, userProfile, 0)
+		applyTargetVersions(idiom, userProfile, 0)
+		applyServerSideHighlighting(idiom)
+	}
+	return printJSON(w, info, true)
+}
+
+func loadTagInfo(ctx context.Context, slug string) (*TagInfo, error) {
+	if slug == "" {
+		return nil, PiErrorf(http.StatusBadRequest, "Missing tag name")
+	}
+	_, idioms, err := dao.getIdiomsByTag(ctx, slug)
+	if err != nil {
+		log.Errorf(ctx, "%v", err)
+		return nil, PiErrorf(http.StatusInternalServerError, "Could not retrieve idioms for tag %q", slug)
+	}
+	if len(idioms) == 0 {
+		return nil, PiErrorf(http.StatusNotFound, "Unknown tag %q", slug)
+	}
+	return &TagInfo{
+		Tag:      tagDisplayName(idioms, slug),
+		Slug:     slug,
+		SubTags:  tagCounts(idioms, slug),
+		Coverage: tagCoverage(idioms),
+		Idioms:   idioms,
+	}, nil
+}
+
+// tagDisplayName finds the human-readable form of slug, in the tags of idioms.
+func tagDisplayName(idioms []*Idiom, slug string) string {
+	for _, idiom := range idioms {
+		for _, tag := range idiom.Tags {
+			for _, ancestor := range TagAncestors(tag) {
+				if TagSlug(ancestor) == slug {
+					return ancestor
+				}
+			}
+		}
+	}
+	return slug
+}
+
+// tagCounts returns the direct subtags of parentSlug found in idioms,
+// with their number of idioms. Empty parentSlug means the root tags.
+func tagCounts(idioms []*Idiom, parentSlug string) []TagCount {
+	depth := 0
+	if parentSlug != "" {
+		depth = len(TagSegments(tagDisplayName(idioms, parentSlug)))
+	}
+	return filterTagCounts(allTagCounts(idioms), func(tc TagCount) bool {
+		return len(TagSegments(tc.Tag)) == depth+1 && (parentSlug == "" || IsSubTagSlug(tc.Slug, parentSlug))
+	})
+}
+
+// allTagCounts returns all the tags found in idioms, including the ancestor tags,
+// with their number of idioms. They are sorted by tag name.
+func allTagCounts(idioms []*Idiom) []TagCount {
+	counts := map[string]*TagCount{}
+	for _, idiom := range idioms {
+		seen := map[string]bool{}
+		for _, tag := range idiom.Tags {
+			for _, ancestor := range TagAncestors(tag) {
+				slug := TagSlug(ancestor)
+				if seen[slug] {
+					continue
+				}
+				seen[slug] = true
+				if counts[slug] == nil {
+					counts[slug] = &TagCount{Tag: ancestor, Slug: slug}
+				}
+				counts[slug].Count++
+			}
+		}
+	}
+	result := make([]TagCount, 0, len(counts))
+	for _, tc := range counts {
+		result = append(result, *tc)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Slug < result[j].Slug
+	})
+	return result
+}
+
+func filterTagCounts(tcs []TagCount, keep func(TagCount) bool) []TagCount {
+	kept := tcs[:0]
+	for _, tc := range tcs {
+		if keep(tc) {
+			kept = append(kept, tc)
+		}
+	}
+	return kept
+}
+
+// tagCoverage counts, for each language, how many of the idioms have
+// at least one implementation. Best covered languages come first.
+func tagCoverage(idioms []*Idiom) []TagCoverage {
+	counts := map[string]int{}
+	for _, idiom := range idioms {
+		seen := map[string]bool{}
+		for _, impl := range idiom.Implementations {
+			if !seen[impl.LanguageName] {
+				seen[impl.LanguageName] = true
+				counts[impl.LanguageName]++
+			}
+		}
+	}
+	coverage := make([]TagCoverage, 0, len(counts))
+	for lang, n := range counts {
+		coverage = append(coverage, TagCoverage{
+			Lang:    lang,
+			Count:   n,
+			Percent: (100 * n) / len(idioms),
+		})
+	}
+	sort.Slice(coverage, func(i, j int) bool {
+		if coverage[i].Count != coverage[j].Count {
+			return coverage[i].Count > coverage[j].Count
+		}
+		return coverage[i].Lang < coverage[j].Lang
+	})
+	return coverage
+}
+
+// tagLeaf is the last segment of tag, e.g. "Formatting" for "Strings > Formatting".
+func tagLeaf(tag string) string {
+	return Last(TagSegments(tag))
+}
@@ -24,7 +24,17 @@ func initTemplates() (*template.Template, error) {
 		"printNiceLangs":        PrintNiceLangs,
 		"prettifyCSSClass":      prettifyCSSClass,
 		"prettifyExtension":     prettifyExtension,
+		"highlightImpl":         highlightImpl,
+		"highlightCheatsheet":   highlightCheatsheetLine,
 		"normLang":              NormLang,
+		"languageNames":         LanguageNames,
+		"languageNiceNames":     LanguageNiceNames,
+		"tagSlug":               TagSlug,
+		"tagLeaf":               tagLeaf,
+		"exampleSlots":          exampleSlots,
+		"localeCodes":           LocaleCodes,
+		"localeName":            localeName,
+		"localePrefix":          localePrefix,
 		"langBadgeClass":        langBadgeClass,
 		"isInStringList":        isInStringList,
 		"idEqual":               idEqual,
@@ -33,6 +43,7 @@ func initTemplates() (*template.Template, error) {
 		"niceIdiomURL":          NiceIdiomURL,
 		"niceIdiomIDTitleURL":   NiceIdiomIDTitleURL,
 		"niceImplURL":           NiceImplURL,
+		"playgroundLink":        playgroundLink,
 		"themeDir":              themeDirectory,
 		"hostPrefix":            hostPrefix,
 		"host":                  host,
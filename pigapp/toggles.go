@@ -92,6 +92,7 @@ func initToggles() {
 
 	toggles["languageBar"] = true
 	toggles["syntaxColoring"] = true
+	toggles["serverSideSyntaxColoring"] = true
 
 	toggles["licenseDisclaimer"] = true
 	toggles["poweredBy"] = true
@@ -110,6 +111,18 @@ func initToggles() {
 	toggles["implVotingDown"] = false
 	toggles["showImplRating"] = false
 	toggles["languageCreation"] = false
+	toggles["languageFallbacks"] = true
+	toggles["reviewQueue"] = true
+	toggles["snippetExecution"] = false
+	toggles["syntaxValidation"] = true
+	toggles["syntaxValidationBlocking"] = false
+	toggles["variablesLint"] = true
+	toggles["translations"] = true
+	toggles["relatedSuggestions"] = true
+	toggles["linkChecker"] = true
+	toggles["playgroundLinks"] = true
+	toggles["discussions"] = true
+	toggles["drafts"] = true
 
 	// Homepage
 	toggles["homeBlockCoverage"] = true
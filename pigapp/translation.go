@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"github.com/gorilla/mux"
+
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+	"google.golang.org/appengine/log"
+)
+
+//
+// Translations of the idioms texts into other human languages.
+// The locale comes from a URL prefix like /fr/idiom/12, or else
+// from the cookie "locale" of the soft profile.
+// Each save of a Translation is kept as a TranslationHistory child entity.
+//
+
+type localeContextKey struct{}
+
+// withLocalePrefix serves /fr/idiom/12 like /idiom/12, in French.
+// The admin pages have no localized URLs: app.yaml requires an admin login
+// for the paths /admin*, not for /fr/admin*.
+func withLocalePrefix(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(r.URL.Path, "/", 3)
+		if len(parts) >= 2 && parts[1] != "" && NormLocale(parts[1]) == parts[1] {
+			locale := parts[1]
+			if len(parts) == 3 && strings.HasPrefix(parts[2], "admin") {
+				http.NotFound(w, r)
+				return
+			}
+			u := *r.URL
+			u.Path = "/"
+			if len(parts) == 3 {
+				u.Path += parts[2]
+			}
+			u.RawPath = ""
+			r = r.WithContext(context.WithValue(r.Context(), localeContextKey{}, locale))
+			r.URL = &u
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// localePrefix returns the URL prefix of locale, e.g. "/fr".
+func localePrefix(locale string) string {
+	if locale == "" || locale == DefaultLocale {
+		return ""
+	}
+	return "/" + locale
+}
+
+// localeName is a template func, e.g. "fr" -> "Français".
+func localeName(locale string) string {
+	if locale == DefaultLocale {
+		return "English"
+	}
+	return Locales[locale]
+}
+
+func newTranslationKey(ctx context.Context, idiomID int, locale string) *datastore.Key {
+	return datastore.NewKey(ctx, "Translation", fmt.Sprintf("%d_%s", idiomID, locale), 0, nil)
+}
+
+// loadTranslation returns nil if the idiom has no translation in locale.
+func loadTranslation(ctx context.Context, idiomID int, locale string) (*Translation, error) {
+	var t Translation
+	err := datastore.Get(ctx, newTranslationKey(ctx, idiomID, locale), &t)
+	if err == datastore.ErrNoSuchEntity {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// loadTranslations returns the existing translations of idioms in locale, by idiom ID.
+func loadTranslations(ctx context.Context, idioms []*Idiom, locale string) (map[int]*Translation, error) {
+	keys := make([]*datastore.Key, len(idioms))
+	for i, idiom := range idioms {
+		keys[i] = newTranslationKey(ctx, idiom.Id, locale)
+	}
+	buffer := make([]Translation, len(keys))
+	err := datastore.GetMulti(ctx, keys, buffer)
+	merr, _ := err.(appengine.MultiError)
+	if err != nil && merr == nil {
+		return nil, err
+	}
+	translations := make(map[int]*Translation, len(idioms))
+	for i, idiom := range idioms {
+		if merr != nil && merr[i] != nil {
+			if merr[i] != datastore.ErrNoSuchEntity {
+				return nil, merr[i]
+			}
+			continue
+		}
+		translations[idiom.Id] = &buffer[i]
+	}
+	return translations, nil
+}
+
+// loadLocaleTranslations returns all the translations in locale, by idiom ID.
+func loadLocaleTranslations(ctx context.Context, locale string) (map[int]*Translation, error) {
+	var list []*Translation
+	_, err := datastore.NewQuery("Translation").
+		Filter("Locale =", locale).
+		GetAll(ctx, &list)
+	if err != nil {
+		return nil, err
+	}
+	translations := make(map[int]*Translation, len(list))
+	for _, t := range list {
+		translations[t.IdiomID] = t
+	}
+	return translations, nil
+}
+
+// applyTranslations replaces the texts of idioms with their translation in locale.
+// On failure, the texts are left in English.
+func applyTranslations(ctx context.Context, idioms []*Idiom, locale string) {
+	if !toggles["translations"] || locale == "" || locale == DefaultLocale || len(idioms) == 0 {
+		return
+	}
+	translations, err := loadTranslations(ctx, idioms, locale)
+	if err != nil {
+		log.Errorf(ctx, "Loading %s translations: %v", locale, err)
+		return
+	}
+	for _, idiom := range idioms {
+		translations[idiom.Id].Apply(idiom)
+	}
+}
+
+// saveTranslation saves t as a new version, and keeps a copy in the history.
+// previousVersion must be the current version, 0 for a new translation.
+func saveTranslation(ctx context.Context, t *Translation, previousVersion int) error {
+	key := newTranslationKey(ctx, t.IdiomID, t.Locale)
+	err := datastore.RunInTransaction(ctx, func(ctx context.Context) error {
+		var existing Translation
+		err := datastore.Get(ctx, key, &existing)
+		if err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		if existing.Version != previousVersion {
+			return PiErrorf(http.StatusConflict, "Translation has been concurrently modified (editing version %v, current version is %v)", previousVersion, existing.Version)
+		}
+		t.Version = existing.Version + 1
+		t.VersionDate = time.Now()
+		if _, err := datastore.Put(ctx, key, t); err != nil {
+			return err
+		}
+		history := TranslationHistory{Translation: *t}
+		_, err = datastore.Put(ctx, datastore.NewIncompleteKey(ctx, "TranslationHistory", key), &history)
+		return err
+	}, nil)
+	if err != nil {
+		return err
+	}
+	err = indexTranslationFullText(ctx, t)
+	logIf(err, log.Errorf, ctx, "indexing translation")
+	return nil
+}
+
+// lookForLocale reads the locale URL prefix, then the cookie "locale".
+// It returns "" for the DefaultLocale.
+func lookForLocale(r *http.Request) string {
+	locale, _ := r.Context().Value(localeContextKey{}).(string)
+	if locale == "" {
+		if cookie, errkie := r.Cookie("locale"); errkie == nil {
+			locale = NormLocale(cookie.Value)
+		}
+	}
+	if locale == DefaultLocale {
+		return ""
+	}
+	return locale
+}
+
+// Handle /set-locale/{locale}
+func setLocale(w http.ResponseWriter, r *http.Request) error {
+	locale := NormLocale(mux.Vars(r)["locale"])
+	if locale == "" {
+		return PiErrorf(http.StatusBadRequest, "Unsupported locale %q", mux.Vars(r)["locale"])
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:    "locale",
+		Value:   locale,
+		Path:    "/",
+		Expires: time.Now().AddDate(0, 0, 100),
+	})
+	http.Redirect(w, r, hostPrefix()+"/", http.StatusFound)
+	return nil
+}
+
+// TranslationEditFacade is the Facade for the translation form.
+type TranslationEditFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	// Idiom is in English
+	Idiom       *Idiom
+	Translation *Translation
+}
+
+// Handle /translate/{idiomId}/{locale}
+func translationEdit(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	locale := NormLocale(vars["locale"])
+	if locale == "" || locale == DefaultLocale {
+		return PiErrorf(http.StatusBadRequest, "Unsupported locale %q", vars["locale"])
+	}
+	idiomID := String2Int(vars["idiomId"])
+	_, idiom, err := dao.getIdiom(ctx, idiomID)
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", vars["idiomId"])
+	}
+	translation, err := loadTranslation(ctx, idiomID, locale)
+	if err != nil {
+		return PiErrorf(http.StatusInternalServerError, "Could not load translation: %v", err)
+	}
+	if translation == nil {
+		translation = &Translation{IdiomID: idiomID, Locale: locale}
+	}
+
+	data := &TranslationEditFacade{
+		PageMeta: PageMeta{
+			PageTitle:             "Translate " + idiom.Title + " into " + Locales[locale],
+			Toggles:               toggles,
+			PreventIndexingRobots: true,
+		},
+		UserProfile: readUserProfile(r),
+		Idiom:       idiom,
+		Translation: translation,
+	}
+	return templates.ExecuteTemplate(w, "page-translation-edit", data)
+}
+
+// Handle /translation-save
+func translationSave(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	username := Truncate(r.FormValue("user_nickname"), 30)
+	if !toggles["anonymousWrite"] && username == "" {
+		return PiErrorf(http.StatusBadRequest, "Username is mandatory. No anonymous edit.")
+	}
+	setNicknameCookie(w, username)
+
+	locale := NormLocale(r.FormValue("locale"))
+	if locale == "" || locale == DefaultLocale {
+		return PiErrorf(http.StatusBadRequest, "Unsupported locale %q", r.FormValue("locale"))
+	}
+	idiomID := String2Int(r.FormValue("idiom_id"))
+	_, idiom, err := dao.getIdiom(ctx, idiomID)
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", r.FormValue("idiom_id"))
+	}
+	previousVersion, err := strconv.Atoi(r.FormValue("translation_version"))
+	if err != nil {
+		return PiErrorf(http.StatusBadRequest, "Invalid translation version %q", r.FormValue("translation_version"))
+	}
+
+	trim := strings.TrimSpace
+	t := &Translation{
+		IdiomID:       idiomID,
+		Locale:        locale,
+		Title:         trim(Truncate(r.FormValue("translation_title"), 200)),
+		LeadParagraph: trim(TruncateBytes(NoCR(r.FormValue("translation_lead")), 500)),
+		SourceHash:    TranslationSourceHash(idiom),
+		LastEditor:    username,
+		EditSummary:   trim(Truncate(r.FormValue("edit_summary"), 120)),
+	}
+	for _, impl := range idiom.Implementations {
+		if impl.AuthorComment == "" {
+			continue
+		}
+		comment := r.FormValue(fmt.Sprintf("impl_comment_%d", impl.Id))
+		t.SetImplComment(impl.Id, trim(TruncateBytes(NoCR(comment), 500)))
+	}
+	if t.EditSummary == "" {
+		t.EditSummary = fmt.Sprintf("%s translation by user [%s]", Locales[locale], username)
+	}
+
+	log.Infof(ctx, "[%s] is translating idiom %d into %s", username, idiomID, locale)
+	if err := saveTranslation(ctx, t, previousVersion); err != nil {
+		return err
+	}
+	http.Redirect(w, r, hostPrefix()+localePrefix(locale)+NiceIdiomRelativeURL(idiom), http.StatusFound)
+	return nil
+}
+
+// TranslationHistoryFacade is the Facade for the versions of a translation.
+type TranslationHistoryFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Idiom       *Idiom
+	Locale      string
+	History     []*TranslationHistory
+}
+
+// Handle /translation-history/{idiomId}/{locale}
+func translationHistory(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	locale := NormLocale(vars["locale"])
+	if locale == "" || locale == DefaultLocale {
+		return PiErrorf(http.StatusBadRequest, "Unsupported locale %q", vars["locale"])
+	}
+	idiomID := String2Int(vars["idiomId"])
+	_, idiom, err := dao.getIdiom(ctx, idiomID)
+	if err != nil {
+		return PiErrorf(http.StatusNotFound, "Could not find idiom %q", vars["idiomId"])
+	}
+	var history []*TranslationHistory
+	_, err = datastore.NewQuery("TranslationHistory").
+		Ancestor(newTranslationKey(ctx, idiomID, locale)).
+		Order("-Version").
+		GetAll(ctx, &history)
+	if err != nil {
+		return PiErrorf(http.StatusInternalServerError, "Could not load translation history: %v", err)
+	}
+
+	data := &TranslationHistoryFacade{
+		PageMeta: PageMeta{
+			PageTitle:             "History of the " + Locales[locale] + " translation of " + idiom.Title,
+			Toggles:               toggles,
+			PreventIndexingRobots: true,
+		},
+		UserProfile: readUserProfile(r),
+		Idiom:       idiom,
+		Locale:      locale,
+		History:     history,
+	}
+	return templates.ExecuteTemplate(w, "page-translation-history", data)
+}
+
+// NeedsTranslationFacade is the Facade for the list of idioms to translate.
+type NeedsTranslationFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	Locale      string
+	Results     []NeedsTranslationLine
+}
+
+// NeedsTranslationLine is an idiom not fully translated.
+type NeedsTranslationLine struct {
+	Idiom  *Idiom
+	Status TranslationStatus
+}
+
+// translationStatusOrder puts the never-translated idioms first.
+var translationStatusOrder = map[TranslationStatus]int{
+	TranslationMissing:    0,
+	TranslationIncomplete: 1,
+	TranslationOutdated:   2,
+}
+
+// Handle /needs-translation/{locale}
+func needsTranslation(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	locale := NormLocale(vars["locale"])
+	if locale == "" || locale == DefaultLocale {
+		return PiErrorf(http.StatusBadRequest, "Unsupported locale %q", vars["locale"])
+	}
+	_, idioms, err := dao.getAllIdioms(ctx, 0, "-Rating")
+	if err != nil {
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve idioms: %v", err)
+	}
+	translations, err := loadLocaleTranslations(ctx, locale)
+	if err != nil {
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve translations: %v", err)
+	}
+
+	var results []NeedsTranslationLine
+	for _, idiom := range idioms {
+		if status := CheckTranslation(idiom, translations[idiom.Id]); status != TranslationUpToDate {
+			results = append(results, NeedsTranslationLine{Idiom: idiom, Status: status})
+		}
+	}
+	// Most popular idioms first, within each status
+	sort.SliceStable(results, func(i, j int) bool {
+		return translationStatusOrder[results[i].Status] < translationStatusOrder[results[j].Status]
+	})
+
+	data := &NeedsTranslationFacade{
+		PageMeta: PageMeta{
+			PageTitle: "Idioms to translate into " + Locales[locale],
+			Toggles:   toggles,
+		},
+		UserProfile: readUserProfile(r),
+		Locale:      locale,
+		Results:     results,
+	}
+	return templates.ExecuteTemplate(w, "page-needs-translation", data)
+}
+
+// mergeTranslatedHits puts first the idioms whose translation in locale
+// contains all the words, followed by the other search hits.
+func mergeTranslatedHits(ctx context.Context, hits []*Idiom, locale string, words []string, limit int) []*Idiom {
+	idiomIDs, err := searchTranslatedIdiomIDs(ctx, locale, words, limit)
+	if err != nil {
+		log.Errorf(ctx, "Searching %s translations: %v", locale, err)
+		return hits
+	}
+	if len(idiomIDs) == 0 {
+		return hits
+	}
+	keys := make([]*datastore.Key, len(idiomIDs))
+	for i, idiomID := range idiomIDs {
+		keys[i] = newIdiomKey(ctx, idiomID)
+	}
+	buffer := make([]Idiom, len(keys))
+	if err := datastore.GetMulti(ctx, keys, buffer); err != nil {
+		log.Errorf(ctx, "Fetching translated hits: %v", err)
+		return hits
+	}
+	merged := make([]*Idiom, 0, limit)
+	seen := make(map[int]bool, limit)
+	for i := range buffer {
+		merged = append(merged, &buffer[i])
+		seen[buffer[i].Id] = true
+	}
+	for _, idiom := range hits {
+		if len(merged) == limit {
+			break
+		}
+		if !seen[idiom.Id] {
+			merged = append(merged, idiom)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithLocalePrefix(t *testing.T) {
+	var served string
+	h := withLocalePrefix(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = r.URL.Path
+	}))
+
+	for _, path := range []string{
+		"/fr/admin-idiom-delete",
+		"/en/admin-data-import-ajax",
+		"/fr/admin",
+	} {
+		served = ""
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code == http.StatusOK || served != "" {
+			t.Errorf("%s: expected no admin handler, got status %d and path %q", path, w.Code, served)
+		}
+	}
+
+	served = ""
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/fr/idiom/12", nil))
+	if served != "/idiom/12" {
+		t.Errorf("expected /idiom/12, got %q", served)
+	}
+}
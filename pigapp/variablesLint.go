@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+
+	"google.golang.org/appengine/log"
+)
+
+//
+// The variables linter checks that each impl uses the Variables
+// declared by its idiom. Problems are warnings: they never block a save.
+//
+
+// decorateImplVariables lets the impl code blocks emphasize the idiom Variables.
+func decorateImplVariables(idiom *Idiom) {
+	if !toggles["variablesLint"] {
+		return
+	}
+	for i := range idiom.Implementations {
+		idiom.Implementations[i].Deco.Variables = idiom.Variables
+	}
+}
+
+// warnVariablesLint sends a message to the contributor of impl,
+// if impl doesn't use the idiom Variables consistently.
+func warnVariablesLint(ctx context.Context, username string, idiom *Idiom, impl *Impl) {
+	if !toggles["variablesLint"] || username == "" {
+		return
+	}
+	lint := LintImplVariables(idiom.Variables, impl)
+	if lint.OK() {
+		return
+	}
+	log.Infof(ctx, "Idiom %d, impl %d: inconsistent variables: %v", idiom.Id, impl.Id, lint)
+	msg := MessageForUser{
+		Username: username,
+		Message: fmt.Sprintf("Your %s implementation of idiom #%d \"%s\" doesn't match the idiom variables: %v.",
+			PrintNiceLang(impl.LanguageName), idiom.Id, idiom.Title, lint),
+		CreationDate: time.Now(),
+	}
+	_, err := dao.saveNewMessage(ctx, &msg)
+	logIf(err, log.Errorf, ctx, "saving variables lint message")
+}
+
+// VariablesLintFacade is the Facade for the inconsistent variables page.
+type VariablesLintFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	// Lang, if not empty, restricts the report to one language.
+	Lang    string
+	Results []VariablesLintIdiom
+}
+
+// VariablesLintIdiom is an idiom having some impls inconsistent with its Variables.
+type VariablesLintIdiom struct {
+	Idiom    *Idiom
+	Problems []VariablesLintImpl
+}
+
+// VariablesLintImpl is an impl inconsistent with the idiom Variables.
+type VariablesLintImpl struct {
+	Impl Impl
+	Lint VariablesLint
+}
+
+// Handle /variables-lint
+func variablesLint(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	lang := ""
+	if langParam := r.FormValue("lang"); langParam != "" {
+		lang = NormLang(langParam)
+	}
+	_, idioms, err := dao.getAllIdioms(ctx, 0, "Id")
+	if err != nil {
+		return PiErrorf(http.StatusInternalServerError, "Could not retrieve idioms: %v", err)
+	}
+
+	var results []VariablesLintIdiom
+	for _, idiom := range idioms {
+		problems := idiom.LintVariables()
+		if len(problems) == 0 {
+			continue
+		}
+		entry := VariablesLintIdiom{Idiom: idiom}
+		for _, impl := range idiom.Implementations {
+			lint, found := problems[impl.Id]
+			if !found || (lang != "" && impl.LanguageName != lang) {
+				continue
+			}
+			entry.Problems = append(entry.Problems, VariablesLintImpl{Impl: impl, Lint: lint})
+		}
+		if len(entry.Problems) == 0 {
+			continue
+		}
+		sort.Slice(entry.Problems, func(i, j int) bool {
+			return entry.Problems[i].Impl.LanguageName < entry.Problems[j].Impl.LanguageName
+		})
+		results = append(results, entry)
+	}
+
+	title := "Implementations not matching the idiom variables"
+	if lang != "" {
+		title = PrintNiceLang(lang) + " " + title
+	}
+	data := &VariablesLintFacade{
+		PageMeta: PageMeta{
+			PageTitle: title,
+			Toggles:   toggles,
+		},
+		UserProfile: readUserProfile(r),
+		Lang:        lang,
+		Results:     results,
+	}
+	if err := templates.ExecuteTemplate(w, "page-variables-lint", data); err != nil {
+		return PiErrorf(http.StatusInternalServerError, "%v", err)
+	}
+	return nil
+}
@@ -22,72 +22,29 @@ type VersionDiffFacade struct {
 	PreviousChangePath, NextChangePath string
 	// ImplID: if we're focused on one single impl of interest
 	ImplID int
+	// Review is set when a reviewer is deciding about this change.
+	Review *ReviewFacade
+	// View is "inline" or "unified", empty for side by side.
+	View string
+	// Diff is the line and word diff, for the inline and unified views.
+	Diff *VersionDiff
 }
 
+// Handle /idiom/{idiomId}/diff/{v1}/{v2}
+// Optional parameters: view=inline|unified, format=diff for the plain text unified diff.
 func versionDiff(w http.ResponseWriter, r *http.Request) error {
-	vars := mux.Vars(r)
-
 	ctx := r.Context()
-
-	idiomIDStr := vars["idiomId"]
-	idiomID := String2Int(idiomIDStr)
-	v1Str := vars["v1"]
-	v1 := String2Int(v1Str)
-	v2Str := vars["v2"]
-	v2 := String2Int(v2Str)
-	if v2 < v1 {
-		return PiErrorf(http.StatusBadRequest, "Won't compare v%v with older v%v", v1, v2)
-	}
-	if v2 == v1 {
-		return PiErrorf(http.StatusBadRequest, "Won't compare v%v with itself", v1)
-	}
-
-	// In case we're interested in a single impl
-	implIDStr := vars["implId"]
-	implID := String2Int(implIDStr)
-	singleImpl := (implID > 0)
-
-	var err error
-	var left *IdiomHistory
-	if v1 == 0 {
-		// Dummy empty object, to show an idiom creation
-		left = &IdiomHistory{}
-		left.Idiom.Id = idiomID
-	} else {
-		_, left, err = dao.getIdiomHistory(ctx, idiomID, v1)
-		if err != nil {
-			return PiErrorf(http.StatusNotFound, "%v", err)
-		}
-	}
-	_, right, err := dao.getIdiomHistory(ctx, idiomID, v2)
+	left, right, implID, err := versionDiffOperands(r)
 	if err != nil {
-		return PiErrorf(http.StatusNotFound, "%v", err)
+		return err
 	}
 
-	if singleImpl {
-		// Remove all other impls
-
-		impls := left.Implementations
-		left.Implementations = nil
-		for _, impl := range impls {
-			if impl.Id == implID {
-				left.Implementations = append(left.Implementations, impl)
-				break
-			}
-		}
-
-		impls = right.Implementations
-		right.Implementations = nil
-		for _, impl := range impls {
-			if impl.Id == implID {
-				right.Implementations = append(right.Implementations, impl)
-				break
-			}
-		}
+	if r.FormValue("format") == "diff" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err = fmt.Fprint(w, DiffVersions(&left.Idiom, &right.Idiom).Unified())
+		return err
 	}
 
-	removeUntouchedImpl(left, right)
-
 	implIDs := make([]int, 0, len(right.Implementations)+1)
 	implLeft := map[int]Impl{}
 	implRight := map[int]Impl{}
@@ -136,6 +93,24 @@ func versionDiff(w http.ResponseWriter, r *http.Request) error {
 		DeletionImplIDs: deletionImplIDs,
 		ImplID:          implID,
 	}
+	switch view := r.FormValue("view"); view {
+	case "":
+	case "inline", "unified":
+		data.View = view
+		data.Diff = DiffVersions(&left.Idiom, &right.Idiom)
+	default:
+		return PiErrorf(http.StatusBadRequest, "Unknown view %q", view)
+	}
+	if reviewKeyStr := r.FormValue("review"); reviewKeyStr != "" && userProfile.IsAdmin {
+		reviewKey, review, err := loadReview(ctx, reviewKeyStr)
+		if err != nil {
+			return err
+		}
+		data.Review = &ReviewFacade{
+			Review: *review,
+			Key:    reviewKey,
+		}
+	}
 	// Note: the Prev/Next links wouldn't work in a case where version numbers
 	// wouldn't be perfectly sequential.
 	if left.Version >= 2 {
@@ -148,6 +123,82 @@ func versionDiff(w http.ResponseWriter, r *http.Request) error {
 	return templates.ExecuteTemplate(w, "page-idiom-version-diff", data)
 }
 
+// Handle /api/idiom/{idiomId}/diff/{v1}/{v2}
+func jsonVersionDiff(w http.ResponseWriter, r *http.Request) error {
+	left, right, _, err := versionDiffOperands(r)
+	if err != nil {
+		return err
+	}
+	return printJSON(w, DiffVersions(&left.Idiom, &right.Idiom), true)
+}
+
+// versionDiffOperands loads the two versions to compare.
+// Impls which didn't change are removed, and all other impls too
+// if the URL is about a single impl.
+func versionDiffOperands(r *http.Request) (left, right *IdiomHistory, implID int, err error) {
+	vars := mux.Vars(r)
+
+	ctx := r.Context()
+
+	idiomIDStr := vars["idiomId"]
+	idiomID := String2Int(idiomIDStr)
+	v1Str := vars["v1"]
+	v1 := String2Int(v1Str)
+	v2Str := vars["v2"]
+	v2 := String2Int(v2Str)
+	if v2 < v1 {
+		return nil, nil, 0, PiErrorf(http.StatusBadRequest, "Won't compare v%v with older v%v", v1, v2)
+	}
+	if v2 == v1 {
+		return nil, nil, 0, PiErrorf(http.StatusBadRequest, "Won't compare v%v with itself", v1)
+	}
+
+	// In case we're interested in a single impl
+	implIDStr := vars["implId"]
+	implID = String2Int(implIDStr)
+	singleImpl := (implID > 0)
+
+	if v1 == 0 {
+		// Dummy empty object, to show an idiom creation
+		left = &IdiomHistory{}
+		left.Idiom.Id = idiomID
+	} else {
+		_, left, err = dao.getIdiomHistory(ctx, idiomID, v1)
+		if err != nil {
+			return nil, nil, 0, PiErrorf(http.StatusNotFound, "%v", err)
+		}
+	}
+	_, right, err = dao.getIdiomHistory(ctx, idiomID, v2)
+	if err != nil {
+		return nil, nil, 0, PiErrorf(http.StatusNotFound, "%v", err)
+	}
+
+	if singleImpl {
+		// Remove all other impls
+
+		impls := left.Implementations
+		left.Implementations = nil
+		for _, impl := range impls {
+			if impl.Id == implID {
+				left.Implementations = append(left.Implementations, impl)
+				break
+			}
+		}
+
+		impls = right.Implementations
+		right.Implementations = nil
+		for _, impl := range impls {
+			if impl.Id == implID {
+				right.Implementations = append(right.Implementations, impl)
+				break
+			}
+		}
+	}
+
+	removeUntouchedImpl(left, right)
+	return left, right, implID, nil
+}
+
 // removeUntouchedImpl strips all non-relevant implementations from diff operands
 func removeUntouchedImpl(a, b *IdiomHistory) {
 	// two maps ImplID -> version
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	. "github.com/Deleplace/programming-idioms/pig"
+)
+
+//
+// This file is about language versions: impls may declare a MinVersion,
+// and users may declare the version they target, for each language.
+//
+
+// applyTargetVersions flags the impls requiring a newer language version
+// than the user target version. If the user wants so, these impls are removed,
+// except keepImplID (e.g. the impl explicitly requested in the URL).
+func applyTargetVersions(idiom *Idiom, userProfile UserProfile, keepImplID int) {
+	if len(userProfile.TargetVersions) == 0 {
+		return
+	}
+	kept := idiom.Implementations[:0]
+	for _, impl := range idiom.Implementations {
+		impl.Deco.TooNew = impl.RequiresNewerThan(userProfile.TargetVersions[impl.LanguageName])
+		if impl.Deco.TooNew && userProfile.HideTooNew && impl.Id != keepImplID {
+			continue
+		}
+		kept = append(kept, impl)
+	}
+	idiom.Implementations = kept
+}
+
+// implTooNew is used by the cheatsheets, where the impls are not full Impl objects.
+func implTooNew(userProfile UserProfile, lang string, minVersion string) bool {
+	impl := Impl{MinVersion: minVersion}
+	return impl.RequiresNewerThan(userProfile.TargetVersions[lang])
+}
+
+// MyVersionsFacade is the Facade for the target versions profile page.
+type MyVersionsFacade struct {
+	PageMeta    PageMeta
+	UserProfile UserProfile
+	// Langs are the user favorite languages first, then all the other languages.
+	Langs []string
+}
+
+// Handle /my-versions
+// GET displays the form, POST saves the preferences in cookies.
+func myVersions(w http.ResponseWriter, r *http.Request) error {
+	userProfile := readUserProfile(r)
+
+	if r.Method == "POST" {
+		r.ParseForm()
+		versions := map[string]string{}
+		var chunks []string
+		for _, lang := range AllLanguages() {
+			v := sanitizeVersion(r.FormValue("version_" + lang))
+			if v != "" {
+				versions[lang] = v
+				chunks = append(chunks, lang+":"+v)
+			}
+		}
+		sort.Strings(chunks)
+		userProfile.TargetVersions = versions
+		userProfile.HideTooNew = r.FormValue("hide_too_new") != ""
+		setVersionsCookies(w, strings.Join(chunks, "_"), userProfile.HideTooNew)
+		userProfile.CheckedOnly = r.FormValue("checked_only") != ""
+		setCheckedOnlyCookie(w, userProfile.CheckedOnly)
+	}
+
+	langs := append([]string{}, userProfile.FavoriteLanguages...)
+	langs = append(langs, FilterOut(AllLanguages(), userProfile.FavoriteLanguages)...)
+	data := &MyVersionsFacade{
+		PageMeta: PageMeta{
+			PageTitle:             "My language versions",
+			Toggles:               toggles,
+			PreventIndexingRobots: true,
+		},
+		UserProfile: userProfile,
+		Langs:       langs,
+	}
+	return templates.ExecuteTemplate(w, "page-my-versions", data)
+}
+
+var regexpVersionUnsafe = regexp.MustCompile(`[^A-Za-z0-9.+-]`)
+
+// sanitizeVersion keeps the version cookie-safe: no spaces, no separators.
+func sanitizeVersion(v string) string {
+	return Truncate(regexpVersionUnsafe.ReplaceAllString(v, ""), 20)
+}
@@ -142,6 +142,29 @@ func (va GaeVotesAccessor) getImplVotesForIdiom(ctx context.Context, nickname st
 	return
 }
 
+// moveImplVotes makes the votes of all users for impl implID point to idiom idiomID.
+func (va GaeVotesAccessor) moveImplVotes(ctx context.Context, implID int, idiomID int) error {
+	q := datastore.NewQuery("ImplVoteLog").Filter("ImplId =", implID)
+	var votes []*ImplVoteLog
+	keys, err := q.GetAll(ctx, &votes)
+	if err != nil {
+		return err
+	}
+	for _, vote := range votes {
+		vote.IdiomId = idiomID
+	}
+	for i := 0; i < len(keys); i += 500 {
+		j := i + 500
+		if j > len(keys) {
+			j = len(keys)
+		}
+		if _, err := datastore.PutMulti(ctx, keys[i:j], votes[i:j]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (va GaeVotesAccessor) saveImplVoteOrRemove(ctx context.Context, vote ImplVoteLog, nickname string) (delta int, key *datastore.Key, storedVote *ImplVoteLog, err error) {
 	key, existing, err := va.getImplVote(ctx, nickname, vote.ImplId)
 	if err != nil {